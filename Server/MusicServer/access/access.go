@@ -0,0 +1,97 @@
+package access
+
+import (
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/*
+This file decides who may see a music entry or playlist. A resource without an access rule is public. CanView checks one resource in memory and Filter builds the equivalent MongoDB filter for listings, so both must follow the same rules:
+
+  - public: everyone, including anonymous visitors
+  - members: every logged in user
+  - restricted: only the named users and members of the named groups
+
+Admins see everything.
+*/
+
+// Caller is who is asking. An empty UserID is an anonymous visitor.
+type Caller struct {
+	UserID string
+	Role   string
+	Groups []string
+}
+
+func (caller Caller) IsAnonymous() bool {
+	return caller.UserID == ""
+}
+
+func (caller Caller) IsAdmin() bool {
+	return caller.Role == "ADMIN"
+}
+
+// CanView reports whether the caller may see a resource with this rule
+func CanView(rule *models.Access, caller Caller) bool {
+	if caller.IsAdmin() || rule == nil {
+		return true
+	}
+
+	switch rule.Visibility {
+	case "", models.VisibilityPublic:
+		return true
+	case models.VisibilityMembers:
+		return !caller.IsAnonymous()
+	}
+
+	if caller.IsAnonymous() {
+		return false
+	}
+	for _, userId := range rule.Users {
+		if userId == caller.UserID {
+			return true
+		}
+	}
+	for _, group := range rule.Groups {
+		for _, callerGroup := range caller.Groups {
+			if group == callerGroup {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Filter returns the MongoDB filter matching the resources the caller may
+// see, for an access rule stored under field. Admins get an empty filter.
+func Filter(field string, caller Caller) bson.M {
+	if caller.IsAdmin() {
+		return bson.M{}
+	}
+
+	// A missing rule or visibility matches null, an empty one counts as public
+	visible := []interface{}{nil, "", models.VisibilityPublic}
+	if caller.IsAnonymous() {
+		return bson.M{field + ".visibility": bson.M{"$in": visible}}
+	}
+
+	visible = append(visible, models.VisibilityMembers)
+	rules := []bson.M{
+		{field + ".visibility": bson.M{"$in": visible}},
+		{field + ".users": caller.UserID},
+	}
+	if len(caller.Groups) > 0 {
+		rules = append(rules, bson.M{field + ".groups": bson.M{"$in": caller.Groups}})
+	}
+	return bson.M{"$or": rules}
+}
+
+// And combines a filter with an access filter
+func And(filter, accessFilter bson.M) bson.M {
+	if len(accessFilter) == 0 {
+		return filter
+	}
+	if len(filter) == 0 {
+		return accessFilter
+	}
+	return bson.M{"$and": []bson.M{filter, accessFilter}}
+}
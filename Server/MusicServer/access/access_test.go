@@ -0,0 +1,184 @@
+package access
+
+import (
+	"testing"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/* This file checks CanView for every kind of caller against every kind of rule, and that Filter selects exactly what CanView allows. */
+
+var (
+	anonymous   = Caller{}
+	member      = Caller{UserID: "u1", Role: "USER"}
+	namedUser   = Caller{UserID: "u2", Role: "USER"}
+	groupMember = Caller{UserID: "u3", Role: "USER", Groups: []string{"staff", "band"}}
+	admin       = Caller{UserID: "a1", Role: "ADMIN"}
+)
+
+var callers = map[string]Caller{
+	"anonymous":    anonymous,
+	"member":       member,
+	"named user":   namedUser,
+	"group member": groupMember,
+	"admin":        admin,
+}
+
+var rules = map[string]*models.Access{
+	"nil rule":           nil,
+	"empty visibility":   {},
+	"public":             {Visibility: models.VisibilityPublic},
+	"members":            {Visibility: models.VisibilityMembers},
+	"restricted":         {Visibility: models.VisibilityRestricted, Users: []string{"u2"}, Groups: []string{"band"}},
+	"restricted, empty":  {Visibility: models.VisibilityRestricted, Users: []string{}, Groups: []string{}},
+	"restricted, nil":    {Visibility: models.VisibilityRestricted},
+	"restricted, groups": {Visibility: models.VisibilityRestricted, Groups: []string{"staff"}},
+}
+
+// Who may see each rule; everyone else may not
+var allowed = map[string][]string{
+	"nil rule":           {"anonymous", "member", "named user", "group member", "admin"},
+	"empty visibility":   {"anonymous", "member", "named user", "group member", "admin"},
+	"public":             {"anonymous", "member", "named user", "group member", "admin"},
+	"members":            {"member", "named user", "group member", "admin"},
+	"restricted":         {"named user", "group member", "admin"},
+	"restricted, empty":  {"admin"},
+	"restricted, nil":    {"admin"},
+	"restricted, groups": {"group member", "admin"},
+}
+
+func expected(rule, caller string) bool {
+	for _, name := range allowed[rule] {
+		if name == caller {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCanView(t *testing.T) {
+	for ruleName, rule := range rules {
+		for callerName, caller := range callers {
+			if got, want := CanView(rule, caller), expected(ruleName, callerName); got != want {
+				t.Errorf("CanView(%s, %s) = %v, want %v", ruleName, callerName, got, want)
+			}
+		}
+	}
+}
+
+// TestFilterMatchesCanView evaluates the filter against each rule as it is
+// stored and expects the same answer as CanView
+func TestFilterMatchesCanView(t *testing.T) {
+	for ruleName, rule := range rules {
+		doc := bson.M{}
+		if rule != nil {
+			doc["access"] = bson.M{"visibility": rule.Visibility, "users": rule.Users, "groups": rule.Groups}
+		}
+
+		for callerName, caller := range callers {
+			filter := Filter("access", caller)
+			if got, want := matches(filter, doc), CanView(rule, caller); got != want {
+				t.Errorf("Filter(%s) on %s = %v, CanView = %v (filter %v)", callerName, ruleName, got, want, filter)
+			}
+		}
+	}
+}
+
+func TestAnd(t *testing.T) {
+	filter := bson.M{"title": "x"}
+	if got := And(filter, bson.M{}); len(got) != 1 || got["title"] != "x" {
+		t.Errorf("And with an empty access filter = %v", got)
+	}
+	accessFilter := Filter("access", member)
+	if got := And(bson.M{}, accessFilter); len(got) != len(accessFilter) {
+		t.Errorf("And with an empty filter = %v", got)
+	}
+	if got := And(filter, accessFilter); len(got["$and"].([]bson.M)) != 2 {
+		t.Errorf("And = %v", got)
+	}
+}
+
+// matches evaluates the subset of MongoDB query operators Filter uses:
+// $or, $in and equality, where arrays match any element and null matches
+// a missing field
+func matches(filter bson.M, doc bson.M) bool {
+	for key, condition := range filter {
+		if key == "$or" {
+			any := false
+			for _, sub := range condition.([]bson.M) {
+				any = any || matches(sub, doc)
+			}
+			if !any {
+				return false
+			}
+			continue
+		}
+
+		values := lookup(doc, key)
+		if in, ok := condition.(bson.M); ok {
+			found := false
+			for _, want := range list(in["$in"]) {
+				found = found || contains(values, want)
+			}
+			if !found {
+				return false
+			}
+			continue
+		}
+		if !contains(values, condition) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the values at a dotted path, a nil for a missing field
+// and every element of an array
+func lookup(doc bson.M, path string) []interface{} {
+	var current interface{} = doc
+	start := 0
+	for i := 0; i <= len(path); i++ {
+		if i < len(path) && path[i] != '.' {
+			continue
+		}
+		m, ok := current.(bson.M)
+		if !ok {
+			return []interface{}{nil}
+		}
+		current, ok = m[path[start:i]]
+		if !ok {
+			return []interface{}{nil}
+		}
+		start = i + 1
+	}
+
+	if strings, ok := current.([]string); ok {
+		if strings == nil {
+			return []interface{}{nil}
+		}
+		return list(strings)
+	}
+	return []interface{}{current}
+}
+
+// list returns the items of an $in operand
+func list(value interface{}) []interface{} {
+	if strings, ok := value.([]string); ok {
+		items := make([]interface{}, 0, len(strings))
+		for _, item := range strings {
+			items = append(items, item)
+		}
+		return items
+	}
+	return value.([]interface{})
+}
+
+func contains(values []interface{}, want interface{}) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package catalog
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/*
+This file helps move entries from artist names in titles to artist records. SplitTitle reads titles like "Deftones - White Pony", SplitCredit separates featured artists, and ProposeArtists combines both into what the artist migration suggests for an entry.
+*/
+
+// Featured artists are credited as "A feat. B", "A ft. B" or "A featuring B"
+var featuring = regexp.MustCompile(`(?i)\s+(?:feat\.?|ft\.?|featuring)\s+`)
+
+// SplitTitle splits "Artist - Title" at the first " - "
+func SplitTitle(title string) (artist, rest string, ok bool) {
+	parts := strings.SplitN(title, " - ", 2)
+	if len(parts) != 2 {
+		return "", title, false
+	}
+	artist, rest = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	if artist == "" || rest == "" {
+		return "", title, false
+	}
+	return artist, rest, true
+}
+
+// SplitCredit lists the artists of a credit. "&" and "and" are left alone,
+// they are part of names like "Simon & Garfunkel".
+func SplitCredit(credit string) []string {
+	var names []string
+	for _, name := range featuring.Split(credit, -1) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ProposeArtists suggests the artists of an entry and its title without
+// them, from the artist credit or else from an "Artist - Title" title.
+// ok is false when the entry gives no hint.
+func ProposeArtists(music models.Music) (title string, artists []string, ok bool) {
+	if music.Artist != "" {
+		title = music.Title
+		// The title may repeat the credit in front
+		if artist, rest, split := SplitTitle(music.Title); split && strings.EqualFold(artist, music.Artist) {
+			title = rest
+		}
+		return title, SplitCredit(music.Artist), true
+	}
+
+	artist, rest, split := SplitTitle(music.Title)
+	if !split {
+		return music.Title, nil, false
+	}
+	return rest, SplitCredit(artist), true
+}
+
+// MissingArtists returns the artist IDs that do not exist
+func MissingArtists(ctx context.Context, artistIDs []string, client *mongo.Client) ([]string, error) {
+	if len(artistIDs) == 0 {
+		return nil, nil
+	}
+
+	var artistCollection *mongo.Collection = database.OpenCollection("artists", client)
+
+	cursor, err := artistCollection.Find(ctx, bson.M{"artist_id": bson.M{"$in": artistIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var artists []models.Artist
+	if err := cursor.All(ctx, &artists); err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(artists))
+	for _, artist := range artists {
+		found[artist.ArtistID] = true
+	}
+
+	var missing []string
+	for _, id := range artistIDs {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
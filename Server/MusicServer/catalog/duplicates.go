@@ -0,0 +1,401 @@
+package catalog
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/search"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/*
+This file finds entries that are probably the same album. Titles are compared as sets of normalized words, with the artist folded in and edition or upload noise ("deluxe", "full album") left out, so "Deftones - White Pony" and "White Pony (Deftones)" come out equal. Entries sharing a video are always duplicates. Clusters groups the whole catalog into sets of suspected duplicates for the admin report.
+*/
+
+// Titles at least this similar are reported as possible duplicates
+const SimilarityThreshold = 0.8
+
+// Reasons two entries are considered duplicates
+const (
+	ReasonMusicID   = "music_id"
+	ReasonYouTubeID = "youtube_id"
+	ReasonTitle     = "title"
+)
+
+// Words that do not tell one album from another
+var noiseWords = map[string]bool{
+	"the": true, "a": true, "an": true, "and": true, "of": true,
+	"feat": true, "ft": true, "featuring": true, "by": true,
+	"deluxe": true, "edition": true, "remaster": true, "remastered": true, "expanded": true,
+	"anniversary": true, "version": true, "bonus": true, "tracks": true,
+	"full": true, "album": true, "official": true, "audio": true, "video": true,
+	"hd": true, "hq": true, "lyrics": true, "stream": true,
+}
+
+// Duplicate is an entry that matches another
+type Duplicate struct {
+	MusicID string  `json:"music_id"`
+	Title   string  `json:"title"`
+	Reason  string  `json:"reason"`
+	Score   float64 `json:"score"`
+}
+
+// TitleWords are the distinguishing words of a title and artist, sorted and
+// without repeats. Titles made only of noise words keep all their words.
+func TitleWords(title, artist string) []string {
+	all := search.Terms(title + " " + artist)
+
+	words := make([]string, 0, len(all))
+	for _, word := range all {
+		if !noiseWords[word] {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		words = all
+	}
+
+	sort.Strings(words)
+	unique := words[:0]
+	for i, word := range words {
+		if i == 0 || word != words[i-1] {
+			unique = append(unique, word)
+		}
+	}
+	return unique
+}
+
+// Similarity is the Dice coefficient of two sorted word sets, 1 when equal
+func Similarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			shared++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
+
+type titleEntry struct {
+	musicID string
+	title   string
+	words   []string
+}
+
+// TitleIndex finds entries with similar titles without comparing every pair
+type TitleIndex struct {
+	entries []titleEntry
+	byWord  map[string][]int
+}
+
+// NewTitleIndex indexes the titles of musics
+func NewTitleIndex(musics []models.Music) *TitleIndex {
+	index := &TitleIndex{byWord: map[string][]int{}}
+	for _, music := range musics {
+		index.Add(music)
+	}
+	return index
+}
+
+// Add indexes one more entry
+func (t *TitleIndex) Add(music models.Music) {
+	words := TitleWords(music.Title, music.Artist)
+	for _, word := range words {
+		t.byWord[word] = append(t.byWord[word], len(t.entries))
+	}
+	t.entries = append(t.entries, titleEntry{musicID: music.MusicID, title: music.Title, words: words})
+}
+
+// candidates are the entries sharing at least one word with words
+func (t *TitleIndex) candidates(words []string) map[int]bool {
+	found := map[int]bool{}
+	for _, word := range words {
+		for _, i := range t.byWord[word] {
+			found[i] = true
+		}
+	}
+	return found
+}
+
+// Similar lists the entries whose title is close to title and artist, best
+// match first. The entry with musicID skip is left out.
+func (t *TitleIndex) Similar(title, artist, skip string) []Duplicate {
+	words := TitleWords(title, artist)
+
+	matches := []Duplicate{}
+	for i := range t.candidates(words) {
+		entry := t.entries[i]
+		if entry.musicID == skip {
+			continue
+		}
+		if score := Similarity(words, entry.words); score >= SimilarityThreshold {
+			matches = append(matches, Duplicate{MusicID: entry.musicID, Title: entry.title, Reason: ReasonTitle, Score: score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].MusicID < matches[j].MusicID
+	})
+	return matches
+}
+
+// Link is one reason two entries of a cluster look alike. A and B are the
+// _id of the entries, their music_id may be the reason for the link.
+type Link struct {
+	A      string  `json:"a"`
+	B      string  `json:"b"`
+	Reason string  `json:"reason"`
+	Score  float64 `json:"score"`
+}
+
+// Cluster is a set of entries that are probably the same album
+type Cluster struct {
+	Musics []models.Music
+	Links  []Link
+}
+
+// Clusters groups musics that share a video or have similar titles. Entries
+// without a look-alike are left out; the largest clusters come first.
+func Clusters(musics []models.Music) []Cluster {
+	parent := make([]int, len(musics))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	var links []Link
+	var linkFrom []int
+	linked := map[[2]int]bool{}
+	link := func(i, j int, reason string, score float64) {
+		if i > j {
+			i, j = j, i
+		}
+		if i == j || linked[[2]int{i, j}] {
+			return
+		}
+		linked[[2]int{i, j}] = true
+		parent[find(i)] = find(j)
+		links = append(links, Link{A: musics[i].ID.Hex(), B: musics[j].ID.Hex(), Reason: reason, Score: score})
+		linkFrom = append(linkFrom, i)
+	}
+
+	// Older edits could reuse a music_id, so those are checked too
+	byID := map[string]int{}
+	byVideo := map[string]int{}
+	for i, music := range musics {
+		if first, ok := byID[music.MusicID]; ok {
+			link(first, i, ReasonMusicID, 1)
+		} else {
+			byID[music.MusicID] = i
+		}
+		if first, ok := byVideo[music.YouTubeID]; ok && music.YouTubeID != "" {
+			link(first, i, ReasonYouTubeID, 1)
+		} else {
+			byVideo[music.YouTubeID] = i
+		}
+	}
+
+	index := NewTitleIndex(musics)
+	for i, entry := range index.entries {
+		for j := range index.candidates(entry.words) {
+			if j <= i {
+				continue
+			}
+			if score := Similarity(entry.words, index.entries[j].words); score >= SimilarityThreshold {
+				link(i, j, ReasonTitle, score)
+			}
+		}
+	}
+
+	members := map[int][]int{}
+	for i := range musics {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+	linksOf := map[int][]Link{}
+	for k, l := range links {
+		root := find(linkFrom[k])
+		linksOf[root] = append(linksOf[root], l)
+	}
+
+	var clusters []Cluster
+	for root, indexes := range members {
+		if len(indexes) < 2 {
+			continue
+		}
+		cluster := Cluster{Links: linksOf[root]}
+		for _, i := range indexes {
+			cluster.Musics = append(cluster.Musics, musics[i])
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].Musics) != len(clusters[j].Musics) {
+			return len(clusters[i].Musics) > len(clusters[j].Musics)
+		}
+		return clusters[i].Musics[0].Title < clusters[j].Musics[0].Title
+	})
+	return clusters
+}
+
+// BetterReview reports whether a has the more complete review of the two:
+// a written review beats a blank one, a ranked one an unranked one, and
+// otherwise the longer text wins
+func BetterReview(a, b models.Music) bool {
+	reviewA, reviewB := strings.TrimSpace(a.AdminReview), strings.TrimSpace(b.AdminReview)
+	if (reviewA != "") != (reviewB != "") {
+		return reviewA != ""
+	}
+
+	rankedA := a.Ranking.RankingValue != models.NotRankedValue && a.Ranking.RankingValue != 0
+	rankedB := b.Ranking.RankingValue != models.NotRankedValue && b.Ranking.RankingValue != 0
+	if rankedA != rankedB {
+		return rankedA
+	}
+	return len(reviewA) > len(reviewB)
+}
+
+// loadCatalog reads the fields duplicate checks need from every entry
+func loadCatalog(ctx context.Context, client *mongo.Client) ([]models.Music, error) {
+	var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+	projection := bson.M{"music_id": 1, "title": 1, "artist": 1, "youtube_id": 1}
+	cursor, err := musicCollection.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var musics []models.Music
+	err = cursor.All(ctx, &musics)
+	return musics, err
+}
+
+// FindDuplicates checks a new or edited entry against the catalog. It
+// returns the entries that already use its music_id or video, which must
+// be refused, and those with a similar title, which only deserve a warning.
+// skip is the music_id the entry had before an edit, so it does not match itself.
+func FindDuplicates(ctx context.Context, music models.Music, skip string, client *mongo.Client) ([]Duplicate, []Duplicate, error) {
+	musics, err := loadCatalog(ctx, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var exact []Duplicate
+	for _, other := range musics {
+		if other.MusicID == skip {
+			continue
+		}
+		switch {
+		case other.MusicID == music.MusicID && music.MusicID != "":
+			exact = append(exact, Duplicate{MusicID: other.MusicID, Title: other.Title, Reason: ReasonMusicID, Score: 1})
+		case other.YouTubeID == music.YouTubeID && music.YouTubeID != "":
+			exact = append(exact, Duplicate{MusicID: other.MusicID, Title: other.Title, Reason: ReasonYouTubeID, Score: 1})
+		}
+	}
+
+	similar := NewTitleIndex(musics).Similar(music.Title, music.Artist, skip)
+	return exact, similar, nil
+}
+
+// Merge folds duplicates into keep and returns the fields of keep to set.
+// keep gets the best review with its ranking, the genres of all entries and
+// any cover, artist, artists, album metadata, track list or MusicBrainz
+// data it lacks.
+func Merge(keep models.Music, duplicates []models.Music) bson.M {
+	changes := bson.M{}
+
+	best := keep
+	genres := keep.Genre
+	seenGenre := map[int]bool{}
+	for _, genre := range keep.Genre {
+		seenGenre[genre.GenreID] = true
+	}
+
+	for _, other := range duplicates {
+		if BetterReview(other, best) {
+			best = other
+		}
+		for _, genre := range other.Genre {
+			if !seenGenre[genre.GenreID] {
+				seenGenre[genre.GenreID] = true
+				genres = append(genres, genre)
+			}
+		}
+
+		if keep.Cover == "" && other.Cover != "" {
+			keep.Cover, changes["cover"] = other.Cover, other.Cover
+		}
+		if keep.Artist == "" && other.Artist != "" {
+			keep.Artist, changes["artist"] = other.Artist, other.Artist
+		}
+		if len(keep.ArtistIDs) == 0 && len(other.ArtistIDs) > 0 {
+			keep.ArtistIDs, changes["artist_ids"] = other.ArtistIDs, other.ArtistIDs
+		}
+		if keep.ReleaseYear == 0 && other.ReleaseYear != 0 {
+			keep.ReleaseYear, changes["release_year"] = other.ReleaseYear, other.ReleaseYear
+		}
+		if len(keep.Tracks) == 0 && len(other.Tracks) > 0 {
+			keep.Tracks, changes["tracks"] = other.Tracks, other.Tracks
+		}
+		if keep.Label == "" && other.Label != "" {
+			keep.Label, changes["label"] = other.Label, other.Label
+		}
+		if keep.Country == "" && other.Country != "" {
+			keep.Country, changes["country"] = other.Country, other.Country
+		}
+		if keep.TrackCount == 0 && other.TrackCount != 0 {
+			keep.TrackCount, changes["track_count"] = other.TrackCount, other.TrackCount
+		}
+		if keep.DurationSeconds == 0 && other.DurationSeconds != 0 {
+			keep.DurationSeconds, changes["duration_seconds"] = other.DurationSeconds, other.DurationSeconds
+		}
+		if keep.Explicit == nil && other.Explicit != nil {
+			keep.Explicit, changes["explicit"] = other.Explicit, *other.Explicit
+		}
+		if len(keep.Links) == 0 && len(other.Links) > 0 {
+			keep.Links, changes["links"] = other.Links, other.Links
+		}
+		if keep.LinerNotes == "" && other.LinerNotes != "" {
+			keep.LinerNotes, changes["liner_notes"] = other.LinerNotes, other.LinerNotes
+		}
+		if keep.MusicBrainz == nil && other.MusicBrainz != nil {
+			keep.MusicBrainz, changes["musicbrainz"] = other.MusicBrainz, other.MusicBrainz
+		}
+	}
+
+	if best.ID != keep.ID {
+		changes["admin_review"] = best.AdminReview
+		changes["ranking"] = best.Ranking
+	}
+	if len(genres) != len(keep.Genre) {
+		changes["genre"] = genres
+	}
+	return changes
+}
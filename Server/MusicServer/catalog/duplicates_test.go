@@ -0,0 +1,84 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/* This file checks how duplicates are merged: which review wins and that entries sharing a music_id are still told apart by their _id. */
+
+var (
+	unranked = models.Ranking{RankingValue: models.NotRankedValue, RankingName: models.NotRankedName}
+	ranked   = models.Ranking{RankingValue: 1, RankingName: "Excellent"}
+)
+
+func TestBetterReview(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b models.Music
+		want bool
+	}{
+		{"written beats blank", models.Music{AdminReview: "Great"}, models.Music{AdminReview: "  "}, true},
+		{"blank loses to written", models.Music{}, models.Music{AdminReview: "Great"}, false},
+		{"ranked beats unranked", models.Music{AdminReview: "Ok", Ranking: ranked}, models.Music{AdminReview: "Much longer review", Ranking: unranked}, true},
+		{"zero value counts as unranked", models.Music{AdminReview: "Ok"}, models.Music{AdminReview: "Ok", Ranking: ranked}, false},
+		{"longer text wins", models.Music{AdminReview: "A longer review", Ranking: ranked}, models.Music{AdminReview: "Short", Ranking: ranked}, true},
+		{"equal is not better", models.Music{AdminReview: "Same"}, models.Music{AdminReview: "Same"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := BetterReview(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: BetterReview = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMergeSharedMusicID(t *testing.T) {
+	keep := models.Music{ID: bson.NewObjectID(), MusicID: "album-1", Title: "White Pony", Ranking: unranked}
+	duplicate := models.Music{
+		ID:          bson.NewObjectID(),
+		MusicID:     "album-1",
+		Title:       "White Pony",
+		AdminReview: "A landmark record",
+		Ranking:     ranked,
+		Label:       "Maverick",
+	}
+
+	changes := Merge(keep, []models.Music{duplicate})
+
+	if changes["admin_review"] != duplicate.AdminReview {
+		t.Errorf("admin_review = %v, want the duplicate's review", changes["admin_review"])
+	}
+	if changes["ranking"] != duplicate.Ranking {
+		t.Errorf("ranking = %v, want %v", changes["ranking"], duplicate.Ranking)
+	}
+	if changes["label"] != "Maverick" {
+		t.Errorf("label = %v, want Maverick", changes["label"])
+	}
+}
+
+func TestMergeKeepsBetterReview(t *testing.T) {
+	keep := models.Music{ID: bson.NewObjectID(), MusicID: "album-1", AdminReview: "A landmark record", Ranking: ranked}
+	duplicate := models.Music{ID: bson.NewObjectID(), MusicID: "album-1", AdminReview: "Fine", Ranking: unranked}
+
+	changes := Merge(keep, []models.Music{duplicate})
+
+	if _, ok := changes["admin_review"]; ok {
+		t.Errorf("the kept review was replaced: %v", changes)
+	}
+}
+
+func TestMergeGenres(t *testing.T) {
+	keep := models.Music{ID: bson.NewObjectID(), Genre: []models.Genre{{GenreID: 1, GenreName: "Rock"}}}
+	duplicates := []models.Music{
+		{ID: bson.NewObjectID(), Genre: []models.Genre{{GenreID: 1, GenreName: "Rock"}, {GenreID: 2, GenreName: "Metal"}}},
+		{ID: bson.NewObjectID(), Genre: []models.Genre{{GenreID: 2, GenreName: "Metal"}}},
+	}
+
+	genres, ok := Merge(keep, duplicates)["genre"].([]models.Genre)
+	if !ok || len(genres) != 2 || genres[1].GenreID != 2 {
+		t.Errorf("genre = %v, want Rock and Metal", genres)
+	}
+}
@@ -0,0 +1,163 @@
+package catalog
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/markdown"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/youtube"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/*
+This file turns a PATCH /edit/:music_id body into a MongoDB update. Values are normalized like in NewMusic, optional fields sent empty are removed from the entry, and the rest is validated. Checks that need the database (genres, artists, duplicates and the video range against stored timestamps) are left to the caller.
+*/
+
+// EditableFields are the JSON fields PATCH /edit/:music_id accepts
+var EditableFields = jsonFields(reflect.TypeOf(models.MusicUpdate{}))
+
+func jsonFields(t reflect.Type) []string {
+	fields := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// Edit is the change to make to an entry
+type Edit struct {
+	Set   bson.M
+	Unset bson.M
+}
+
+// Document is the MongoDB update for the edit
+func (e Edit) Document() bson.M {
+	update := bson.M{}
+	if len(e.Set) > 0 {
+		update["$set"] = e.Set
+	}
+	if len(e.Unset) > 0 {
+		update["$unset"] = e.Unset
+	}
+	return update
+}
+
+// Empty reports whether the edit changes nothing
+func (e Edit) Empty() bool {
+	return len(e.Set) == 0 && len(e.Unset) == 0
+}
+
+// unsetEmpty removes an optional field sent empty from the request and records it
+// for $unset
+func unsetEmpty[T any](field **T, name string, empty bool, unset bson.M) {
+	if *field != nil && empty {
+		unset[name] = ""
+		*field = nil
+	}
+}
+
+// NewEdit normalizes and validates an edit. A new video replaces the stored
+// timestamps with those of its URL unless youtube_start or youtube_end are
+// sent too. Genres are set as sent, the caller resolves them.
+func NewEdit(req models.MusicUpdate) (Edit, error) {
+	edit := Edit{Set: bson.M{}, Unset: bson.M{}}
+
+	trim := func(value *string) {
+		if value != nil {
+			*value = strings.TrimSpace(*value)
+		}
+	}
+	trim(req.MusicID)
+	trim(req.Title)
+	trim(req.AlbumImg)
+	trim(req.Artist)
+	trim(req.Label)
+	if req.Country != nil {
+		*req.Country = strings.ToUpper(strings.TrimSpace(*req.Country))
+	}
+	if req.LinerNotes != nil {
+		*req.LinerNotes = markdown.Sanitize(*req.LinerNotes)
+	}
+
+	unsetEmpty(&req.Artist, "artist", req.Artist != nil && *req.Artist == "", edit.Unset)
+	unsetEmpty(&req.ArtistIDs, "artist_ids", req.ArtistIDs != nil && len(*req.ArtistIDs) == 0, edit.Unset)
+	unsetEmpty(&req.ReleaseYear, "release_year", req.ReleaseYear != nil && *req.ReleaseYear == 0, edit.Unset)
+	unsetEmpty(&req.Tracks, "tracks", req.Tracks != nil && len(*req.Tracks) == 0, edit.Unset)
+	unsetEmpty(&req.Label, "label", req.Label != nil && *req.Label == "", edit.Unset)
+	unsetEmpty(&req.Country, "country", req.Country != nil && *req.Country == "", edit.Unset)
+	unsetEmpty(&req.TrackCount, "track_count", req.TrackCount != nil && *req.TrackCount == 0, edit.Unset)
+	unsetEmpty(&req.DurationSeconds, "duration_seconds", req.DurationSeconds != nil && *req.DurationSeconds == 0, edit.Unset)
+	unsetEmpty(&req.Links, "links", req.Links != nil && len(*req.Links) == 0, edit.Unset)
+	unsetEmpty(&req.LinerNotes, "liner_notes", req.LinerNotes != nil && *req.LinerNotes == "", edit.Unset)
+
+	if err := Validate.Struct(req); err != nil {
+		return edit, err
+	}
+
+	if req.YouTubeID != nil {
+		video, err := youtube.Parse(*req.YouTubeID)
+		if err != nil {
+			return edit, fmt.Errorf("invalid youtube_id: %w", err)
+		}
+		edit.Set["youtube_id"] = video.ID
+		edit.Set["youtube_start"] = video.Start
+		edit.Set["youtube_end"] = video.End
+	}
+	if req.YouTubeStart != nil {
+		edit.Set["youtube_start"] = int(*req.YouTubeStart)
+	}
+	if req.YouTubeEnd != nil {
+		edit.Set["youtube_end"] = int(*req.YouTubeEnd)
+	}
+
+	if req.MusicID != nil {
+		edit.Set["music_id"] = *req.MusicID
+	}
+	if req.Title != nil {
+		edit.Set["title"] = *req.Title
+	}
+	if req.AlbumImg != nil {
+		edit.Set["album_img"] = *req.AlbumImg
+	}
+	if req.Genre != nil {
+		edit.Set["genre"] = *req.Genre
+	}
+	if req.Artist != nil {
+		edit.Set["artist"] = *req.Artist
+	}
+	if req.ArtistIDs != nil {
+		edit.Set["artist_ids"] = *req.ArtistIDs
+	}
+	if req.ReleaseYear != nil {
+		edit.Set["release_year"] = *req.ReleaseYear
+	}
+	if req.Tracks != nil {
+		edit.Set["tracks"] = *req.Tracks
+	}
+	if req.Label != nil {
+		edit.Set["label"] = *req.Label
+	}
+	if req.Country != nil {
+		edit.Set["country"] = *req.Country
+	}
+	if req.TrackCount != nil {
+		edit.Set["track_count"] = *req.TrackCount
+	}
+	if req.DurationSeconds != nil {
+		edit.Set["duration_seconds"] = *req.DurationSeconds
+	}
+	if req.Explicit != nil {
+		edit.Set["explicit"] = *req.Explicit
+	}
+	if req.Links != nil {
+		edit.Set["links"] = *req.Links
+	}
+	if req.LinerNotes != nil {
+		edit.Set["liner_notes"] = *req.LinerNotes
+	}
+
+	return edit, nil
+}
@@ -0,0 +1,450 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/youtube"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/*
+This file imports many music entries at once from CSV or JSON Lines. Plan checks every row with the same rules as POST /addmusic and reports errors, warnings and the planned action per row without writing anything; Apply then inserts the valid rows, all in one transaction or in transactional batches.
+*/
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+
+	MaxRows = 5000
+)
+
+// Row actions in a report
+const (
+	ActionCreate      = "create"
+	ActionCreated     = "created"
+	ActionSkip        = "skip"
+	ActionNotImported = "not_imported"
+)
+
+var (
+	ErrUnknownFormat = errors.New("format must be csv or jsonl")
+	ErrTooManyRows   = fmt.Errorf("an import can have at most %d rows", MaxRows)
+	ErrInvalidRows   = errors.New("some rows are invalid, fix them or skip them with skip_invalid")
+	ErrDuplicateRows = errors.New("some rows were added by someone else during the import")
+)
+
+// CSV columns; the first five are required. genre lists genre IDs or names
+// separated by "|".
+var csvColumns = []string{"music_id", "title", "album_img", "youtube_id", "genre", "admin_review", "artist", "release_year",
+	"label", "country", "track_count", "duration_seconds", "explicit", "youtube_start", "youtube_end"}
+
+const requiredCSVColumns = 5
+
+// Columns of a CSV export that an import reads past
+var exportOnlyColumns = map[string]bool{"ranking": true, "url": true}
+
+// Row is one parsed line of an import file
+type Row struct {
+	Line       int
+	Request    models.MusicRequest
+	ParseError string
+}
+
+// RowReport is the outcome of one row
+type RowReport struct {
+	Line     int      `json:"row"`
+	MusicID  string   `json:"music_id,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Action   string   `json:"action"`
+	Errors   []string `json:"errors,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+
+	music models.Music
+}
+
+// Report is the outcome of a whole import
+type Report struct {
+	DryRun      bool        `json:"dry_run"`
+	Total       int         `json:"total"`
+	Valid       int         `json:"valid"`
+	Invalid     int         `json:"invalid"`
+	WithWarning int         `json:"with_warnings"`
+	Created     int         `json:"created"`
+	Batches     int         `json:"batches_committed"`
+	Error       string      `json:"error,omitempty"`
+	Rows        []RowReport `json:"rows"`
+}
+
+// Parse reads an import file in the given format
+func Parse(format string, r io.Reader) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatJSONL:
+		return parseJSONL(r)
+	}
+	return nil, ErrUnknownFormat
+}
+
+func parseCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		known := exportOnlyColumns[name]
+		for _, column := range csvColumns {
+			known = known || column == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown CSV column %q, columns are %s", name, strings.Join(csvColumns, ", "))
+		}
+		columns[name] = i
+	}
+	for _, column := range csvColumns[:requiredCSVColumns] {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("CSV column %q is required", column)
+		}
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, Row{Line: parseErr.Line, ParseError: parseErr.Err.Error()})
+				continue
+			}
+			return nil, err
+		}
+		if len(rows) >= MaxRows {
+			return nil, ErrTooManyRows
+		}
+		line, _ := reader.FieldPos(0)
+
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row := Row{Line: line}
+		row.Request = models.MusicRequest{
+			MusicID:     cell("music_id"),
+			Title:       cell("title"),
+			AlbumImg:    cell("album_img"),
+			YouTubeID:   cell("youtube_id"),
+			Genre:       parseGenreCell(cell("genre")),
+			AdminReview: cell("admin_review"),
+			Artist:      cell("artist"),
+			Label:       cell("label"),
+			Country:     cell("country"),
+		}
+
+		var problems []string
+		numbers := map[string]*int{
+			"release_year":     &row.Request.ReleaseYear,
+			"track_count":      &row.Request.TrackCount,
+			"duration_seconds": &row.Request.DurationSeconds,
+		}
+		for _, name := range []string{"release_year", "track_count", "duration_seconds"} {
+			if value := cell(name); value != "" {
+				number, err := strconv.Atoi(value)
+				if err != nil {
+					problems = append(problems, name+" must be a number")
+				}
+				*numbers[name] = number
+			}
+		}
+		if value := cell("explicit"); value != "" {
+			explicit, err := strconv.ParseBool(value)
+			if err != nil {
+				problems = append(problems, "explicit must be true or false")
+			} else {
+				row.Request.Explicit = &explicit
+			}
+		}
+		for _, name := range []string{"youtube_start", "youtube_end"} {
+			value := cell(name)
+			if value == "" {
+				continue
+			}
+			seconds, err := youtube.ParseTime(value)
+			if err != nil {
+				problems = append(problems, name+" must be seconds or a timestamp like 1m30s")
+				continue
+			}
+			if name == "youtube_start" {
+				row.Request.YouTubeStart = &seconds
+			} else {
+				row.Request.YouTubeEnd = &seconds
+			}
+		}
+		row.ParseError = strings.Join(problems, "; ")
+
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseGenreCell reads "1|Rock|Heavy Metal" as genre IDs and names
+func parseGenreCell(value string) []models.Genre {
+	var genres []models.Genre
+	for _, part := range strings.Split(value, "|") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if id, err := strconv.Atoi(part); err == nil {
+			genres = append(genres, models.Genre{GenreID: id})
+		} else {
+			genres = append(genres, models.Genre{GenreName: part})
+		}
+	}
+	return genres
+}
+
+func parseJSONL(r io.Reader) ([]Row, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var rows []Row
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(rows) >= MaxRows {
+			return nil, ErrTooManyRows
+		}
+
+		row := Row{Line: line}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.Request); err != nil {
+			row.ParseError = "invalid JSON: " + err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+// Plan checks every row and reports what an import would do, without writing
+func Plan(ctx context.Context, rows []Row, client *mongo.Client) (*Report, error) {
+	genres, err := LoadGenres(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := loadCatalog(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	takenIDs := make(map[string]bool, len(existing))
+	usedVideos := make(map[string]string, len(existing))
+	for _, music := range existing {
+		takenIDs[music.MusicID] = true
+		usedVideos[music.YouTubeID] = music.MusicID
+	}
+	titles := NewTitleIndex(existing)
+
+	var artistIDs []string
+	for _, row := range rows {
+		artistIDs = append(artistIDs, row.Request.ArtistIDs...)
+	}
+	missing, err := MissingArtists(ctx, artistIDs, client)
+	if err != nil {
+		return nil, err
+	}
+	missingArtists := make(map[string]bool, len(missing))
+	for _, id := range missing {
+		missingArtists[id] = true
+	}
+
+	report := &Report{DryRun: true, Total: len(rows), Rows: make([]RowReport, 0, len(rows))}
+	rowOfID := map[string]int{}
+	rowOfVideo := map[string]int{}
+
+	for _, row := range rows {
+		result := RowReport{Line: row.Line, MusicID: row.Request.MusicID, Title: row.Request.Title}
+		fail := func(message string) { result.Errors = append(result.Errors, message) }
+		warn := func(message string) { result.Warnings = append(result.Warnings, message) }
+
+		if row.ParseError != "" {
+			fail(row.ParseError)
+		}
+		if row.Request.Access != nil {
+			fail("access rules cannot be imported, set them on the entry afterwards")
+		}
+		for _, id := range row.Request.ArtistIDs {
+			if missingArtists[id] {
+				fail("artist " + id + " does not exist")
+			}
+		}
+		if row.Request.ImportMetadata {
+			warn("import_metadata is ignored in bulk imports, run cmd/spotify-backfill afterwards")
+		}
+
+		music, err := NewMusic(row.Request)
+		if err != nil {
+			fail(err.Error())
+		}
+
+		if err == nil {
+			resolved, genreWarnings, err := genres.Resolve(music.Genre)
+			if err != nil {
+				fail(err.Error())
+			}
+			for _, warning := range genreWarnings {
+				warn(warning)
+			}
+			music.Genre = resolved
+
+			if err := Finish(&music); err != nil {
+				fail("validation failed: " + err.Error())
+			}
+
+			if takenIDs[music.MusicID] {
+				fail("music_id " + music.MusicID + " already exists")
+			} else if first, ok := rowOfID[music.MusicID]; ok && music.MusicID != "" {
+				fail(fmt.Sprintf("music_id %s is also on row %d", music.MusicID, first))
+			}
+			if other, ok := usedVideos[music.YouTubeID]; ok {
+				fail("video " + music.YouTubeID + " is already used by " + other)
+			} else if first, ok := rowOfVideo[music.YouTubeID]; ok {
+				fail(fmt.Sprintf("video %s is also on row %d", music.YouTubeID, first))
+			}
+			for _, similar := range titles.Similar(music.Title, music.Artist, music.MusicID) {
+				warn(fmt.Sprintf("title looks like %s %q", similar.MusicID, similar.Title))
+			}
+			titles.Add(music)
+
+			if _, ok := rowOfID[music.MusicID]; !ok {
+				rowOfID[music.MusicID] = row.Line
+			}
+			if _, ok := rowOfVideo[music.YouTubeID]; !ok {
+				rowOfVideo[music.YouTubeID] = row.Line
+			}
+		}
+
+		result.MusicID, result.music = music.MusicID, music
+		if music.Title != "" {
+			result.Title = music.Title
+		}
+
+		result.Action = ActionCreate
+		if len(result.Errors) > 0 {
+			result.Action = ActionSkip
+			report.Invalid++
+		} else {
+			report.Valid++
+		}
+		if len(result.Warnings) > 0 {
+			report.WithWarning++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+	return report, nil
+}
+
+// markDuplicateRows turns the rows the unique indexes refused into invalid
+// rows. Their music_id or video was added after the import was planned.
+func markDuplicateRows(report *Report, batch []int, err error) {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) {
+		return
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Index < 0 || writeErr.Index >= len(batch) {
+			continue
+		}
+		row := &report.Rows[batch[writeErr.Index]]
+		if strings.Contains(writeErr.Message, "youtube_id") {
+			row.Errors = append(row.Errors, "video "+row.music.YouTubeID+" already exists")
+		} else {
+			row.Errors = append(row.Errors, "music_id "+row.MusicID+" already exists")
+		}
+		row.Action = ActionSkip
+		report.Valid--
+		report.Invalid++
+	}
+}
+
+// Apply inserts the valid rows of a planned import. With batchSize 0 the
+// whole import is one transaction, otherwise every batch is its own and the
+// import stops at the first batch that fails. Invalid rows abort the import
+// unless skipInvalid is set.
+func Apply(ctx context.Context, report *Report, batchSize int, skipInvalid bool, client *mongo.Client) error {
+	if report.Invalid > 0 && !skipInvalid {
+		return ErrInvalidRows
+	}
+	report.DryRun = false
+
+	var pending []int
+	for i, row := range report.Rows {
+		if row.Action == ActionCreate {
+			pending = append(pending, i)
+		}
+	}
+	if batchSize <= 0 {
+		batchSize = len(pending)
+	}
+
+	var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+	for start := 0; start < len(pending); start += batchSize {
+		batch := pending[start:min(start+batchSize, len(pending))]
+
+		documents := make([]interface{}, 0, len(batch))
+		for _, i := range batch {
+			documents = append(documents, report.Rows[i].music)
+		}
+
+		err := database.RunTransaction(ctx, client, func(ctx context.Context) error {
+			_, err := musicCollection.InsertMany(ctx, documents)
+			return err
+		})
+		if err != nil {
+			for _, i := range pending[start:] {
+				report.Rows[i].Action = ActionNotImported
+			}
+			report.Error = fmt.Sprintf("batch %d failed: %v", report.Batches+1, err)
+			if mongo.IsDuplicateKeyError(err) {
+				markDuplicateRows(report, batch, err)
+				return ErrDuplicateRows
+			}
+			return err
+		}
+
+		for _, i := range batch {
+			report.Rows[i].Action = ActionCreated
+		}
+		report.Created += len(batch)
+		report.Batches++
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/markdown"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/youtube"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/*
+This file holds the rules a new music entry has to pass, shared by POST /addmusic, the bulk import endpoint and the import command: the YouTube reference is normalized, defaults are filled in, the struct is validated, genres must exist and a music_id may only be used once.
+*/
+
+// Validate checks models and requests, with the custom tags they use. It is
+// shared with the controllers so the tags are registered only once.
+var Validate = newValidator()
+
+// newValidator returns a validator with the custom tags used by the models
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("youtube_id", func(fl validator.FieldLevel) bool {
+		return youtube.ValidID(fl.Field().String())
+	})
+	return v
+}
+
+// NewMusic builds a music entry from a request, keeping only the video ID of
+// a pasted YouTube URL. Explicit timestamps win over the ones in the URL,
+// and liner notes are sanitized.
+func NewMusic(req models.MusicRequest) (models.Music, error) {
+	video, err := youtube.Parse(req.YouTubeID)
+	if err != nil {
+		return models.Music{}, fmt.Errorf("invalid youtube_id: %w", err)
+	}
+
+	if req.YouTubeStart != nil {
+		video.Start = *req.YouTubeStart
+	}
+	if req.YouTubeEnd != nil {
+		video.End = *req.YouTubeEnd
+	}
+
+	return models.Music{
+		MusicID:         strings.TrimSpace(req.MusicID),
+		Title:           strings.TrimSpace(req.Title),
+		AlbumImg:        strings.TrimSpace(req.AlbumImg),
+		YouTubeID:       video.ID,
+		YouTubeStart:    video.Start,
+		YouTubeEnd:      video.End,
+		Genre:           req.Genre,
+		AdminReview:     req.AdminReview,
+		Ranking:         req.Ranking,
+		Access:          req.Access,
+		Artist:          strings.TrimSpace(req.Artist),
+		ArtistIDs:       req.ArtistIDs,
+		ReleaseYear:     req.ReleaseYear,
+		Tracks:          req.Tracks,
+		Label:           strings.TrimSpace(req.Label),
+		Country:         strings.ToUpper(strings.TrimSpace(req.Country)),
+		TrackCount:      req.TrackCount,
+		DurationSeconds: req.DurationSeconds,
+		Explicit:        req.Explicit,
+		Links:           req.Links,
+		LinerNotes:      markdown.Sanitize(req.LinerNotes),
+	}, nil
+}
+
+// Finish fills in the defaults of a new entry and validates it
+func Finish(music *models.Music) error {
+	// An empty review would fail validation, a blank one marks "not reviewed"
+	if music.AdminReview == "" {
+		music.AdminReview = " "
+	}
+
+	if music.Ranking.RankingValue == 0 || music.Ranking.RankingName == "" {
+		music.Ranking = models.Ranking{
+			RankingValue: models.NotRankedValue,
+			RankingName:  models.NotRankedName,
+		}
+	}
+
+	return Validate.Struct(music)
+}
+
+// Genres is the genre list entries are checked against
+type Genres struct {
+	byID   map[int]string
+	byName map[string]models.Genre
+}
+
+// LoadGenres reads the genre collection
+func LoadGenres(ctx context.Context, client *mongo.Client) (Genres, error) {
+	var genreCollection *mongo.Collection = database.OpenCollection("genres", client)
+
+	cursor, err := genreCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return Genres{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var list []models.Genre
+	if err := cursor.All(ctx, &list); err != nil {
+		return Genres{}, err
+	}
+
+	genres := Genres{byID: make(map[int]string, len(list)), byName: make(map[string]models.Genre, len(list))}
+	for _, genre := range list {
+		genres.byID[genre.GenreID] = genre.GenreName
+		genres.byName[strings.ToLower(genre.GenreName)] = genre
+	}
+	return genres, nil
+}
+
+// Resolve checks that every genre exists, looked up by ID or else by name,
+// and returns them with their stored names. A name that does not match its
+// ID is corrected and reported as a warning.
+func (g Genres) Resolve(requested []models.Genre) ([]models.Genre, []string, error) {
+	resolved := make([]models.Genre, 0, len(requested))
+	var warnings []string
+	seen := map[int]bool{}
+
+	for _, genre := range requested {
+		var found models.Genre
+		switch {
+		case genre.GenreID != 0:
+			name, ok := g.byID[genre.GenreID]
+			if !ok {
+				return nil, nil, fmt.Errorf("genre_id %d does not exist", genre.GenreID)
+			}
+			if genre.GenreName != "" && !strings.EqualFold(genre.GenreName, name) {
+				warnings = append(warnings, fmt.Sprintf("genre %d is named %q, not %q", genre.GenreID, name, genre.GenreName))
+			}
+			found = models.Genre{GenreID: genre.GenreID, GenreName: name}
+		case genre.GenreName != "":
+			var ok bool
+			found, ok = g.byName[strings.ToLower(strings.TrimSpace(genre.GenreName))]
+			if !ok {
+				return nil, nil, fmt.Errorf("genre %q does not exist", genre.GenreName)
+			}
+		default:
+			return nil, nil, fmt.Errorf("genre needs a genre_id or genre_name")
+		}
+
+		if !seen[found.GenreID] {
+			seen[found.GenreID] = true
+			resolved = append(resolved, found)
+		}
+	}
+	return resolved, warnings, nil
+}
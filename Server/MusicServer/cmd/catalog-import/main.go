@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/catalog"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/* This file is a command that imports music entries from a CSV or JSON Lines file with the same checks and report as POST /admin/musics/import. Run it from Server/MusicServer so it picks up the same .env as the server:
+
+   go run ./cmd/catalog-import -file albums.csv -dry-run
+
+It exits with status 1 when a row is invalid or a batch fails. The server's search indexes pick up the new entries on its next restart or catalog edit. */
+
+func main() {
+	path := flag.String("file", "", "CSV or JSON Lines file to import, - for stdin")
+	format := flag.String("format", "", "csv or jsonl (default: from the file extension)")
+	dryRun := flag.Bool("dry-run", false, "check the rows and print the report without saving")
+	batchSize := flag.Int("batch-size", 0, "rows per transaction (0 imports everything in one)")
+	skipInvalid := flag.Bool("skip-invalid", false, "import the valid rows even when others are invalid")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	if *path == "" {
+		log.Fatal("-file is required")
+	}
+	if *format == "" {
+		*format = catalog.FormatCSV
+		if ext := strings.ToLower(filepath.Ext(*path)); ext == ".jsonl" || ext == ".ndjson" {
+			*format = catalog.FormatJSONL
+		}
+	}
+
+	var input io.Reader = os.Stdin
+	if *path != "-" {
+		file, err := os.Open(*path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	rows, err := catalog.Parse(*format, input)
+	if err != nil {
+		log.Fatal("Failed to read import file: ", err)
+	}
+
+	var client *mongo.Client = database.Connect()
+	if client == nil {
+		log.Fatal("Failed to connect to MongoDB")
+	}
+	defer client.Disconnect(context.Background())
+
+	ctx := context.Background()
+
+	report, err := catalog.Plan(ctx, rows, client)
+	if err != nil {
+		log.Fatal("Failed to check import: ", err)
+	}
+	if !*dryRun {
+		if err = catalog.Apply(ctx, report, *batchSize, *skipInvalid, client); err != nil && report.Error == "" {
+			report.Error = err.Error()
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		printReport(report)
+	}
+
+	if report.Error != "" || (report.Invalid > 0 && !*skipInvalid) {
+		os.Exit(1)
+	}
+}
+
+// printReport lists the rows with problems, then the totals
+func printReport(report *catalog.Report) {
+	for _, row := range report.Rows {
+		for _, message := range row.Errors {
+			fmt.Printf("row %d (%s): error: %s\n", row.Line, row.MusicID, message)
+		}
+		for _, message := range row.Warnings {
+			fmt.Printf("row %d (%s): warning: %s\n", row.Line, row.MusicID, message)
+		}
+	}
+
+	fmt.Printf("%d rows: %d valid, %d invalid, %d with warnings\n", report.Total, report.Valid, report.Invalid, report.WithWarning)
+	if report.DryRun {
+		fmt.Println("Dry run, nothing was saved")
+		return
+	}
+	fmt.Printf("%d created in %d batches\n", report.Created, report.Batches)
+	if report.Error != "" {
+		fmt.Println("Import stopped:", report.Error)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/spotify"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/* This file is a command that fills the artist, release year, track list and any empty title or cover of existing music entries from their Spotify albums. Run it from Server/MusicServer so it picks up the same .env as the server:
+
+   go run ./cmd/spotify-backfill -dry-run
+
+The server's search indexes pick up the changes on its next restart or catalog edit. */
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "print the changes without saving them")
+	overwrite := flag.Bool("overwrite", false, "replace fields that already have a value")
+	only := flag.String("id", "", "backfill a single music_id")
+	limit := flag.Int("limit", 0, "stop after this many entries (0 for all)")
+	delay := flag.Duration("delay", 200*time.Millisecond, "pause between Spotify requests")
+	flag.Parse()
+
+	provider, err := spotify.NewClientFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var client *mongo.Client = database.Connect()
+	if client == nil {
+		log.Fatal("Failed to connect to MongoDB")
+	}
+	defer client.Disconnect(context.Background())
+
+	ctx := context.Background()
+
+	// Without -overwrite only entries missing imported fields are visited
+	filter := bson.M{}
+	if !*overwrite {
+		filter["$or"] = []bson.M{
+			{"artist": bson.M{"$exists": false}},
+			{"release_year": bson.M{"$exists": false}},
+			{"tracks": bson.M{"$exists": false}},
+		}
+	}
+	if *only != "" {
+		filter["music_id"] = *only
+	}
+
+	var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+	cursor, err := musicCollection.Find(ctx, filter)
+	if err != nil {
+		log.Fatal("Failed to fetch music entries: ", err)
+	}
+	defer cursor.Close(ctx)
+
+	var visited, updated, skipped, failed int
+	for cursor.Next(ctx) {
+		if *limit > 0 && visited >= *limit {
+			break
+		}
+
+		var music models.Music
+		if err := cursor.Decode(&music); err != nil {
+			log.Println("Skipping undecodable entry:", err)
+			failed++
+			continue
+		}
+		visited++
+
+		if !spotify.ValidID(music.MusicID) {
+			log.Printf("%s: not a Spotify album ID, skipped", music.MusicID)
+			skipped++
+			continue
+		}
+
+		album, err := fetchAlbum(ctx, provider, music.MusicID)
+		time.Sleep(*delay)
+		if err != nil {
+			log.Printf("%s: %v", music.MusicID, err)
+			failed++
+			continue
+		}
+
+		changes := spotify.Fill(&music, album, *overwrite)
+		if len(changes) == 0 {
+			skipped++
+			continue
+		}
+
+		fields := make([]string, 0, len(changes))
+		for field := range changes {
+			fields = append(fields, field)
+		}
+
+		if *dryRun {
+			log.Printf("%s: would set %v", music.MusicID, fields)
+			updated++
+			continue
+		}
+
+		if _, err := musicCollection.UpdateOne(ctx, bson.M{"music_id": music.MusicID}, bson.M{"$set": changes}); err != nil {
+			log.Printf("%s: failed to save: %v", music.MusicID, err)
+			failed++
+			continue
+		}
+		log.Printf("%s: set %v", music.MusicID, fields)
+		updated++
+	}
+	if err := cursor.Err(); err != nil {
+		log.Println("Cursor error:", err)
+	}
+
+	log.Printf("Done: %d visited, %d updated, %d skipped, %d failed (dry run: %t)", visited, updated, skipped, failed, *dryRun)
+}
+
+// fetchAlbum waits out one rate limit response before giving up
+func fetchAlbum(ctx context.Context, provider spotify.AlbumProvider, id string) (spotify.Album, error) {
+	album, err := provider.Album(ctx, id)
+	if errors.Is(err, spotify.ErrRateLimited) {
+		log.Println("Rate limited by Spotify, waiting 30 seconds")
+		time.Sleep(30 * time.Second)
+		album, err = provider.Album(ctx, id)
+	}
+	return album, err
+}
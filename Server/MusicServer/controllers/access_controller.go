@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/access"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/* This file lets admins set who may see a music entry or playlist. The rules themselves are evaluated by the access package; handlers use callerAccess to describe the caller. */
+
+// callerAccess describes the caller for access checks, from the values set
+// by AuthMiddleWare or OptionalAuthMiddleWare
+func callerAccess(c *gin.Context) access.Caller {
+	userId, _ := utils.GetUserIdFromContext(c)
+	role, _ := utils.GetRoleFromContext(c)
+
+	return access.Caller{UserID: userId, Role: role, Groups: utils.GetGroupsFromContext(c)}
+}
+
+// checkAccessRule validates a rule and the users and groups it names. Lists
+// only apply to restricted resources, so they are cleared for the others.
+func checkAccessRule(ctx context.Context, rule *models.Access, client *mongo.Client) (int, error) {
+	if err := validate.Struct(rule); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	if rule.Visibility != models.VisibilityRestricted {
+		rule.Users = []string{}
+		rule.Groups = []string{}
+		return 0, nil
+	}
+	if rule.Users == nil {
+		rule.Users = []string{}
+	}
+	if rule.Groups == nil {
+		rule.Groups = []string{}
+	}
+
+	if err := checkUsersExist(ctx, rule.Users, client); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if err := checkGroupsExist(ctx, rule.Groups, client); err != nil {
+		return http.StatusBadRequest, err
+	}
+	return 0, nil
+}
+
+// setAccess is shared by the music and playlist access endpoints
+func setAccess(client *mongo.Client, collectionName, idField, param, targetType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rule models.Access
+		if err := c.ShouldBindJSON(&rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if status, err := checkAccessRule(ctx, &rule, client); err != nil {
+			c.JSON(status, gin.H{"error": "Invalid access rule", "details": err.Error()})
+			return
+		}
+
+		var collection *mongo.Collection = database.OpenCollection(collectionName, client)
+
+		id := c.Param(param)
+		result, err := collection.UpdateOne(ctx, bson.M{idField: id}, bson.M{"$set": bson.M{"access": rule}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update access"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+
+		details := bson.M{"visibility": rule.Visibility, "users": rule.Users, "groups": rule.Groups}
+		recordAudit(c, ctx, targetType+".access", targetType, id, details, client)
+		if collectionName == "musics" {
+			refreshCatalogIndexes(client)
+		}
+
+		c.JSON(http.StatusOK, gin.H{idField: id, "access": rule})
+	}
+}
+
+// AdminSetMusicAccess sets who may see a music entry
+func AdminSetMusicAccess(client *mongo.Client) gin.HandlerFunc {
+	return setAccess(client, "musics", "music_id", "music_id", "music")
+}
+
+// AdminSetPlaylistAccess sets who may see a playlist
+func AdminSetPlaylistAccess(client *mongo.Client) gin.HandlerFunc {
+	return setAccess(client, "playlists", "playlist_id", "playlist_id", "playlist")
+}
@@ -0,0 +1,302 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file is the admin user management API. Admins can list and search registered users, view a user, change their role, disable or re-enable their account and force a logout. Every change is written to the audit trail. */
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+// toAdminUserResponse adds the account details only admins need to see
+func toAdminUserResponse(user models.User) models.AdminUserResponse {
+	return models.AdminUserResponse{
+		UserResponse: toUserResponse(user),
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+		DisabledAt:   user.DisabledAt,
+	}
+}
+
+// pageParams reads page and limit query parameters with defaults and a cap
+func pageParams(c *gin.Context, defaultLimit, maxLimit int64) (int64, int64) {
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.FormatInt(defaultLimit, 10)), 10, 64)
+	if err != nil || limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return page, limit
+}
+
+// AdminListUsers lists users with optional search (q), role and disabled filters
+func AdminListUsers(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+
+		if q := c.Query("q"); q != "" {
+			pattern := bson.Regex{Pattern: regexp.QuoteMeta(q), Options: "i"}
+			filter["$or"] = []bson.M{
+				{"email": pattern},
+				{"first_name": pattern},
+				{"last_name": pattern},
+			}
+		}
+
+		if role := c.Query("role"); role != "" {
+			filter["role"] = role
+		}
+
+		if disabled := c.Query("disabled"); disabled != "" {
+			value, err := strconv.ParseBool(disabled)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "disabled must be true or false"})
+				return
+			}
+			if value {
+				filter["disabled"] = true
+			} else {
+				filter["disabled"] = bson.M{"$ne": true}
+			}
+		}
+
+		page, limit := pageParams(c, defaultUserPageSize, maxUserPageSize)
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		total, err := userCollection.CountDocuments(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users"})
+			return
+		}
+
+		findOptions := options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+			SetSkip((page - 1) * limit).
+			SetLimit(limit)
+
+		cursor, err := userCollection.Find(ctx, filter, findOptions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var users []models.User
+		if err := cursor.All(ctx, &users); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode users"})
+			return
+		}
+
+		response := make([]models.AdminUserResponse, 0, len(users))
+		for _, user := range users {
+			response = append(response, toAdminUserResponse(user))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"users": response,
+			"total": total,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
+
+// AdminGetUser returns a single user
+func AdminGetUser(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, err := findUserByID(ctx, c.Param("user_id"), client)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, toAdminUserResponse(user))
+	}
+}
+
+// targetUser loads the user from the URL and refuses to let admins act on themselves
+func targetUser(c *gin.Context, ctx context.Context, client *mongo.Client) (models.User, string, bool) {
+	adminId, err := utils.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userId not found in context"})
+		return models.User{}, "", false
+	}
+
+	user, err := findUserByID(ctx, c.Param("user_id"), client)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return models.User{}, "", false
+	}
+
+	if user.UserID == adminId {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Admins cannot change their own account here"})
+		return models.User{}, "", false
+	}
+	return user, adminId, true
+}
+
+// AdminUpdateUserRole changes a user's role. Their sessions are revoked so
+// the new role takes effect on the next login instead of after token expiry.
+func AdminUpdateUserRole(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.RoleUpdate
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, adminId, ok := targetUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		if user.Role == req.Role {
+			c.JSON(http.StatusOK, toAdminUserResponse(user))
+			return
+		}
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		update := bson.M{"$set": bson.M{"role": req.Role, "update_at": time.Now()}}
+		if _, err := userCollection.UpdateOne(ctx, bson.M{"user_id": user.UserID}, update); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+			return
+		}
+
+		if _, err := utils.RevokeUserSessions(ctx, user.UserID, "", client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Role changed but sessions could not be revoked"})
+			return
+		}
+
+		details := bson.M{"from": user.Role, "to": req.Role}
+		if err := utils.WriteAuditLog(ctx, adminId, "user.role", "user", user.UserID, details, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Role changed but audit log could not be written"})
+			return
+		}
+
+		user.Role = req.Role
+		c.JSON(http.StatusOK, toAdminUserResponse(user))
+	}
+}
+
+// setUserDisabled is shared by the disable and enable endpoints
+func setUserDisabled(client *mongo.Client, disabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, adminId, ok := targetUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+		update := bson.M{"$set": bson.M{"disabled": true, "disabled_at": now, "update_at": now}}
+		action := "user.disable"
+		if !disabled {
+			update = bson.M{"$set": bson.M{"disabled": false, "update_at": now}, "$unset": bson.M{"disabled_at": ""}}
+			action = "user.enable"
+		}
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		if _, err := userCollection.UpdateOne(ctx, bson.M{"user_id": user.UserID}, update); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
+			return
+		}
+
+		// A disabled account loses every session right away
+		if disabled {
+			if _, err := utils.RevokeUserSessions(ctx, user.UserID, "", client); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Account disabled but sessions could not be revoked"})
+				return
+			}
+		}
+
+		if err := utils.WriteAuditLog(ctx, adminId, action, "user", user.UserID, nil, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Account updated but audit log could not be written"})
+			return
+		}
+
+		updated, err := findUserByID(ctx, user.UserID, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated user"})
+			return
+		}
+
+		c.JSON(http.StatusOK, toAdminUserResponse(updated))
+	}
+}
+
+// AdminDisableUser blocks a user from logging in and ends their sessions
+func AdminDisableUser(client *mongo.Client) gin.HandlerFunc {
+	return setUserDisabled(client, true)
+}
+
+// AdminEnableUser lets a disabled user log in again
+func AdminEnableUser(client *mongo.Client) gin.HandlerFunc {
+	return setUserDisabled(client, false)
+}
+
+// AdminForceLogout revokes every session of a user
+func AdminForceLogout(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, adminId, ok := targetUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		revoked, err := utils.RevokeUserSessions(ctx, user.UserID, "", client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+			return
+		}
+
+		details := bson.M{"revoked_sessions": revoked}
+		if err := utils.WriteAuditLog(ctx, adminId, "user.logout", "user", user.UserID, details, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Sessions revoked but audit log could not be written"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "User logged out", "revoked_sessions": revoked})
+	}
+}
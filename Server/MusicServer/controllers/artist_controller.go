@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/access"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file provides artists: public listing and artist pages with the albums the caller may see, and admin create, edit and delete. Artist names are unique regardless of case, so the artist migration can match names to existing artists. */
+
+// findArtist loads one artist by its artist_id
+func findArtist(ctx context.Context, artistId string, client *mongo.Client) (models.Artist, error) {
+	var artist models.Artist
+
+	var artistCollection *mongo.Collection = database.OpenCollection("artists", client)
+
+	err := artistCollection.FindOne(ctx, bson.M{"artist_id": artistId}).Decode(&artist)
+	return artist, err
+}
+
+// artistOr404 loads the artist from the URL or answers 404
+func artistOr404(c *gin.Context, ctx context.Context, client *mongo.Client) (models.Artist, bool) {
+	artist, err := findArtist(ctx, c.Param("artist_id"), client)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
+		return artist, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch artist"})
+		return artist, false
+	}
+	return artist, true
+}
+
+// artistNameFilter matches a name regardless of case
+func artistNameFilter(name string) bson.M {
+	return bson.M{"name": bson.M{"$regex": "^" + regexp.QuoteMeta(name) + "$", "$options": "i"}}
+}
+
+// artistNameTaken reports whether another artist already has the name
+func artistNameTaken(ctx context.Context, name, exceptId string, client *mongo.Client) (bool, error) {
+	var artistCollection *mongo.Collection = database.OpenCollection("artists", client)
+
+	filter := artistNameFilter(name)
+	if exceptId != "" {
+		filter["artist_id"] = bson.M{"$ne": exceptId}
+	}
+	count, err := artistCollection.CountDocuments(ctx, filter)
+	return count > 0, err
+}
+
+// artistAlbumCounts counts the albums the caller may see per artist
+func artistAlbumCounts(c *gin.Context, ctx context.Context, client *mongo.Client) (map[string]int, error) {
+	var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+	match := access.And(bson.M{"artist_ids.0": bson.M{"$exists": true}}, access.Filter("access", callerAccess(c)))
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$unwind", Value: "$artist_ids"}},
+		{{Key: "$group", Value: bson.M{"_id": "$artist_ids", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := musicCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		ArtistID string `bson:"_id"`
+		Count    int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(groups))
+	for _, group := range groups {
+		counts[group.ArtistID] = group.Count
+	}
+	return counts, nil
+}
+
+// GetArtists lists artists by name with the number of albums the caller may see
+func GetArtists(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var artistCollection *mongo.Collection = database.OpenCollection("artists", client)
+
+		findOptions := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+		cursor, err := artistCollection.Find(ctx, bson.M{}, findOptions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch artists"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var artists []models.Artist
+		if err := cursor.All(ctx, &artists); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode artists"})
+			return
+		}
+
+		counts, err := artistAlbumCounts(c, ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count albums"})
+			return
+		}
+
+		response := make([]models.ArtistSummary, 0, len(artists))
+		for _, artist := range artists {
+			response = append(response, models.ArtistSummary{
+				ArtistID:   artist.ArtistID,
+				Name:       artist.Name,
+				Image:      artist.Image,
+				AlbumCount: counts[artist.ArtistID],
+			})
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// GetArtist returns an artist with the albums the caller may see, newest first
+func GetArtist(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		artist, ok := artistOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		filter := access.And(bson.M{"artist_ids": artist.ArtistID}, access.Filter("access", callerAccess(c)))
+		findOptions := options.Find().SetSort(bson.D{{Key: "release_year", Value: -1}, {Key: "title", Value: 1}})
+		cursor, err := musicCollection.Find(ctx, filter, findOptions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch albums"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var musics []models.Music
+		if err := cursor.All(ctx, &musics); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode albums"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"artist_id":  artist.ArtistID,
+			"name":       artist.Name,
+			"bio":        artist.Bio,
+			"image":      artist.Image,
+			"links":      artist.Links,
+			"created_at": artist.CreatedAt,
+			"updated_at": artist.UpdatedAt,
+			"albums":     models.NewMusicViews(musics, callerAudience(c)),
+		})
+	}
+}
+
+// AdminCreateArtist creates an artist
+func AdminCreateArtist(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ArtistRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		req.Name = strings.TrimSpace(req.Name)
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		taken, err := artistNameTaken(ctx, req.Name, "", client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check artist name"})
+			return
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "An artist with this name already exists"})
+			return
+		}
+
+		links := req.Links
+		if links == nil {
+			links = []models.ExternalLink{}
+		}
+
+		now := time.Now()
+		artist := models.Artist{
+			ArtistID:  bson.NewObjectID().Hex(),
+			Name:      req.Name,
+			Bio:       req.Bio,
+			Image:     req.Image,
+			Links:     links,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		var artistCollection *mongo.Collection = database.OpenCollection("artists", client)
+
+		if _, err := artistCollection.InsertOne(ctx, artist); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create artist"})
+			return
+		}
+
+		recordAudit(c, ctx, "artist.create", "artist", artist.ArtistID, bson.M{"name": artist.Name}, client)
+
+		c.JSON(http.StatusCreated, artist)
+	}
+}
+
+// AdminUpdateArtist changes the name, bio, image or links
+func AdminUpdateArtist(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ArtistUpdate
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if req.Name != nil {
+			*req.Name = strings.TrimSpace(*req.Name)
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		artistId := c.Param("artist_id")
+
+		set := bson.M{"updated_at": time.Now()}
+		if req.Name != nil {
+			taken, err := artistNameTaken(ctx, *req.Name, artistId, client)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check artist name"})
+				return
+			}
+			if taken {
+				c.JSON(http.StatusConflict, gin.H{"error": "An artist with this name already exists"})
+				return
+			}
+			set["name"] = *req.Name
+		}
+		if req.Bio != nil {
+			set["bio"] = *req.Bio
+		}
+		if req.Image != nil {
+			set["image"] = *req.Image
+		}
+		if req.Links != nil {
+			set["links"] = *req.Links
+		}
+		if len(set) == 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+			return
+		}
+
+		var artistCollection *mongo.Collection = database.OpenCollection("artists", client)
+
+		result, err := artistCollection.UpdateOne(ctx, bson.M{"artist_id": artistId}, bson.M{"$set": set})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update artist"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
+			return
+		}
+
+		delete(set, "updated_at")
+		recordAudit(c, ctx, "artist.update", "artist", artistId, set, client)
+
+		artist, err := findArtist(ctx, artistId, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated artist"})
+			return
+		}
+		c.JSON(http.StatusOK, artist)
+	}
+}
+
+// AdminDeleteArtist deletes an artist and removes it from its albums. The
+// albums themselves and their artist credit are kept.
+func AdminDeleteArtist(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		artist, ok := artistOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		var artistCollection *mongo.Collection = database.OpenCollection("artists", client)
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		var albums int64
+		err := database.RunTransaction(ctx, client, func(ctx context.Context) error {
+			result, err := musicCollection.UpdateMany(ctx,
+				bson.M{"artist_ids": artist.ArtistID},
+				bson.M{"$pull": bson.M{"artist_ids": artist.ArtistID}},
+			)
+			if err != nil {
+				return err
+			}
+			albums = result.ModifiedCount
+
+			_, err = artistCollection.DeleteOne(ctx, bson.M{"artist_id": artist.ArtistID})
+			return err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete artist"})
+			return
+		}
+
+		recordAudit(c, ctx, "artist.delete", "artist", artist.ArtistID, bson.M{"name": artist.Name, "albums": albums}, client)
+		refreshCatalogIndexes(client)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Artist deleted", "albums_updated": albums})
+	}
+}
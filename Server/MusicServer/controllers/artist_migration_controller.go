@@ -0,0 +1,383 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/catalog"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/jobs"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file moves old entries over to artist records. A background job proposes artists and a shorter title for every entry without artists, from its artist credit or an "Artist - Title" title. Nothing changes until an admin applies a proposal, possibly after correcting it; applying creates the artists that do not exist yet. */
+
+const artistMigrationJobKind = "artists"
+
+// artistsByName maps lowercased names to artist IDs
+func artistsByName(ctx context.Context, client *mongo.Client) (map[string]string, error) {
+	var artists []models.Artist
+	if err := findAll(ctx, "artists", bson.M{}, &artists, client); err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]string, len(artists))
+	for _, artist := range artists {
+		byName[strings.ToLower(artist.Name)] = artist.ArtistID
+	}
+	return byName, nil
+}
+
+// startArtistMigrationJob proposes artists for every entry that has none
+// and no proposal yet. Rejected proposals are not made again.
+func startArtistMigrationJob(c *gin.Context, client *mongo.Client) (jobs.Job, error) {
+	adminId, _ := utils.GetUserIdFromContext(c)
+
+	return jobs.Start(artistMigrationJobKind, "propose", adminId, func(ctx context.Context, progress *jobs.Progress) error {
+		var proposals []models.ArtistProposal
+		if err := findAll(ctx, "artist_proposals", bson.M{}, &proposals, client); err != nil {
+			return err
+		}
+		proposed := make(map[string]bool, len(proposals))
+		for _, proposal := range proposals {
+			proposed[proposal.MusicID] = true
+		}
+
+		var musics []models.Music
+		if err := findAll(ctx, "musics", bson.M{"artist_ids.0": bson.M{"$exists": false}}, &musics, client); err != nil {
+			return err
+		}
+		progress.SetTotal(len(musics))
+
+		var proposalCollection *mongo.Collection = database.OpenCollection("artist_proposals", client)
+
+		for _, music := range musics {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if proposed[music.MusicID] {
+				progress.Step(music.MusicID, nil)
+				continue
+			}
+
+			var err error
+			if title, artists, ok := catalog.ProposeArtists(music); ok {
+				_, err = proposalCollection.InsertOne(ctx, models.ArtistProposal{
+					MusicID:       music.MusicID,
+					OriginalTitle: music.Title,
+					Title:         title,
+					Artists:       artists,
+					Status:        models.ArtistProposalPending,
+					CreatedAt:     time.Now(),
+				})
+			}
+			progress.Step(music.MusicID, err)
+		}
+		return nil
+	})
+}
+
+// AdminStartArtistMigration starts the job that proposes artists
+func AdminStartArtistMigration(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := startArtistMigrationJob(c, client)
+		if err == jobs.ErrJobRunning {
+			c.JSON(http.StatusConflict, gin.H{"error": "The artist migration is already running"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start the artist migration"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		recordAudit(c, ctx, "artist.migration", "job", job.ID, bson.M{}, client)
+
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// AdminListArtistProposals lists proposals by status, pending ones by
+// default. Each proposed artist shows the artist_id it would be matched to,
+// or an empty one when applying would create it.
+func AdminListArtistProposals(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := c.DefaultQuery("status", models.ArtistProposalPending)
+		switch status {
+		case models.ArtistProposalPending, models.ArtistProposalApplied, models.ArtistProposalRejected:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var proposalCollection *mongo.Collection = database.OpenCollection("artist_proposals", client)
+
+		findOptions := options.Find().SetSort(bson.D{{Key: "original_title", Value: 1}})
+		cursor, err := proposalCollection.Find(ctx, bson.M{"status": status}, findOptions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch proposals"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var proposals []models.ArtistProposal
+		if err := cursor.All(ctx, &proposals); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode proposals"})
+			return
+		}
+
+		byName, err := artistsByName(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch artists"})
+			return
+		}
+
+		response := make([]gin.H, 0, len(proposals))
+		for _, proposal := range proposals {
+			artists := make([]gin.H, 0, len(proposal.Artists))
+			for _, name := range proposal.Artists {
+				artists = append(artists, gin.H{"name": name, "artist_id": byName[strings.ToLower(name)]})
+			}
+			response = append(response, gin.H{
+				"music_id":       proposal.MusicID,
+				"original_title": proposal.OriginalTitle,
+				"title":          proposal.Title,
+				"artists":        artists,
+				"status":         proposal.Status,
+				"created_at":     proposal.CreatedAt,
+				"reviewed_by":    proposal.ReviewedBy,
+				"reviewed_at":    proposal.ReviewedAt,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"proposals": response, "total": len(response)})
+	}
+}
+
+// AdminUpdateArtistProposal corrects the title or artists of a pending proposal
+func AdminUpdateArtistProposal(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ArtistProposalUpdate
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		req.Title = strings.TrimSpace(req.Title)
+		for i := range req.Artists {
+			req.Artists[i] = strings.TrimSpace(req.Artists[i])
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var proposalCollection *mongo.Collection = database.OpenCollection("artist_proposals", client)
+
+		musicId := c.Param("music_id")
+		filter := bson.M{"music_id": musicId, "status": models.ArtistProposalPending}
+		update := bson.M{"$set": bson.M{"title": req.Title, "artists": req.Artists}}
+
+		var proposal models.ArtistProposal
+		err := proposalCollection.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&proposal)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No pending proposal for this music"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update proposal"})
+			return
+		}
+
+		c.JSON(http.StatusOK, proposal)
+	}
+}
+
+// pendingProposals loads the pending proposals among musicIds
+func pendingProposals(ctx context.Context, musicIds []string, client *mongo.Client) ([]models.ArtistProposal, error) {
+	var proposals []models.ArtistProposal
+	filter := bson.M{"music_id": bson.M{"$in": musicIds}, "status": models.ArtistProposalPending}
+	err := findAll(ctx, "artist_proposals", filter, &proposals, client)
+	return proposals, err
+}
+
+// bindProposalReview reads and validates the music_ids of an apply or reject
+func bindProposalReview(c *gin.Context) (models.ArtistProposalReview, bool) {
+	var req models.ArtistProposalReview
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+		return req, false
+	}
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+// AdminApplyArtistProposals applies pending proposals: the entries get the
+// proposed title and artists, and an artist credit if they had none.
+// Artists are matched by name regardless of case and created when missing.
+func AdminApplyArtistProposals(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := bindProposalReview(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		proposals, err := pendingProposals(ctx, req.MusicIDs, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch proposals"})
+			return
+		}
+
+		var musics []models.Music
+		if err := findAll(ctx, "musics", bson.M{"music_id": bson.M{"$in": req.MusicIDs}}, &musics, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch music"})
+			return
+		}
+		musicsById := make(map[string]models.Music, len(musics))
+		for _, music := range musics {
+			musicsById[music.MusicID] = music
+		}
+
+		byName, err := artistsByName(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch artists"})
+			return
+		}
+
+		// Everything is decided before the transaction, which may be retried
+		now := time.Now()
+		var newArtists []models.Artist
+		updates := map[string]bson.M{}
+		skipped := []gin.H{}
+		for _, proposal := range proposals {
+			music, found := musicsById[proposal.MusicID]
+			if !found {
+				skipped = append(skipped, gin.H{"music_id": proposal.MusicID, "reason": "music not found"})
+				continue
+			}
+			if len(strings.TrimSpace(proposal.Title)) < 2 || len(proposal.Artists) == 0 {
+				skipped = append(skipped, gin.H{"music_id": proposal.MusicID, "reason": "proposal needs a title and an artist"})
+				continue
+			}
+
+			artistIds := make([]string, 0, len(proposal.Artists))
+			for _, name := range proposal.Artists {
+				artistId, exists := byName[strings.ToLower(name)]
+				if !exists {
+					artist := models.Artist{
+						ArtistID:  bson.NewObjectID().Hex(),
+						Name:      name,
+						Links:     []models.ExternalLink{},
+						CreatedAt: now,
+						UpdatedAt: now,
+					}
+					newArtists = append(newArtists, artist)
+					artistId = artist.ArtistID
+					byName[strings.ToLower(name)] = artistId
+				}
+				artistIds = append(artistIds, artistId)
+			}
+
+			set := bson.M{"title": proposal.Title, "artist_ids": artistIds}
+			if music.Artist == "" {
+				set["artist"] = strings.Join(proposal.Artists, ", ")
+			}
+			updates[proposal.MusicID] = set
+		}
+
+		adminId, _ := utils.GetUserIdFromContext(c)
+
+		var artistCollection *mongo.Collection = database.OpenCollection("artists", client)
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+		var proposalCollection *mongo.Collection = database.OpenCollection("artist_proposals", client)
+
+		applied := make([]string, 0, len(updates))
+		for musicId := range updates {
+			applied = append(applied, musicId)
+		}
+		sort.Strings(applied)
+
+		err = database.RunTransaction(ctx, client, func(ctx context.Context) error {
+			for _, artist := range newArtists {
+				if _, err := artistCollection.InsertOne(ctx, artist); err != nil {
+					return err
+				}
+			}
+			for musicId, set := range updates {
+				if _, err := musicCollection.UpdateOne(ctx, bson.M{"music_id": musicId}, bson.M{"$set": set}); err != nil {
+					return err
+				}
+			}
+			_, err := proposalCollection.UpdateMany(ctx,
+				bson.M{"music_id": bson.M{"$in": applied}, "status": models.ArtistProposalPending},
+				bson.M{"$set": bson.M{"status": models.ArtistProposalApplied, "reviewed_by": adminId, "reviewed_at": now}},
+			)
+			return err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply proposals", "details": err.Error()})
+			return
+		}
+
+		created := make([]string, 0, len(newArtists))
+		for _, artist := range newArtists {
+			created = append(created, artist.ArtistID)
+		}
+		recordAudit(c, ctx, "artist.migration.apply", "music", "", bson.M{"music_ids": applied, "created_artists": created}, client)
+		refreshCatalogIndexes(client)
+
+		c.JSON(http.StatusOK, gin.H{"applied": applied, "created_artists": newArtists, "skipped": skipped})
+	}
+}
+
+// AdminRejectArtistProposals rejects pending proposals; the entries stay as they are
+func AdminRejectArtistProposals(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, ok := bindProposalReview(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		adminId, _ := utils.GetUserIdFromContext(c)
+
+		var proposalCollection *mongo.Collection = database.OpenCollection("artist_proposals", client)
+
+		result, err := proposalCollection.UpdateMany(ctx,
+			bson.M{"music_id": bson.M{"$in": req.MusicIDs}, "status": models.ArtistProposalPending},
+			bson.M{"$set": bson.M{"status": models.ArtistProposalRejected, "reviewed_by": adminId, "reviewed_at": time.Now()}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject proposals"})
+			return
+		}
+
+		recordAudit(c, ctx, "artist.migration.reject", "music", "", bson.M{"music_ids": req.MusicIDs}, client)
+
+		c.JSON(http.StatusOK, gin.H{"rejected": result.ModifiedCount})
+	}
+}
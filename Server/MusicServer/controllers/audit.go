@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"context"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/* This file writes audit trail entries for the handlers that change the catalog, genres, groups, rankings, playlists and share links, with the caller as the actor. User, invite and settings changes call utils.WriteAuditLog themselves and report a failed entry to the admin. */
+
+// recordAudit writes an audit entry for a change made by the caller. The
+// change has already happened, so a failure is only logged.
+func recordAudit(c *gin.Context, ctx context.Context, action, targetType, targetId string, details bson.M, client *mongo.Client) {
+	actorId, _ := utils.GetUserIdFromContext(c)
+
+	if err := utils.WriteAuditLog(ctx, actorId, action, targetType, targetId, details, client); err != nil {
+		log.Println("Warning: failed to write audit log:", action, targetId, err)
+	}
+}
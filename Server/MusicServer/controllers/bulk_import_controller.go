@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/catalog"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/* This file adds many music entries at once from a CSV or JSON Lines file. Every row gets the same checks as AddMusic; a dry run only returns the per-row report, a real import inserts the valid rows in transactional batches. cmd/catalog-import does the same from the command line. */
+
+const maxImportBytes = 10 << 20
+
+// importFile returns the uploaded "file" of a multipart request, or else the raw body
+func importFile(c *gin.Context) (io.ReadCloser, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes+1<<20)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		if file.Size > maxImportBytes {
+			return nil, errors.New("import file is larger than 10MB")
+		}
+		return file.Open()
+	}
+	return c.Request.Body, nil
+}
+
+// AdminImportMusics imports music entries. Query parameters: format (csv or
+// jsonl), dry_run, batch_size (0 imports everything in one transaction) and
+// skip_invalid to import the valid rows even when others fail.
+func AdminImportMusics(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", catalog.FormatCSV)
+		dryRun := c.Query("dry_run") == "true"
+		skipInvalid := c.Query("skip_invalid") == "true"
+
+		batchSize, err := strconv.Atoi(c.DefaultQuery("batch_size", "0"))
+		if err != nil || batchSize < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "batch_size must be a non-negative number"})
+			return
+		}
+
+		file, err := importFile(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An import file is required", "details": err.Error()})
+			return
+		}
+		defer file.Close()
+
+		rows, err := catalog.Parse(format, file)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file", "details": err.Error()})
+			return
+		}
+		if len(rows) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The import file has no rows"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		report, err := catalog.Plan(ctx, rows, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check import"})
+			return
+		}
+		if dryRun {
+			c.JSON(http.StatusOK, report)
+			return
+		}
+
+		err = catalog.Apply(ctx, report, batchSize, skipInvalid, client)
+		if report.Created > 0 {
+			recordAudit(c, ctx, "music.import", "music", "", bson.M{"format": format, "created": report.Created, "batches": report.Batches, "invalid": report.Invalid}, client)
+			refreshCatalogIndexes(client)
+		}
+
+		switch {
+		case errors.Is(err, catalog.ErrInvalidRows):
+			report.Error = err.Error()
+			c.JSON(http.StatusUnprocessableEntity, report)
+		case errors.Is(err, catalog.ErrDuplicateRows):
+			c.JSON(http.StatusConflict, report)
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, report)
+		default:
+			c.JSON(http.StatusCreated, report)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/access"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file parses the catalog listing query string (genre, ranking range, album metadata, sort order, limit and cursor) into a MongoDB filter and find options. Paging is cursor based: the cursor remembers the sort key and _id of the last item, so pages stay stable while albums are added or removed. */
+
+const (
+	defaultCatalogPageSize = 24
+	maxCatalogPageSize     = 100
+)
+
+// catalogSortField is the document field behind a sort key
+type catalogSortField struct {
+	Field    string
+	Text     bool // a string, otherwise a number
+	Optional bool // missing on some entries
+}
+
+// Sort keys mapped to their document fields. "added" sorts by _id, whose
+// ObjectID timestamp is the date the album was added.
+var catalogSortFields = map[string]catalogSortField{
+	"title":       {Field: "title", Text: true},
+	"ranking":     {Field: "ranking.ranking_value"},
+	"added":       {Field: "_id"},
+	"year":        {Field: "release_year", Optional: true},
+	"label":       {Field: "label", Text: true, Optional: true},
+	"country":     {Field: "country", Text: true, Optional: true},
+	"track_count": {Field: "track_count", Optional: true},
+	"duration":    {Field: "duration_seconds", Optional: true},
+}
+
+// Number ranges on album metadata: query parameter prefix to document field
+var catalogRangeFields = []struct {
+	Param string
+	Field string
+}{
+	{"year", "release_year"},
+	{"track_count", "track_count"},
+	{"duration", "duration_seconds"},
+}
+
+// catalogQuery is the parsed listing request
+type catalogQuery struct {
+	Genres     []string
+	MatchAll   bool
+	RankingMin *int
+	RankingMax *int
+	Ranges     map[string]bson.M // album metadata ranges by document field
+	Label      string
+	Countries  []string
+	Explicit   *bool
+	Sort       string
+	Descending bool
+	Limit      int64
+	Cursor     *catalogCursor
+	Paged      bool   // limit or cursor given, respond with a page envelope
+	Access     bson.M // access filter for the caller, set by the handler
+}
+
+// catalogCursor marks the last item of the previous page
+type catalogCursor struct {
+	Sort  string          `json:"s"`
+	Desc  bool            `json:"d"`
+	Value json.RawMessage `json:"v,omitempty"`
+	ID    string          `json:"id"`
+}
+
+// splitList reads a query parameter given either as a=x,y or a=x&a=y
+func splitList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// optionalInt parses an integer query parameter that may be missing
+func optionalInt(c *gin.Context, name string) (*int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number", name)
+	}
+	return &value, nil
+}
+
+// parseCatalogQuery validates the listing query parameters
+func parseCatalogQuery(c *gin.Context) (catalogQuery, error) {
+	query := catalogQuery{
+		Genres: splitList(c.QueryArray("genre")),
+		Sort:   c.DefaultQuery("sort", "added"),
+	}
+
+	switch c.DefaultQuery("genre_match", "any") {
+	case "any":
+	case "all":
+		query.MatchAll = true
+	default:
+		return query, errors.New("genre_match must be any or all")
+	}
+
+	var err error
+	if query.RankingMin, err = optionalInt(c, "ranking_min"); err != nil {
+		return query, err
+	}
+	if query.RankingMax, err = optionalInt(c, "ranking_max"); err != nil {
+		return query, err
+	}
+
+	query.Ranges = map[string]bson.M{}
+	for _, r := range catalogRangeFields {
+		low, err := optionalInt(c, r.Param+"_min")
+		if err != nil {
+			return query, err
+		}
+		high, err := optionalInt(c, r.Param+"_max")
+		if err != nil {
+			return query, err
+		}
+		if low != nil {
+			query.Ranges[r.Field] = bson.M{"$gte": *low}
+		}
+		if high != nil {
+			if query.Ranges[r.Field] == nil {
+				query.Ranges[r.Field] = bson.M{}
+			}
+			query.Ranges[r.Field]["$lte"] = *high
+		}
+	}
+
+	query.Label = strings.TrimSpace(c.Query("label"))
+	for _, country := range splitList(c.QueryArray("country")) {
+		query.Countries = append(query.Countries, strings.ToUpper(country))
+	}
+	if raw := c.Query("explicit"); raw != "" {
+		explicit, err := strconv.ParseBool(raw)
+		if err != nil {
+			return query, errors.New("explicit must be true or false")
+		}
+		query.Explicit = &explicit
+	}
+
+	if _, ok := catalogSortFields[query.Sort]; !ok {
+		return query, errors.New("sort must be title, ranking, added, year, label, country, track_count or duration")
+	}
+
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, errors.New("order must be asc or desc")
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || limit < 1 {
+			return query, errors.New("limit must be a positive number")
+		}
+		if limit > maxCatalogPageSize {
+			limit = maxCatalogPageSize
+		}
+		query.Limit = limit
+		query.Paged = true
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := decodeCatalogCursor(raw)
+		if err != nil {
+			return query, errors.New("invalid cursor")
+		}
+		if cursor.Sort != query.Sort || cursor.Desc != query.Descending {
+			return query, errors.New("cursor does not match the requested sort")
+		}
+		query.Cursor = &cursor
+		query.Paged = true
+	}
+
+	if query.Paged && query.Limit == 0 {
+		query.Limit = defaultCatalogPageSize
+	}
+
+	return query, nil
+}
+
+// filter builds the MongoDB filter without the cursor, used for the total count
+func (q catalogQuery) filter() bson.M {
+	filter := bson.M{}
+
+	if len(q.Genres) > 0 {
+		if q.MatchAll {
+			filter["genre.genre_name"] = bson.M{"$all": q.Genres}
+		} else {
+			filter["genre.genre_name"] = bson.M{"$in": q.Genres}
+		}
+	}
+
+	ranking := bson.M{}
+	if q.RankingMin != nil {
+		ranking["$gte"] = *q.RankingMin
+	}
+	if q.RankingMax != nil {
+		ranking["$lte"] = *q.RankingMax
+	}
+	if len(ranking) > 0 {
+		filter["ranking.ranking_value"] = ranking
+	}
+
+	for field, r := range q.Ranges {
+		filter[field] = r
+	}
+	if q.Label != "" {
+		filter["label"] = bson.M{"$regex": "^" + regexp.QuoteMeta(q.Label) + "$", "$options": "i"}
+	}
+	if len(q.Countries) > 0 {
+		filter["country"] = bson.M{"$in": q.Countries}
+	}
+	if q.Explicit != nil {
+		if *q.Explicit {
+			filter["explicit"] = true
+		} else {
+			// Entries not known to be explicit count as clean
+			filter["explicit"] = bson.M{"$ne": true}
+		}
+	}
+
+	return access.And(filter, q.Access)
+}
+
+// pageFilter adds the cursor condition to the filter
+func (q catalogQuery) pageFilter() (bson.M, error) {
+	filter := q.filter()
+	if q.Cursor == nil {
+		return filter, nil
+	}
+
+	lastID, err := bson.ObjectIDFromHex(q.Cursor.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	op := "$gt"
+	if q.Descending {
+		op = "$lt"
+	}
+
+	sortField := catalogSortFields[q.Sort]
+	field := sortField.Field
+	if field == "_id" {
+		return bson.M{"$and": []bson.M{filter, {"_id": bson.M{op: lastID}}}}, nil
+	}
+
+	var lastValue interface{}
+	if len(q.Cursor.Value) > 0 && string(q.Cursor.Value) != "null" {
+		if sortField.Text {
+			var text string
+			err = json.Unmarshal(q.Cursor.Value, &text)
+			lastValue = text
+		} else {
+			var number int
+			err = json.Unmarshal(q.Cursor.Value, &number)
+			lastValue = number
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var after bson.M
+	switch {
+	case lastValue == nil && q.Descending:
+		// Entries without the value come last in descending order
+		after = bson.M{field: nil, "_id": bson.M{op: lastID}}
+	case lastValue == nil:
+		// and first in ascending order
+		after = bson.M{"$or": []bson.M{
+			{field: bson.M{"$ne": nil}},
+			{field: nil, "_id": bson.M{op: lastID}},
+		}}
+	default:
+		or := []bson.M{
+			{field: bson.M{op: lastValue}},
+			{field: lastValue, "_id": bson.M{op: lastID}},
+		}
+		if sortField.Optional && q.Descending {
+			or = append(or, bson.M{field: nil})
+		}
+		after = bson.M{"$or": or}
+	}
+	return bson.M{"$and": []bson.M{filter, after}}, nil
+}
+
+// findOptions sorts by the requested key with _id as the tie breaker
+func (q catalogQuery) findOptions() *options.FindOptionsBuilder {
+	direction := 1
+	if q.Descending {
+		direction = -1
+	}
+
+	field := catalogSortFields[q.Sort].Field
+	sort := bson.D{{Key: field, Value: direction}}
+	if field != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: direction})
+	}
+
+	findOptions := options.Find().SetSort(sort)
+	if q.Limit > 0 {
+		// One extra document tells us whether there is a next page
+		findOptions.SetLimit(q.Limit + 1)
+	}
+	return findOptions
+}
+
+// nextCursor encodes the position after the given item
+func (q catalogQuery) nextCursor(music models.Music) string {
+	cursor := catalogCursor{Sort: q.Sort, Desc: q.Descending, ID: music.ID.Hex()}
+
+	var value interface{}
+	switch q.Sort {
+	case "title":
+		value = music.Title
+	case "ranking":
+		value = music.Ranking.RankingValue
+	case "year":
+		value = music.ReleaseYear
+	case "label":
+		value = music.Label
+	case "country":
+		value = music.Country
+	case "track_count":
+		value = music.TrackCount
+	case "duration":
+		value = music.DurationSeconds
+	}
+	// Empty optional values are not stored, the cursor marks them as null
+	if catalogSortFields[q.Sort].Optional && (value == "" || value == 0) {
+		value = nil
+	}
+	if q.Sort != "added" {
+		cursor.Value, _ = json.Marshal(value)
+	}
+
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCatalogCursor reverses nextCursor
+func decodeCatalogCursor(raw string) (catalogCursor, error) {
+	var cursor catalogCursor
+
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, err
+	}
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return cursor, err
+	}
+	if _, err := bson.ObjectIDFromHex(cursor.ID); err != nil {
+		return cursor, err
+	}
+	return cursor, nil
+}
@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/access"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/covers"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file handles uploaded album covers. Admins upload an image for a music entry; it is stored on disk with JPEG thumbnails by the covers package. GET /covers/:hash/:variant serves a stored file with a long cache lifetime. GET /musics/:music_id/cover redirects to an entry's cover, to the proxied album_img when it has no upload, or serves no_cover.png. GET /covers/proxy fetches the external covers of catalog entries from allowed hosts through the covers package's disk cache. */
+
+const (
+	defaultCoversDir        = "uploads/covers"
+	defaultCoverPlaceholder = "../../covers_2k/no_cover.png"
+
+	// Cover URLs contain the content hash, so they never go stale
+	coverCacheControl       = "public, max-age=31536000, immutable"
+	placeholderCacheControl = "public, max-age=300"
+)
+
+var (
+	coverStore     *covers.Store
+	coverStoreOnce sync.Once
+)
+
+// getCoverStore opens the store on first use, after main has loaded .env
+func getCoverStore() *covers.Store {
+	coverStoreOnce.Do(func() {
+		dir := os.Getenv("COVERS_DIR")
+		if dir == "" {
+			dir = defaultCoversDir
+		}
+		coverStore = covers.NewStore(dir)
+	})
+	return coverStore
+}
+
+// serveCoverPlaceholder answers with no_cover.png, cached only briefly so a
+// cover uploaded later shows up
+func serveCoverPlaceholder(c *gin.Context) {
+	path := os.Getenv("COVER_PLACEHOLDER")
+	if path == "" {
+		path = defaultCoverPlaceholder
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+		return
+	}
+
+	c.Header("Cache-Control", placeholderCacheControl)
+	c.File(path)
+}
+
+// GetCover serves one variant of an uploaded cover
+func GetCover() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hash, variant := c.Param("hash"), c.Param("variant")
+
+		path, err := getCoverStore().File(hash, variant)
+		if err != nil {
+			serveCoverPlaceholder(c)
+			return
+		}
+
+		etag := `"` + hash + "-" + variant + `"`
+		c.Header("Cache-Control", coverCacheControl)
+		c.Header("ETag", etag)
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		c.File(path)
+	}
+}
+
+// GetMusicCover redirects to a music entry's cover in the requested size
+// (small, medium, large or original, medium by default)
+func GetMusicCover(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		variant := c.DefaultQuery("size", "medium")
+		paths := covers.Paths("")
+		if _, ok := paths[variant]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size", "allowed_sizes": covers.Variants()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var music models.Music
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		err := musicCollection.FindOne(ctx, bson.M{"music_id": c.Param("music_id")}).Decode(&music)
+		if err != nil || !canViewMusic(c, music) {
+			serveCoverPlaceholder(c)
+			return
+		}
+
+		// The target changes when a new cover is uploaded, so the redirect is not cached
+		c.Header("Cache-Control", "no-cache")
+
+		switch {
+		case music.Cover != "":
+			c.Redirect(http.StatusFound, covers.Paths(music.Cover)[variant])
+		case variant != covers.Original && getCoverProxy().Allowed(music.AlbumImg):
+			// Thumbnails of an external cover come through the proxy
+			c.Redirect(http.StatusFound, coverProxyPath(music.AlbumImg, variant))
+		default:
+			serveCoverPlaceholder(c)
+		}
+	}
+}
+
+// AdminUploadCover stores the multipart file "cover" as a music entry's cover
+func AdminUploadCover(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Leave room for the multipart envelope around the file
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, covers.MaxBytes+1<<20)
+
+		file, err := c.FormFile("cover")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A cover file is required", "details": err.Error()})
+			return
+		}
+		if file.Size > covers.MaxBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": covers.ErrTooLarge.Error()})
+			return
+		}
+
+		upload, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read cover file"})
+			return
+		}
+		defer upload.Close()
+
+		data, err := io.ReadAll(io.LimitReader(upload, covers.MaxBytes+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read cover file"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		musicId := c.Param("music_id")
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		count, err := musicCollection.CountDocuments(ctx, bson.M{"music_id": musicId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch music"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Music not found"})
+			return
+		}
+
+		hash, err := getCoverStore().Save(data)
+		switch {
+		case errors.Is(err, covers.ErrTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, covers.ErrUnsupportedType):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, covers.ErrTooManyPixels), errors.Is(err, covers.ErrCorrupt):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store cover"})
+			return
+		}
+
+		_, err = musicCollection.UpdateOne(ctx, bson.M{"music_id": musicId}, bson.M{"$set": bson.M{"cover": hash}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update music"})
+			return
+		}
+
+		recordAudit(c, ctx, "music.cover", "music", musicId, bson.M{"cover": hash}, client)
+		refreshCatalogIndexes(client)
+
+		c.JSON(http.StatusOK, gin.H{"music_id": musicId, "cover": hash, "covers": covers.Paths(hash)})
+	}
+}
+
+// AdminDeleteCover detaches the uploaded cover from a music entry. The files
+// stay, another entry may use the same image.
+func AdminDeleteCover(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		musicId := c.Param("music_id")
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		result, err := musicCollection.UpdateOne(ctx, bson.M{"music_id": musicId}, bson.M{"$unset": bson.M{"cover": ""}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update music"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Music not found"})
+			return
+		}
+
+		recordAudit(c, ctx, "music.cover_delete", "music", musicId, nil, client)
+		refreshCatalogIndexes(client)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Cover removed"})
+	}
+}
+
+// Hosts the cover proxy may fetch from when COVER_PROXY_HOSTS is not set
+var defaultCoverProxyHosts = []string{
+	"raw.githubusercontent.com",
+	"i.ytimg.com",
+	"i.scdn.co",
+	"coverartarchive.org",
+	"*.archive.org",
+}
+
+const (
+	defaultCoverProxyDir     = "uploads/proxy"
+	defaultCoverProxyCacheMB = 200
+
+	// The remote image behind a URL can change, so proxied covers expire sooner
+	proxyCacheControl = "public, max-age=86400"
+)
+
+var (
+	coverProxy     *covers.Proxy
+	coverProxyOnce sync.Once
+)
+
+// getCoverProxy opens the proxy cache on first use, after main has loaded .env
+func getCoverProxy() *covers.Proxy {
+	coverProxyOnce.Do(func() {
+		dir := os.Getenv("COVER_PROXY_DIR")
+		if dir == "" {
+			dir = defaultCoverProxyDir
+		}
+
+		hosts := defaultCoverProxyHosts
+		if value := os.Getenv("COVER_PROXY_HOSTS"); value != "" {
+			hosts = strings.Split(value, ",")
+		}
+
+		cacheMB, err := strconv.Atoi(os.Getenv("COVER_PROXY_CACHE_MB"))
+		if err != nil || cacheMB <= 0 {
+			cacheMB = defaultCoverProxyCacheMB
+		}
+
+		coverProxy = covers.NewProxy(dir, hosts, int64(cacheMB)<<20)
+	})
+	return coverProxy
+}
+
+// coverProxyPath is the proxy URL path of an external cover
+func coverProxyPath(rawURL, variant string) string {
+	query := url.Values{}
+	query.Set("url", rawURL)
+	query.Set("size", variant)
+	return "/covers/proxy?" + query.Encode()
+}
+
+// GetCoverProxy serves a resized copy of an external cover image from an
+// allowed host (size = small, medium or large), or no_cover.png when the
+// image cannot be fetched. Only the album_img of an entry the caller may see
+// is proxied, so the endpoint cannot be used to fetch or cache other images.
+func GetCoverProxy(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawURL := c.Query("url")
+		variant := c.DefaultQuery("size", "medium")
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		filter := access.And(bson.M{"album_img": rawURL}, access.Filter("access", callerAccess(c)))
+		count, err := musicCollection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up cover"})
+			return
+		}
+		if rawURL == "" || count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "url is not the cover of a catalog entry"})
+			return
+		}
+
+		path, err := getCoverProxy().File(ctx, rawURL, variant)
+		switch {
+		case errors.Is(err, covers.ErrInvalidURL), errors.Is(err, covers.ErrNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A valid url and size (small, medium or large) are required"})
+			return
+		case errors.Is(err, covers.ErrHostNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			serveCoverPlaceholder(c)
+			return
+		}
+
+		// Evicted since it was looked up
+		if _, err := os.Stat(path); err != nil {
+			serveCoverPlaceholder(c)
+			return
+		}
+
+		c.Header("Cache-Control", proxyCacheControl)
+		c.File(path)
+	}
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/catalog"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file is the admin side of duplicate detection. The report groups the catalog into clusters of entries that share a music_id or video or have look-alike titles, and suggests which entry to keep. A merge folds the other entries into the kept one, moves their playlist places over and deletes them. */
+
+// setDuplicateWarning lists look-alike entries in the X-Possible-Duplicates header
+func setDuplicateWarning(c *gin.Context, similar []catalog.Duplicate) {
+	if len(similar) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(similar))
+	for _, duplicate := range similar {
+		ids = append(ids, duplicate.MusicID)
+	}
+	c.Header("X-Possible-Duplicates", strings.Join(ids, ","))
+}
+
+// AdminListDuplicates reports clusters of suspected duplicates, largest first
+func AdminListDuplicates(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var musics []models.Music
+		if err := findAll(ctx, "musics", bson.M{}, &musics, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch music"})
+			return
+		}
+
+		clusters := catalog.Clusters(musics)
+
+		response := make([]gin.H, 0, len(clusters))
+		for _, cluster := range clusters {
+			keep := cluster.Musics[0]
+			for _, music := range cluster.Musics[1:] {
+				if catalog.BetterReview(music, keep) {
+					keep = music
+				}
+			}
+
+			response = append(response, gin.H{
+				"musics":         models.NewMusicViews(cluster.Musics, models.AudienceAdmin),
+				"links":          cluster.Links,
+				"suggested_keep": keep.ID.Hex(),
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"clusters": response, "total": len(response)})
+	}
+}
+
+// mergeIntoPlaylists moves the playlist places of a merged entry to the kept
+// one, or drops them where the playlist already has it
+func mergeIntoPlaylists(ctx context.Context, from, into string, client *mongo.Client) error {
+	var playlistCollection *mongo.Collection = database.OpenCollection("playlists", client)
+
+	now := time.Now()
+
+	_, err := playlistCollection.UpdateMany(ctx,
+		bson.M{"items.music_id": bson.M{"$all": []string{from, into}}},
+		bson.M{"$pull": bson.M{"items": bson.M{"music_id": from}}, "$set": bson.M{"updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+
+	updateOptions := options.UpdateMany().SetArrayFilters([]any{bson.M{"item.music_id": from}})
+	_, err = playlistCollection.UpdateMany(ctx,
+		bson.M{"items.music_id": from},
+		bson.M{"$set": bson.M{"items.$[item].music_id": into, "updated_at": now}},
+		updateOptions,
+	)
+	return err
+}
+
+// AdminMergeDuplicates folds the "merge" entries into "keep": keep gets the
+// best review and ranking and anything it is missing, the others are deleted
+func AdminMergeDuplicates(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.DuplicateMerge
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		ids := make([]bson.ObjectID, 0, len(req.Merge)+1)
+		for _, hex := range append([]string{req.Keep}, req.Merge...) {
+			id, err := bson.ObjectIDFromHex(hex)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid _id", "_id": hex})
+				return
+			}
+			if len(ids) > 0 && id == ids[0] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge an entry into itself"})
+				return
+			}
+			ids = append(ids, id)
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var musics []models.Music
+		if err := findAll(ctx, "musics", bson.M{"_id": bson.M{"$in": ids}}, &musics, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch music"})
+			return
+		}
+
+		var keep models.Music
+		var duplicates []models.Music
+		found := map[bson.ObjectID]bool{}
+		for _, music := range musics {
+			found[music.ID] = true
+			if music.ID == ids[0] {
+				keep = music
+			} else {
+				duplicates = append(duplicates, music)
+			}
+		}
+		for _, id := range ids {
+			if !found[id] {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Music not found", "_id": id.Hex()})
+				return
+			}
+		}
+
+		changes := catalog.Merge(keep, duplicates)
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		mergedIds := make([]string, 0, len(duplicates))
+		for _, duplicate := range duplicates {
+			mergedIds = append(mergedIds, duplicate.MusicID)
+		}
+
+		err := database.RunTransaction(ctx, client, func(ctx context.Context) error {
+			if len(changes) > 0 {
+				if _, err := musicCollection.UpdateOne(ctx, bson.M{"_id": keep.ID}, bson.M{"$set": changes}); err != nil {
+					return err
+				}
+			}
+			for _, duplicate := range duplicates {
+				// Playlists and share links naming a shared music_id already
+				// lead to the kept entry
+				if duplicate.MusicID == keep.MusicID {
+					continue
+				}
+				if err := mergeIntoPlaylists(ctx, duplicate.MusicID, keep.MusicID, client); err != nil {
+					return err
+				}
+				// A link to one entry must not open another one with other access rules
+				if err := deleteShareLinks(ctx, models.ShareTargetMusic, duplicate.MusicID, client); err != nil {
+					return err
+				}
+			}
+			_, err := musicCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids[1:]}})
+			return err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge music", "details": err.Error()})
+			return
+		}
+
+		fields := make([]string, 0, len(changes))
+		for field := range changes {
+			fields = append(fields, field)
+		}
+		recordAudit(c, ctx, "music.merge", "music", keep.MusicID, bson.M{"merged": mergedIds, "fields": fields}, client)
+		refreshCatalogIndexes(client)
+
+		var merged models.Music
+		if err := musicCollection.FindOne(ctx, bson.M{"_id": keep.ID}).Decode(&merged); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Merged, but failed to fetch the kept entry"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"music": models.NewMusicAdminView(merged), "merged": mergedIds, "updated_fields": fields})
+	}
+}
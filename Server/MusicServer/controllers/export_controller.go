@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/access"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/export"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/* This file exports the catalog, a filtered part of it or a playlist as CSV, JSON, M3U8 or XSPF. Entries are streamed from the database as they are read and pass the same access rules as the listings. Anonymous callers get the teaser fields only, so no video links, and only admins get the admin review. */
+
+// Flush the response after this many entries so clients see progress
+const exportFlushEvery = 100
+
+// exportOptions are the fields the caller may see
+func exportOptions(c *gin.Context, title string) export.Options {
+	audience := callerAudience(c)
+	return export.Options{
+		Title:  title,
+		Video:  audience != models.AudienceAnonymous,
+		Review: audience == models.AudienceAdmin,
+	}
+}
+
+// startExport checks the format and writes the download headers. It answers
+// the request itself and returns nil when the export cannot start.
+func startExport(c *gin.Context, filename string, opts export.Options) export.Writer {
+	format := c.DefaultQuery("format", export.FormatCSV)
+
+	writer, err := export.NewWriter(format, c.Writer, opts)
+	switch err {
+	case nil:
+	case export.ErrNeedsVideo:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+"."+format+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	return writer
+}
+
+// finishExport closes the file. Once streaming has started the status is
+// already sent, so a failure can only be logged and the file is cut short.
+func finishExport(writer export.Writer, err error) {
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.Println("Warning: export stopped early:", err)
+	}
+}
+
+// ExportMusics exports the catalog, narrowed by the same genre and ranking
+// filters and sort order as GET /musics
+func ExportMusics(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, err := parseCatalogQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// An export is never paged
+		query.Limit, query.Cursor = 0, nil
+		query.Access = access.Filter("access", callerAccess(c))
+
+		// A large export can take longer than the usual timeout, so the
+		// cursor lives as long as the request and stops when the client leaves
+		var ctx context.Context = c
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		cursor, err := musicCollection.Find(ctx, query.filter(), query.findOptions())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch music"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		opts := exportOptions(c, "TunePeep catalog")
+		writer := startExport(c, "tunepeep-catalog", opts)
+		if writer == nil {
+			return
+		}
+
+		count := 0
+		for cursor.Next(ctx) {
+			var music models.Music
+			if err = cursor.Decode(&music); err != nil {
+				break
+			}
+			if err = writer.Write(export.NewEntry(music, opts)); err != nil {
+				break
+			}
+			if count++; count%exportFlushEvery == 0 {
+				if err = writer.Flush(); err != nil {
+					break
+				}
+				c.Writer.Flush()
+			}
+		}
+		if err == nil {
+			err = cursor.Err()
+		}
+		finishExport(writer, err)
+	}
+}
+
+// ExportPlaylist exports a playlist's entries in saved order, skipping the
+// ones the caller may not see
+func ExportPlaylist(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		playlist, ok := playlistOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		// Playlists the caller may not see look the same as missing ones
+		if !access.CanView(playlist.Access, callerAccess(c)) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+			return
+		}
+
+		var musics []models.Music
+		if err := findAll(ctx, "musics", bson.M{"music_id": bson.M{"$in": playlistMusicIDs(playlist)}}, &musics, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist entries"})
+			return
+		}
+
+		byId := make(map[string]models.Music, len(musics))
+		for _, music := range musics {
+			byId[music.MusicID] = music
+		}
+
+		opts := exportOptions(c, playlist.Title)
+		writer := startExport(c, playlist.PlaylistID, opts)
+		if writer == nil {
+			return
+		}
+
+		var err error
+		for _, item := range playlist.Items {
+			music, ok := byId[item.MusicID]
+			if !ok || !canViewMusic(c, music) {
+				continue
+			}
+			if err = writer.Write(export.NewEntry(music, opts)); err != nil {
+				break
+			}
+		}
+		finishExport(writer, err)
+	}
+}
@@ -0,0 +1,414 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file is the admin genre management API. Genres are copied into every musics.genre and users.favorite_genres array, so renames and merges cascade to those embedded copies inside a MongoDB transaction. A genre that is still in use can only be deleted when it is reassigned to another genre. */
+
+// Collections and array fields that hold embedded copies of a genre
+var genreCopies = []struct {
+	collection string
+	field      string
+}{
+	{"musics", "genre"},
+	{"users", "favorite_genres"},
+}
+
+var errGenreNotFound = errors.New("genre not found")
+
+// How often a new genre_id is picked when another request took it first
+const genreIdAttempts = 5
+
+// genreIDParam reads :genre_id from the URL
+func genreIDParam(c *gin.Context) (int, bool) {
+	genreId, err := strconv.Atoi(c.Param("genre_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "genre_id must be a number"})
+		return 0, false
+	}
+	return genreId, true
+}
+
+// findGenre loads one genre by ID
+func findGenre(ctx context.Context, genreId int, client *mongo.Client) (models.Genre, error) {
+	var genre models.Genre
+
+	var genreCollection *mongo.Collection = database.OpenCollection("genres", client)
+
+	err := genreCollection.FindOne(ctx, bson.M{"genre_id": genreId}).Decode(&genre)
+	if err == mongo.ErrNoDocuments {
+		return genre, errGenreNotFound
+	}
+	return genre, err
+}
+
+// genreNameTaken checks for another genre with the same name, ignoring case
+func genreNameTaken(ctx context.Context, name string, exceptId int, client *mongo.Client) (bool, error) {
+	var genreCollection *mongo.Collection = database.OpenCollection("genres", client)
+
+	filter := bson.M{
+		"genre_name": bson.Regex{Pattern: "^" + regexp.QuoteMeta(name) + "$", Options: "i"},
+		"genre_id":   bson.M{"$ne": exceptId},
+	}
+	count, err := genreCollection.CountDocuments(ctx, filter)
+	return count > 0, err
+}
+
+// genreUsage counts the documents that embed the genre, per collection
+func genreUsage(ctx context.Context, genreId int, client *mongo.Client) (gin.H, int64, error) {
+	usage := gin.H{}
+	var total int64
+	for _, target := range genreCopies {
+		var collection *mongo.Collection = database.OpenCollection(target.collection, client)
+
+		count, err := collection.CountDocuments(ctx, bson.M{target.field + ".genre_id": genreId})
+		if err != nil {
+			return nil, 0, err
+		}
+		usage[target.collection] = count
+		total += count
+	}
+	return usage, total, nil
+}
+
+// renameGenreCopies updates the embedded name everywhere the genre is used
+func renameGenreCopies(ctx context.Context, genreId int, name string, client *mongo.Client) error {
+	for _, target := range genreCopies {
+		var collection *mongo.Collection = database.OpenCollection(target.collection, client)
+
+		update := bson.M{"$set": bson.M{target.field + ".$[g].genre_name": name}}
+		opts := options.UpdateMany().SetArrayFilters([]any{bson.M{"g.genre_id": genreId}})
+
+		if _, err := collection.UpdateMany(ctx, bson.M{target.field + ".genre_id": genreId}, update, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeGenreCopies replaces the embedded copies of from with into. Documents
+// that already have both simply lose from, so no array ends up with duplicates.
+func mergeGenreCopies(ctx context.Context, from, into models.Genre, client *mongo.Client) error {
+	for _, target := range genreCopies {
+		var collection *mongo.Collection = database.OpenCollection(target.collection, client)
+
+		both := bson.M{"$and": []bson.M{
+			{target.field + ".genre_id": from.GenreID},
+			{target.field + ".genre_id": into.GenreID},
+		}}
+		pull := bson.M{"$pull": bson.M{target.field: bson.M{"genre_id": from.GenreID}}}
+		if _, err := collection.UpdateMany(ctx, both, pull); err != nil {
+			return err
+		}
+
+		replace := bson.M{"$set": bson.M{target.field + ".$[g]": into}}
+		opts := options.UpdateMany().SetArrayFilters([]any{bson.M{"g.genre_id": from.GenreID}})
+		if _, err := collection.UpdateMany(ctx, bson.M{target.field + ".genre_id": from.GenreID}, replace, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeGenre moves every use of from onto into and deletes from, in one transaction
+func mergeGenre(ctx context.Context, from, into models.Genre, client *mongo.Client) error {
+	return database.RunTransaction(ctx, client, func(ctx context.Context) error {
+		if err := mergeGenreCopies(ctx, from, into, client); err != nil {
+			return err
+		}
+
+		var genreCollection *mongo.Collection = database.OpenCollection("genres", client)
+
+		_, err := genreCollection.DeleteOne(ctx, bson.M{"genre_id": from.GenreID})
+		return err
+	})
+}
+
+// AdminListGenres lists genres with how many albums and users use each one
+func AdminListGenres(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var genres []models.Genre
+		if err := findAll(ctx, "genres", bson.M{}, &genres, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching music genres"})
+			return
+		}
+
+		response := make([]gin.H, 0, len(genres))
+		for _, genre := range genres {
+			usage, _, err := genreUsage(ctx, genre.GenreID, client)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error counting genre usage"})
+				return
+			}
+			response = append(response, gin.H{"genre_id": genre.GenreID, "genre_name": genre.GenreName, "usage": usage})
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// insertGenre stores a genre with the next free genre_id. Two requests can
+// pick the same id; the unique index rejects the second, which tries again.
+func insertGenre(ctx context.Context, name string, client *mongo.Client) (models.Genre, error) {
+	var genreCollection *mongo.Collection = database.OpenCollection("genres", client)
+
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "genre_id", Value: -1}})
+
+	var err error
+	for attempt := 0; attempt < genreIdAttempts; attempt++ {
+		var last models.Genre
+		err = genreCollection.FindOne(ctx, bson.M{}, findOptions).Decode(&last)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return models.Genre{}, err
+		}
+
+		genre := models.Genre{GenreID: last.GenreID + 1, GenreName: name}
+		_, err = genreCollection.InsertOne(ctx, genre)
+		if !mongo.IsDuplicateKeyError(err) {
+			return genre, err
+		}
+	}
+	return models.Genre{}, err
+}
+
+// AdminCreateGenre adds a genre with the next free genre_id
+func AdminCreateGenre(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.GenreRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		taken, err := genreNameTaken(ctx, req.GenreName, 0, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing genres"})
+			return
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "Genre already exists"})
+			return
+		}
+
+		genre, err := insertGenre(ctx, req.GenreName, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create genre"})
+			return
+		}
+
+		recordAudit(c, ctx, "genre.create", "genre", strconv.Itoa(genre.GenreID), bson.M{"genre_name": genre.GenreName}, client)
+		refreshCatalogIndexes(client)
+
+		c.JSON(http.StatusCreated, genre)
+	}
+}
+
+// AdminRenameGenre renames a genre and every embedded copy of it
+func AdminRenameGenre(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		genreId, ok := genreIDParam(c)
+		if !ok {
+			return
+		}
+
+		var req models.GenreRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		genre, err := findGenre(ctx, genreId, client)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Genre not found"})
+			return
+		}
+
+		taken, err := genreNameTaken(ctx, req.GenreName, genreId, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing genres"})
+			return
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "Another genre already has this name, merge them instead"})
+			return
+		}
+
+		err = database.RunTransaction(ctx, client, func(ctx context.Context) error {
+			var genreCollection *mongo.Collection = database.OpenCollection("genres", client)
+
+			update := bson.M{"$set": bson.M{"genre_name": req.GenreName}}
+			if _, err := genreCollection.UpdateOne(ctx, bson.M{"genre_id": genreId}, update); err != nil {
+				return err
+			}
+			return renameGenreCopies(ctx, genreId, req.GenreName, client)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename genre", "details": err.Error()})
+			return
+		}
+
+		details := bson.M{"from": genre.GenreName, "to": req.GenreName}
+		recordAudit(c, ctx, "genre.rename", "genre", strconv.Itoa(genreId), details, client)
+		refreshCatalogIndexes(client)
+
+		c.JSON(http.StatusOK, models.Genre{GenreID: genreId, GenreName: req.GenreName})
+	}
+}
+
+// AdminMergeGenre merges a genre into another one, e.g. "Alt Rock" into "Alternative"
+func AdminMergeGenre(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		genreId, ok := genreIDParam(c)
+		if !ok {
+			return
+		}
+
+		var req models.GenreMerge
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		if req.IntoGenreID == genreId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a genre into itself"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		from, err := findGenre(ctx, genreId, client)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Genre not found"})
+			return
+		}
+
+		into, err := findGenre(ctx, req.IntoGenreID, client)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target genre not found"})
+			return
+		}
+
+		usage, _, err := genreUsage(ctx, from.GenreID, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error counting genre usage"})
+			return
+		}
+
+		if err := mergeGenre(ctx, from, into, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge genres", "details": err.Error()})
+			return
+		}
+
+		details := bson.M{"from": from.GenreName, "into": into.GenreName, "usage": usage}
+		recordAudit(c, ctx, "genre.merge", "genre", strconv.Itoa(from.GenreID), details, client)
+		refreshCatalogIndexes(client)
+
+		c.JSON(http.StatusOK, gin.H{"merged": from, "into": into, "moved": usage})
+	}
+}
+
+// AdminDeleteGenre deletes an unused genre. A genre in use needs ?reassign_to=<genre_id>.
+func AdminDeleteGenre(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		genreId, ok := genreIDParam(c)
+		if !ok {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		genre, err := findGenre(ctx, genreId, client)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Genre not found"})
+			return
+		}
+
+		usage, inUse, err := genreUsage(ctx, genreId, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error counting genre usage"})
+			return
+		}
+
+		reassignTo := c.Query("reassign_to")
+		if inUse > 0 && reassignTo == "" {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Genre is still in use, pass reassign_to=<genre_id> to move it first",
+				"usage": usage,
+			})
+			return
+		}
+
+		details := bson.M{"genre_name": genre.GenreName, "usage": usage}
+
+		if reassignTo != "" {
+			intoId, err := strconv.Atoi(reassignTo)
+			if err != nil || intoId == genreId {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "reassign_to must be another genre_id"})
+				return
+			}
+
+			into, err := findGenre(ctx, intoId, client)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Target genre not found"})
+				return
+			}
+
+			if err := mergeGenre(ctx, genre, into, client); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reassign genre", "details": err.Error()})
+				return
+			}
+			details["reassigned_to"] = into.GenreName
+		} else {
+			var genreCollection *mongo.Collection = database.OpenCollection("genres", client)
+
+			if _, err := genreCollection.DeleteOne(ctx, bson.M{"genre_id": genreId}); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete genre"})
+				return
+			}
+		}
+
+		recordAudit(c, ctx, "genre.delete", "genre", strconv.Itoa(genreId), details, client)
+		refreshCatalogIndexes(client)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Genre deleted", "deleted": genre, "usage": usage})
+	}
+}
@@ -0,0 +1,377 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file is the admin API for user groups. Groups are named in access rules on music entries and playlists, and a user's memberships are stored in users.groups. Deleting a group removes it from every user, access rule and invite in one transaction. */
+
+// Group names are short lower case slugs, e.g. "beta-testers"
+var groupNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,49}$`)
+
+// Collections and fields that hold group names
+var groupReferences = []struct {
+	collection string
+	field      string
+}{
+	{"users", "groups"},
+	{"musics", "access.groups"},
+	{"playlists", "access.groups"},
+	{"invites", "groups"},
+}
+
+// checkGroupsExist makes sure every name is a group in the groups collection
+func checkGroupsExist(ctx context.Context, names []string, client *mongo.Client) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	var groups []models.Group
+	if err := findAll(ctx, "groups", bson.M{"name": bson.M{"$in": names}}, &groups, client); err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		known[group.Name] = true
+	}
+	for _, name := range names {
+		if !known[name] {
+			return fmt.Errorf("group %s does not exist", name)
+		}
+	}
+	return nil
+}
+
+// checkUsersExist makes sure every ID belongs to a registered user
+func checkUsersExist(ctx context.Context, userIds []string, client *mongo.Client) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+
+	var users []models.User
+	if err := findAll(ctx, "users", bson.M{"user_id": bson.M{"$in": userIds}}, &users, client); err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(users))
+	for _, user := range users {
+		known[user.UserID] = true
+	}
+	for _, userId := range userIds {
+		if !known[userId] {
+			return fmt.Errorf("user %s does not exist", userId)
+		}
+	}
+	return nil
+}
+
+// groupOr404 loads the group from the URL or answers 404
+func groupOr404(c *gin.Context, ctx context.Context, client *mongo.Client) (models.Group, bool) {
+	var group models.Group
+
+	var groupCollection *mongo.Collection = database.OpenCollection("groups", client)
+
+	err := groupCollection.FindOne(ctx, bson.M{"name": c.Param("name")}).Decode(&group)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return group, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group"})
+		return group, false
+	}
+	return group, true
+}
+
+// AdminListGroups lists groups with their member counts
+func AdminListGroups(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var groups []models.Group
+		if err := findAll(ctx, "groups", bson.M{}, &groups, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch groups"})
+			return
+		}
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		response := make([]gin.H, 0, len(groups))
+		for _, group := range groups {
+			members, err := userCollection.CountDocuments(ctx, bson.M{"groups": group.Name})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count group members"})
+				return
+			}
+			response = append(response, gin.H{
+				"name":        group.Name,
+				"description": group.Description,
+				"members":     members,
+				"created_at":  group.CreatedAt,
+				"updated_at":  group.UpdatedAt,
+			})
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// AdminCreateGroup creates an empty group
+func AdminCreateGroup(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.GroupRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		name := strings.ToLower(strings.TrimSpace(req.Name))
+		if !groupNamePattern.MatchString(name) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Group names may only use lower case letters, digits, - and _"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var groupCollection *mongo.Collection = database.OpenCollection("groups", client)
+
+		count, err := groupCollection.CountDocuments(ctx, bson.M{"name": name})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing groups"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Group already exists"})
+			return
+		}
+
+		adminId, _ := utils.GetUserIdFromContext(c)
+		now := time.Now()
+		group := models.Group{
+			Name:        name,
+			Description: req.Description,
+			CreatedBy:   adminId,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+
+		if _, err := groupCollection.InsertOne(ctx, group); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+			return
+		}
+
+		recordAudit(c, ctx, "group.create", "group", name, nil, client)
+
+		c.JSON(http.StatusCreated, group)
+	}
+}
+
+// AdminUpdateGroup changes a group's description. Names are fixed because
+// access rules refer to them.
+func AdminUpdateGroup(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.GroupUpdate
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		group, ok := groupOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		var groupCollection *mongo.Collection = database.OpenCollection("groups", client)
+
+		group.Description = req.Description
+		group.UpdatedAt = time.Now()
+		update := bson.M{"$set": bson.M{"description": group.Description, "updated_at": group.UpdatedAt}}
+		if _, err := groupCollection.UpdateOne(ctx, bson.M{"name": group.Name}, update); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+			return
+		}
+
+		c.JSON(http.StatusOK, group)
+	}
+}
+
+// AdminDeleteGroup deletes a group and removes it from every member, access
+// rule and invite. Restricted resources shared only with the group become admin only.
+func AdminDeleteGroup(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		group, ok := groupOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		removed := bson.M{}
+		err := database.RunTransaction(ctx, client, func(ctx context.Context) error {
+			for _, ref := range groupReferences {
+				var collection *mongo.Collection = database.OpenCollection(ref.collection, client)
+
+				update := bson.M{"$pull": bson.M{ref.field: group.Name}}
+				result, err := collection.UpdateMany(ctx, bson.M{ref.field: group.Name}, update)
+				if err != nil {
+					return err
+				}
+				removed[ref.collection] = result.ModifiedCount
+			}
+
+			var groupCollection *mongo.Collection = database.OpenCollection("groups", client)
+
+			_, err := groupCollection.DeleteOne(ctx, bson.M{"name": group.Name})
+			return err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group", "details": err.Error()})
+			return
+		}
+
+		recordAudit(c, ctx, "group.delete", "group", group.Name, removed, client)
+		refreshCatalogIndexes(client)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Group deleted", "removed_from": removed})
+	}
+}
+
+// AdminListGroupMembers lists the users in a group
+func AdminListGroupMembers(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		group, ok := groupOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		findOptions := options.Find().SetSort(bson.D{{Key: "email", Value: 1}})
+		cursor, err := userCollection.Find(ctx, bson.M{"groups": group.Name}, findOptions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group members"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var users []models.User
+		if err := cursor.All(ctx, &users); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode group members"})
+			return
+		}
+
+		members := make([]models.UserResponse, 0, len(users))
+		for _, user := range users {
+			members = append(members, toUserResponse(user))
+		}
+
+		c.JSON(http.StatusOK, gin.H{"group": group, "members": members})
+	}
+}
+
+// AdminAddGroupMembers adds users to a group. Access changes apply on their next request.
+func AdminAddGroupMembers(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.GroupMembers
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		group, ok := groupOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		if err := checkUsersExist(ctx, req.UserIDs, client); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		update := bson.M{"$addToSet": bson.M{"groups": group.Name}, "$set": bson.M{"update_at": time.Now()}}
+		result, err := userCollection.UpdateMany(ctx, bson.M{"user_id": bson.M{"$in": req.UserIDs}}, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add group members"})
+			return
+		}
+
+		recordAudit(c, ctx, "group.members.add", "group", group.Name, bson.M{"user_ids": req.UserIDs}, client)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Members added", "updated": result.ModifiedCount})
+	}
+}
+
+// AdminRemoveGroupMember removes one user from a group
+func AdminRemoveGroupMember(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		group, ok := groupOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		userId := c.Param("user_id")
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		update := bson.M{"$pull": bson.M{"groups": group.Name}, "$set": bson.M{"update_at": time.Now()}}
+		result, err := userCollection.UpdateOne(ctx, bson.M{"user_id": userId, "groups": group.Name}, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove group member"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User is not in this group"})
+			return
+		}
+
+		recordAudit(c, ctx, "group.members.remove", "group", group.Name, bson.M{"user_id": userId}, client)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+	}
+}
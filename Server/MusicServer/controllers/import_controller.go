@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/spotify"
+)
+
+/* This file connects AddMusic to the Spotify importer. With "import_metadata" set, the entry's music_id is looked up as a Spotify album and the fields the admin left empty are filled from it. cmd/spotify-backfill does the same for entries already in the catalog. */
+
+var (
+	albumProvider     spotify.AlbumProvider
+	albumProviderErr  error
+	albumProviderOnce sync.Once
+)
+
+// getAlbumProvider builds the Spotify client on first use, after main has loaded .env
+func getAlbumProvider() (spotify.AlbumProvider, error) {
+	albumProviderOnce.Do(func() {
+		albumProvider, albumProviderErr = spotify.NewClientFromEnv()
+	})
+	return albumProvider, albumProviderErr
+}
+
+// importAlbum fills the empty fields of music from its Spotify album and
+// returns the HTTP status to answer with when that fails
+func importAlbum(ctx context.Context, music *models.Music) (int, error) {
+	provider, err := getAlbumProvider()
+	if err != nil {
+		return http.StatusServiceUnavailable, err
+	}
+
+	// Accept a pasted spotify:album: URI or open.spotify.com link as the ID
+	id, err := spotify.ParseID(music.MusicID)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	music.MusicID = id
+
+	album, err := provider.Album(ctx, id)
+	switch {
+	case errors.Is(err, spotify.ErrAlbumNotFound):
+		return http.StatusNotFound, err
+	case errors.Is(err, spotify.ErrRateLimited):
+		return http.StatusTooManyRequests, err
+	case err != nil:
+		return http.StatusBadGateway, err
+	}
+
+	spotify.Fill(music, album, false)
+	return 0, nil
+}
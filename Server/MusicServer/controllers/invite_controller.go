@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file controls who may register. It stores the registration mode (open, invite-only or closed) and lets admins create, list, inspect and revoke invite codes. RegisterUser consumes a code to decide the new account's role and access groups. */
+
+const (
+	registrationModeKey   = "registration_mode"
+	defaultInviteLifetime = 7 * 24 * time.Hour
+)
+
+// isRegistrationMode reports whether the value is one of the known modes
+func isRegistrationMode(mode string) bool {
+	switch mode {
+	case models.RegistrationOpen, models.RegistrationInviteOnly, models.RegistrationClosed:
+		return true
+	}
+	return false
+}
+
+// GetRegistrationMode reads the mode set by an admin, falling back to the
+// REGISTRATION_MODE environment variable and finally to open
+func GetRegistrationMode(ctx context.Context, client *mongo.Client) (string, error) {
+	var settingsCollection *mongo.Collection = database.OpenCollection("settings", client)
+
+	var setting models.RegistrationSetting
+	err := settingsCollection.FindOne(ctx, bson.M{"key": registrationModeKey}).Decode(&setting)
+	if err == nil && isRegistrationMode(setting.Mode) {
+		return setting.Mode, nil
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return "", err
+	}
+
+	if mode := os.Getenv("REGISTRATION_MODE"); isRegistrationMode(mode) {
+		return mode, nil
+	}
+	return models.RegistrationOpen, nil
+}
+
+// generateInviteCode creates a readable code like ABCD-EFGH-JKLM
+func generateInviteCode() (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	for i := range raw {
+		raw[i] = alphabet[int(raw[i])%len(alphabet)]
+	}
+	return string(raw[0:4]) + "-" + string(raw[4:8]) + "-" + string(raw[8:12]), nil
+}
+
+// normalizeInviteCode makes code lookups forgiving of case and spacing
+func normalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// consumeInvite atomically uses one slot of a valid invite
+func consumeInvite(ctx context.Context, code, userId, email string, client *mongo.Client) (*models.Invite, error) {
+	var inviteCollection *mongo.Collection = database.OpenCollection("invites", client)
+
+	now := time.Now()
+	filter := bson.M{
+		"code":       normalizeInviteCode(code),
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+		"$expr":      bson.M{"$lt": bson.A{"$uses", "$max_uses"}},
+	}
+	update := bson.M{
+		"$inc":  bson.M{"uses": 1},
+		"$push": bson.M{"used_by": models.InviteUse{UserID: userId, Email: email, UsedAt: now}},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var invite models.Invite
+	if err := inviteCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&invite); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("invalid or expired invite code")
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// releaseInvite gives back an invite use when the registration failed afterwards
+func releaseInvite(ctx context.Context, code, userId string, client *mongo.Client) {
+	var inviteCollection *mongo.Collection = database.OpenCollection("invites", client)
+
+	update := bson.M{
+		"$inc":  bson.M{"uses": -1},
+		"$pull": bson.M{"used_by": bson.M{"user_id": userId}},
+	}
+	inviteCollection.UpdateOne(ctx, bson.M{"code": code}, update)
+}
+
+// inviteStatus describes an invite for listings
+func inviteStatus(invite models.Invite, now time.Time) string {
+	switch {
+	case invite.RevokedAt != nil:
+		return "revoked"
+	case !invite.ExpiresAt.After(now):
+		return "expired"
+	case invite.Uses >= invite.MaxUses:
+		return "used_up"
+	}
+	return "active"
+}
+
+// GetRegistrationInfo tells the client which registration form to show
+func GetRegistrationInfo(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		mode, err := GetRegistrationMode(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read registration mode"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"mode": mode})
+	}
+}
+
+// AdminSetRegistrationMode switches between open, invite-only and closed registration
+func AdminSetRegistrationMode(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.RegistrationSetting
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		adminId, _ := utils.GetUserIdFromContext(c)
+
+		var settingsCollection *mongo.Collection = database.OpenCollection("settings", client)
+
+		update := bson.M{"$set": bson.M{"key": registrationModeKey, "mode": req.Mode, "update_at": time.Now()}}
+		opts := options.UpdateOne().SetUpsert(true)
+		if _, err := settingsCollection.UpdateOne(ctx, bson.M{"key": registrationModeKey}, update, opts); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save registration mode"})
+			return
+		}
+
+		if err := utils.WriteAuditLog(ctx, adminId, "settings.registration_mode", "settings", registrationModeKey, bson.M{"mode": req.Mode}, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Mode saved but audit log could not be written"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"mode": req.Mode})
+	}
+}
+
+// AdminCreateInvite creates a single-use (default) or multi-use invite code
+func AdminCreateInvite(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.InviteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if err := checkGroupsExist(ctx, req.Groups, client); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		adminId, _ := utils.GetUserIdFromContext(c)
+
+		code, err := generateInviteCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invite code"})
+			return
+		}
+
+		now := time.Now()
+		invite := models.Invite{
+			Code:      code,
+			MaxUses:   req.MaxUses,
+			Role:      req.Role,
+			Groups:    req.Groups,
+			ExpiresAt: now.Add(defaultInviteLifetime),
+			CreatedBy: adminId,
+			CreatedAt: now,
+			UsedBy:    []models.InviteUse{},
+		}
+		if invite.MaxUses == 0 {
+			invite.MaxUses = 1
+		}
+		if invite.Role == "" {
+			invite.Role = "USER"
+		}
+		if invite.Groups == nil {
+			invite.Groups = []string{}
+		}
+		if req.ExpiresInHours > 0 {
+			invite.ExpiresAt = now.Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		}
+
+		var inviteCollection *mongo.Collection = database.OpenCollection("invites", client)
+
+		if _, err := inviteCollection.InsertOne(ctx, invite); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invite"})
+			return
+		}
+
+		details := bson.M{"role": invite.Role, "max_uses": invite.MaxUses, "expires_at": invite.ExpiresAt}
+		if err := utils.WriteAuditLog(ctx, adminId, "invite.create", "invite", invite.Code, details, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invite created but audit log could not be written"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, invite)
+	}
+}
+
+// AdminListInvites lists invites, optionally filtered by status
+func AdminListInvites(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var invites []models.Invite
+		findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+		var inviteCollection *mongo.Collection = database.OpenCollection("invites", client)
+
+		cursor, err := inviteCollection.Find(ctx, bson.M{}, findOptions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invites"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		if err := cursor.All(ctx, &invites); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode invites"})
+			return
+		}
+
+		status := c.Query("status")
+		now := time.Now()
+
+		type inviteListItem struct {
+			models.Invite
+			Status string `json:"status"`
+		}
+
+		response := make([]inviteListItem, 0, len(invites))
+		for _, invite := range invites {
+			itemStatus := inviteStatus(invite, now)
+			if status != "" && status != itemStatus {
+				continue
+			}
+			response = append(response, inviteListItem{Invite: invite, Status: itemStatus})
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// AdminGetInvite shows one invite and who used it
+func AdminGetInvite(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var inviteCollection *mongo.Collection = database.OpenCollection("invites", client)
+
+		var invite models.Invite
+		err := inviteCollection.FindOne(ctx, bson.M{"code": normalizeInviteCode(c.Param("code"))}).Decode(&invite)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"invite": invite, "status": inviteStatus(invite, time.Now())})
+	}
+}
+
+// AdminRevokeInvite stops an invite from being used. The record is kept for usage history.
+func AdminRevokeInvite(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		adminId, _ := utils.GetUserIdFromContext(c)
+		code := normalizeInviteCode(c.Param("code"))
+
+		var inviteCollection *mongo.Collection = database.OpenCollection("invites", client)
+
+		filter := bson.M{"code": code, "revoked_at": bson.M{"$exists": false}}
+		result, err := inviteCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invite"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found or already revoked"})
+			return
+		}
+
+		if err := utils.WriteAuditLog(ctx, adminId, "invite.revoke", "invite", code, nil, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invite revoked but audit log could not be written"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Invite revoked", "code": code})
+	}
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/jobs"
+)
+
+/* This file lets admins follow background jobs (such as re-ranking the catalog) and cancel them. Job progress is kept in memory by the jobs package. */
+
+// AdminListJobs lists recent jobs, newest first, optionally filtered by ?kind=
+func AdminListJobs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, jobs.List(c.Query("kind")))
+	}
+}
+
+// AdminGetJob returns the progress of one job
+func AdminGetJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := jobs.Get(c.Param("job_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+// AdminCancelJob asks a running job to stop. Items already processed stay
+// changed. A job that has committed its changes, such as a ranking remap,
+// cannot be canceled.
+func AdminCancelJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := jobs.Cancel(c.Param("job_id"))
+		if errors.Is(err, jobs.ErrJobCommitted) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Job has committed its changes and can no longer be canceled", "job": job})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		if job.Status != jobs.StatusRunning {
+			c.JSON(http.StatusConflict, gin.H{"error": "Job has already finished", "job": job})
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+	}
+}
@@ -0,0 +1,581 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/* This file handles TOTP two-factor authentication. It covers enrollment (secret, provisioning URI and recovery codes), the second step of the login flow, disabling MFA and regenerating recovery codes. MFA is optional for users and mandatory for ADMIN accounts. */
+
+var (
+	errInvalidMFACode      = errors.New("invalid authentication code")
+	errAccountDisabled     = errors.New("Account disabled")
+	errChallengeClosed     = errors.New("MFA challenge already used, locked or expired, log in again")
+	errEnrollmentAbandoned = errors.New("too many invalid codes, start MFA enrollment again")
+	errMFALocked           = errors.New("too many invalid codes, try again later")
+)
+
+// findUserByID loads a user document by its user_id
+func findUserByID(ctx context.Context, userId string, client *mongo.Client) (models.User, error) {
+	var user models.User
+
+	var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+	err := userCollection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user)
+	return user, err
+}
+
+// startMFAEnrollment stores a pending secret and returns what the authenticator app needs
+func startMFAEnrollment(ctx context.Context, user models.User, client *mongo.Client) (models.MFAEnrollmentResponse, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return models.MFAEnrollmentResponse{}, err
+	}
+
+	var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+	update := bson.M{
+		"$set":   bson.M{"mfa_pending_secret": secret, "update_at": time.Now()},
+		"$unset": bson.M{"mfa_pending_attempts": ""},
+	}
+	if _, err := userCollection.UpdateOne(ctx, bson.M{"user_id": user.UserID}, update); err != nil {
+		return models.MFAEnrollmentResponse{}, err
+	}
+
+	uri := utils.TOTPProvisioningURI(user.Email, secret)
+
+	return models.MFAEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: uri,
+		QRPayload:       uri,
+	}, nil
+}
+
+// newRecoveryCodes generates recovery codes and their bcrypt hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hash, err := HashPassword(utils.NormalizeRecoveryCode(code))
+		if err != nil {
+			return nil, nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return codes, hashes, nil
+}
+
+// confirmMFAEnrollment checks the first code from the pending secret and turns MFA on
+func confirmMFAEnrollment(ctx context.Context, user models.User, code string, client *mongo.Client) ([]string, error) {
+	if user.MFAPendingSecret == "" {
+		return nil, errors.New("no MFA enrollment in progress")
+	}
+
+	var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+	// The pending secret only counts while it is the one the codes were tried against
+	pending := bson.M{"user_id": user.UserID, "mfa_pending_secret": user.MFAPendingSecret}
+
+	step, ok := utils.ValidateTOTP(user.MFAPendingSecret, code, time.Now(), 0)
+	if !ok {
+		// Too many wrong codes drop the pending secret, enrollment starts over
+		if user.MFAPendingAttempts+1 >= utils.MaxMFAAttempts {
+			update := bson.M{"$unset": bson.M{"mfa_pending_secret": "", "mfa_pending_attempts": ""}}
+			if _, err := userCollection.UpdateOne(ctx, pending, update); err != nil {
+				return nil, err
+			}
+			return nil, errEnrollmentAbandoned
+		}
+		if _, err := userCollection.UpdateOne(ctx, pending, bson.M{"$inc": bson.M{"mfa_pending_attempts": 1}}); err != nil {
+			return nil, err
+		}
+		return nil, errInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"mfa_enabled":        true,
+			"mfa_secret":         user.MFAPendingSecret,
+			"mfa_recovery_codes": hashes,
+			"mfa_last_step":      step,
+			"update_at":          time.Now(),
+		},
+		"$unset": bson.M{"mfa_pending_secret": "", "mfa_pending_attempts": ""},
+	}
+
+	result, err := userCollection.UpdateOne(ctx, pending, update)
+	if err != nil {
+		return nil, err
+	}
+	// Enrollment was restarted or confirmed by another request in the meantime
+	if result.ModifiedCount == 0 {
+		return nil, errInvalidMFACode
+	}
+	return codes, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code.
+// A recovery code is removed once it has been used.
+func verifySecondFactor(ctx context.Context, user models.User, code, recoveryCode string, client *mongo.Client) error {
+	if !user.MFAEnabled {
+		return errors.New("MFA is not enabled")
+	}
+
+	if code == "" && recoveryCode == "" {
+		return errors.New("code or recovery_code required")
+	}
+
+	if err := reserveMFAFailure(ctx, user.UserID, client); err != nil {
+		return err
+	}
+
+	if code != "" {
+		step, ok := utils.ValidateTOTP(user.MFASecret, code, time.Now(), user.MFALastStep)
+		if !ok {
+			return errInvalidMFACode
+		}
+		if err := acceptTOTPStep(ctx, user.UserID, step, client); err != nil {
+			return err
+		}
+		return resetMFAFailures(ctx, user.UserID, client)
+	}
+
+	normalized := utils.NormalizeRecoveryCode(recoveryCode)
+	for _, hash := range user.RecoveryCodes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(normalized)) != nil {
+			continue
+		}
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		update := bson.M{"$pull": bson.M{"mfa_recovery_codes": hash}}
+		result, err := userCollection.UpdateOne(ctx, bson.M{"user_id": user.UserID}, update)
+		if err != nil {
+			return err
+		}
+		// Someone else used the same code in the meantime
+		if result.ModifiedCount == 0 {
+			return errInvalidMFACode
+		}
+		return resetMFAFailures(ctx, user.UserID, client)
+	}
+	return errInvalidMFACode
+}
+
+// reserveMFAFailure counts a code against the user before it is checked, so
+// fresh challenges from new password logins do not reset the limit. After
+// MaxMFAFailures codes without an accepted one the user is locked out for
+// MFALockout. Counting and locking happen in one update so parallel
+// requests cannot slip past the limit.
+func reserveMFAFailure(ctx context.Context, userId string, client *mongo.Client) error {
+	var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+	now := time.Now()
+	filter := bson.M{"user_id": userId, "mfa_locked_until": bson.M{"$not": bson.M{"$gt": now}}}
+
+	locks := bson.M{"$gte": bson.A{"$mfa_failures", utils.MaxMFAFailures}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"mfa_failures": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$mfa_failures", 0}}, 1}}}}},
+		{{Key: "$set", Value: bson.M{
+			"mfa_locked_until": bson.M{"$cond": bson.A{locks, now.Add(utils.MFALockout), "$$REMOVE"}},
+			"mfa_failures":     bson.M{"$cond": bson.A{locks, 0, "$mfa_failures"}},
+		}}},
+	}
+
+	result, err := userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errMFALocked
+	}
+	return nil
+}
+
+// resetMFAFailures clears the failure count once a code was accepted
+func resetMFAFailures(ctx context.Context, userId string, client *mongo.Client) error {
+	var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+	update := bson.M{"$unset": bson.M{"mfa_failures": "", "mfa_locked_until": ""}}
+	_, err := userCollection.UpdateOne(ctx, bson.M{"user_id": userId}, update)
+	return err
+}
+
+// acceptTOTPStep records the time step of an accepted code. A code of the
+// same or an earlier step was already used, so it is rejected.
+func acceptTOTPStep(ctx context.Context, userId string, step int64, client *mongo.Client) error {
+	var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+	filter := bson.M{
+		"user_id": userId,
+		"$or": []bson.M{
+			{"mfa_last_step": bson.M{"$exists": false}},
+			{"mfa_last_step": bson.M{"$lt": step}},
+		},
+	}
+	result, err := userCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"mfa_last_step": step}})
+	if err != nil {
+		return err
+	}
+	// Another request used this or a later code in the meantime
+	if result.ModifiedCount == 0 {
+		return errInvalidMFACode
+	}
+	return nil
+}
+
+// mfaErrorStatus maps MFA errors to HTTP status codes
+func mfaErrorStatus(err error) int {
+	if errors.Is(err, errInvalidMFACode) {
+		return http.StatusUnauthorized
+	}
+	if errors.Is(err, errEnrollmentAbandoned) || errors.Is(err, errMFALocked) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusBadRequest
+}
+
+// userFromChallenge validates a challenge token and loads its user. With
+// attempt set the request tries a code and counts against the challenge.
+func userFromChallenge(ctx context.Context, mfaToken, purpose string, attempt bool, client *mongo.Client) (models.User, string, error) {
+	claims, err := utils.ValidateMFAChallengeToken(mfaToken, purpose)
+	if err != nil {
+		return models.User{}, "", err
+	}
+
+	var open bool
+	if attempt {
+		open, err = utils.ReserveMFAAttempt(ctx, claims.ID, claims.UserId, client)
+	} else {
+		open, err = utils.IsMFAChallengeOpen(ctx, claims.ID, claims.UserId, client)
+	}
+	if err != nil {
+		return models.User{}, "", err
+	}
+	if !open {
+		return models.User{}, "", errChallengeClosed
+	}
+
+	user, err := findUserByID(ctx, claims.UserId, client)
+	if err != nil {
+		return models.User{}, "", err
+	}
+
+	// The account may have been disabled between the two login steps
+	if user.Disabled {
+		return models.User{}, "", errAccountDisabled
+	}
+	return user, claims.ID, nil
+}
+
+// consumeChallenge uses the challenge up once the second factor was accepted
+func consumeChallenge(c *gin.Context, ctx context.Context, challengeId string, client *mongo.Client) bool {
+	consumed, err := utils.ConsumeMFAChallenge(ctx, challengeId, client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete MFA challenge"})
+		return false
+	}
+	if !consumed {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errChallengeClosed.Error()})
+		return false
+	}
+	return true
+}
+
+// challengeError reports a failed challenge token lookup
+func challengeError(c *gin.Context, err error) {
+	if errors.Is(err, errAccountDisabled) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errChallengeClosed) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+}
+
+// VerifyMFALogin is the second login step for users with MFA enabled
+func VerifyMFALogin(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MFAChallengeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, challengeId, err := userFromChallenge(ctx, req.MFAToken, utils.MFAPurposeVerify, true, client)
+		if err != nil {
+			challengeError(c, err)
+			return
+		}
+
+		if err := verifySecondFactor(ctx, user, req.Code, req.RecoveryCode, client); err != nil {
+			c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		if !consumeChallenge(c, ctx, challengeId, client) {
+			return
+		}
+
+		response, err := issueTokens(c, user, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// BeginLoginMFAEnrollment starts enrollment for an admin who was stopped at login
+func BeginLoginMFAEnrollment(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MFAChallengeRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.MFAToken == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mfa_token required"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, _, err := userFromChallenge(ctx, req.MFAToken, utils.MFAPurposeEnroll, false, client)
+		if err != nil {
+			challengeError(c, err)
+			return
+		}
+
+		enrollment, err := startMFAEnrollment(ctx, user, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start MFA enrollment"})
+			return
+		}
+
+		c.JSON(http.StatusOK, enrollment)
+	}
+}
+
+// ConfirmLoginMFAEnrollment finishes enrollment during login and issues the real tokens
+func ConfirmLoginMFAEnrollment(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MFAChallengeRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.MFAToken == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mfa_token and code required"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, challengeId, err := userFromChallenge(ctx, req.MFAToken, utils.MFAPurposeEnroll, true, client)
+		if err != nil {
+			challengeError(c, err)
+			return
+		}
+
+		recoveryCodes, err := confirmMFAEnrollment(ctx, user, req.Code, client)
+		if err != nil {
+			c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		if !consumeChallenge(c, ctx, challengeId, client) {
+			return
+		}
+
+		user.MFAEnabled = true
+		response, err := issueTokens(c, user, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"user": response, "recovery_codes": recoveryCodes})
+	}
+}
+
+// currentUser loads the authenticated user from the request context
+func currentUser(c *gin.Context, ctx context.Context, client *mongo.Client) (models.User, bool) {
+	userId, err := utils.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userId not found in context"})
+		return models.User{}, false
+	}
+
+	user, err := findUserByID(ctx, userId, client)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return models.User{}, false
+	}
+	return user, true
+}
+
+// BeginMFAEnrollment starts enrollment for a logged in user
+func BeginMFAEnrollment(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := currentUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		if user.MFAEnabled {
+			c.JSON(http.StatusConflict, gin.H{"error": "MFA is already enabled"})
+			return
+		}
+
+		enrollment, err := startMFAEnrollment(ctx, user, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start MFA enrollment"})
+			return
+		}
+
+		c.JSON(http.StatusOK, enrollment)
+	}
+}
+
+// ConfirmMFAEnrollment turns MFA on for a logged in user and returns the recovery codes
+func ConfirmMFAEnrollment(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MFACode
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := currentUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		recoveryCodes, err := confirmMFAEnrollment(ctx, user, req.Code, client)
+		if err != nil {
+			c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"mfa_enabled": true, "recovery_codes": recoveryCodes})
+	}
+}
+
+// DisableMFA turns MFA off after checking a current code. Admins cannot opt out.
+func DisableMFA(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MFACode
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := currentUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		if user.Role == "ADMIN" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "MFA is mandatory for admin accounts"})
+			return
+		}
+
+		if err := verifySecondFactor(ctx, user, req.Code, req.RecoveryCode, client); err != nil {
+			c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		update := bson.M{
+			"$set":   bson.M{"mfa_enabled": false, "update_at": time.Now()},
+			"$unset": bson.M{"mfa_secret": "", "mfa_pending_secret": "", "mfa_pending_attempts": "", "mfa_recovery_codes": "", "mfa_last_step": "", "mfa_failures": "", "mfa_locked_until": ""},
+		}
+
+		if _, err := userCollection.UpdateOne(ctx, bson.M{"user_id": user.UserID}, update); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable MFA"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"mfa_enabled": false})
+	}
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current code
+func RegenerateRecoveryCodes(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MFACode
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := currentUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		// Only a TOTP code is accepted here, a recovery code would be replaced anyway
+		if req.Code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "code required"})
+			return
+		}
+		if err := verifySecondFactor(ctx, user, req.Code, "", client); err != nil {
+			c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		codes, hashes, err := newRecoveryCodes()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+			return
+		}
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		update := bson.M{"$set": bson.M{"mfa_recovery_codes": hashes, "update_at": time.Now()}}
+		if _, err := userCollection.UpdateOne(ctx, bson.M{"user_id": user.UserID}, update); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save recovery codes"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	}
+}
@@ -1,9 +1,10 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -12,8 +13,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/access"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/catalog"
 	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
 	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
 	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
@@ -23,22 +25,66 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-/* This file provides functions for CRUD operations on music entries, music recommendations, reviews and ranking assignment. */
+/* This file provides functions for CRUD operations on music entries, music recommendations, reviews and ranking assignment. Every read is filtered by the album's access rule. */
 
-var validate = validator.New()
+var validate = catalog.Validate
 
+// callerAudience decides which music view the caller gets, based on the
+// role set by AuthMiddleWare or OptionalAuthMiddleWare
+func callerAudience(c *gin.Context) string {
+	role, err := utils.GetRoleFromContext(c)
+	if err != nil {
+		return models.AudienceAnonymous
+	}
+	if role == "ADMIN" {
+		return models.AudienceAdmin
+	}
+	return models.AudienceMember
+}
 
+// canViewMusic reports whether the caller may know the album exists, based
+// on the album's access rule
+func canViewMusic(c *gin.Context, music models.Music) bool {
+	return access.CanView(music.Access, callerAccess(c))
+}
+
+
+// GetMusics lists the catalog. Without limit or cursor it returns every
+// matching album as a plain array (what the React client expects), with
+// them it returns a page envelope with next_cursor and total.
 func GetMusics(client *mongo.Client) gin.HandlerFunc {
 		return func(c *gin.Context){
-			// c.JSON(200, gin.H{"message":"List of Albums"})
+			query, err := parseCatalogQuery(c)
+
+			if err != nil{
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			query.Access = access.Filter("access", callerAccess(c))
+
 			ctx, cancel := context.WithTimeout(c, 100*time.Second)
 			defer cancel()
 
 			var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
 
+			total, err := musicCollection.CountDocuments(ctx, query.filter())
+
+			if err != nil{
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count music"})
+				return
+			}
+
+			filter, err := query.pageFilter()
+
+			if err != nil{
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+				return
+			}
+
 			var musics []models.Music
 
-			cursor, err := musicCollection.Find(ctx, bson.M{})
+			cursor, err := musicCollection.Find(ctx, filter, query.findOptions())
 
 			if err !=nil{
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch music"})
@@ -51,7 +97,27 @@ func GetMusics(client *mongo.Client) gin.HandlerFunc {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode music"})
 				return  
 			}
-			c.JSON(http.StatusOK,musics)
+
+			// The extra document fetched by findOptions means there is a next page
+			nextCursor := ""
+			if query.Limit > 0 && int64(len(musics)) > query.Limit {
+				musics = musics[:query.Limit]
+				last := musics[len(musics)-1]
+				nextCursor = query.nextCursor(last)
+			}
+
+			c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+
+			if !query.Paged {
+				c.JSON(http.StatusOK, models.NewMusicViews(musics, callerAudience(c)))
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"items":       models.NewMusicViews(musics, callerAudience(c)),
+				"next_cursor": nextCursor,
+				"total":       total,
+			})
 		}
 }
 
@@ -74,11 +140,12 @@ func GetMusic(client *mongo.Client) gin.HandlerFunc {
 
 			err := musicCollection.FindOne(ctx, bson.M{"music_id": musicID}).Decode(&music)
 			
-			if err != nil{
+			// Albums the caller may not see look the same as missing ones
+			if err != nil || !canViewMusic(c, music){
 				c.JSON(http.StatusNotFound, gin.H{"error":"Album not found"})
 				return
 			}
-			c.JSON(http.StatusOK, music)
+			c.JSON(http.StatusOK, models.NewMusicView(music, callerAudience(c)))
 	}
 
 }
@@ -88,15 +155,7 @@ func AddMusic(client *mongo.Client) gin.HandlerFunc {
         ctx, cancel := context.WithTimeout(c, 100*time.Second)
         defer cancel()
 
-        var musicReq struct {
-            MusicID    string          `json:"music_id" validate:"required"`
-            Title      string          `json:"title" validate:"required,min=2,max=500"`
-            AlbumImg   string          `json:"album_img" validate:"required,url"`
-            YouTubeID  string          `json:"youtube_id" validate:"required"`
-            Genre      []models.Genre  `json:"genre" validate:"required,dive"`
-            AdminReview string         `json:"admin_review,omitempty"`
-            Ranking    models.Ranking  `json:"ranking,omitempty"`
-        }
+        var musicReq models.MusicRequest
         
         // Bind JSON from request
         if err := c.ShouldBindJSON(&musicReq); err != nil {
@@ -104,44 +163,83 @@ func AddMusic(client *mongo.Client) gin.HandlerFunc {
             return
         }
 
-        // Create the complete Music struct with defaults
-        music := models.Music{
-            MusicID:     musicReq.MusicID,
-            Title:       musicReq.Title,
-            AlbumImg:    musicReq.AlbumImg,
-            YouTubeID:   musicReq.YouTubeID,
-            Genre:       musicReq.Genre,
-            AdminReview: musicReq.AdminReview,
-            Ranking:     musicReq.Ranking,
+        // Admins often paste a full URL, keep only the video ID and timestamps
+        music, err := catalog.NewMusic(musicReq)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid youtube_id", "details": err.Error()})
+            return
+        }
+
+        // Music IDs are Spotify album IDs, so Spotify can fill in the rest
+        if musicReq.ImportMetadata {
+            if status, err := importAlbum(ctx, &music); err != nil {
+                c.JSON(status, gin.H{"error": "Failed to import album metadata", "details": err.Error()})
+                return
+            }
         }
 
-        // Ensure default admin review if not provided
-        if music.AdminReview == "" {
-            music.AdminReview = " "
+        // Genres must exist, given by ID or by name
+        genres, err := catalog.LoadGenres(ctx, client)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch genres"})
+            return
+        }
+        if music.Genre, _, err = genres.Resolve(music.Genre); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid genre", "details": err.Error()})
+            return
         }
 
-        // Ensure default ranking if not provided
-        if music.Ranking.RankingValue == 0 || music.Ranking.RankingName == "" {
-            music.Ranking = models.Ranking{
-                RankingValue: 999,
-                RankingName: "Not_Ranked",
-            }
+        // Referenced artists must exist
+        missingArtists, err := catalog.MissingArtists(ctx, music.ArtistIDs, client)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check artists"})
+            return
+        }
+        if len(missingArtists) > 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown artist_ids", "artist_ids": missingArtists})
+            return
         }
 
-        // Validate the populated music struct
-        if err := validate.Struct(music); err != nil {
+        // Fill in the default review and ranking, then validate
+        if err := catalog.Finish(&music); err != nil {
             c.JSON(http.StatusBadRequest, gin.H{"error":"Validation failed", "details":err.Error()})
             return
         }
+
+        // Unlisted entries can be restricted from the start
+        if music.Access != nil {
+            if status, err := checkAccessRule(ctx, music.Access, client); err != nil {
+                c.JSON(status, gin.H{"error": "Invalid access rule", "details": err.Error()})
+                return
+            }
+        }
+
+        // The same music_id or video twice is refused, a similar title only warned about
+        duplicates, similar, err := catalog.FindDuplicates(ctx, music, "", client)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check for duplicates"})
+            return
+        }
+        if len(duplicates) > 0 {
+            c.JSON(http.StatusConflict, gin.H{"error": "Music already exists", "duplicates": duplicates})
+            return
+        }
         
         var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
 
         result, err := musicCollection.InsertOne(ctx, music)
+        // Another request added the same music_id or video after the check above
+        if mongo.IsDuplicateKeyError(err) {
+            c.JSON(http.StatusConflict, gin.H{"error": "music_id or youtube_id already exists"})
+            return
+        }
         if err != nil{
             c.JSON(http.StatusInternalServerError, gin.H{"error" : "Failed to add music"})
             return
         }
-        c.JSON(http.StatusCreated, result)
+        refreshCatalogIndexes(client)
+        setDuplicateWarning(c, similar)
+        c.JSON(http.StatusCreated, gin.H{"InsertedID": result.InsertedID, "possible_duplicates": similar})
     }
 }
 
@@ -214,6 +312,7 @@ func AdminReviewUpdate(client *mongo.Client) gin.HandlerFunc{
 			c.JSON(http.StatusNotFound, gin.H{"error":"Music not found"})
 			return
 		}
+		refreshCatalogIndexes(client)
 
 		resp.RankingName = sentiment
 		resp.AdminReview = req.AdminReview
@@ -222,7 +321,9 @@ func AdminReviewUpdate(client *mongo.Client) gin.HandlerFunc{
 	}
 }
 
-func GetReviewRanking(admin_review string, client *mongo.Client, c *gin.Context)(string, int,error){
+// GetReviewRanking asks the LLM to pick a ranking tier for a review. An answer
+// that matches no tier leaves the album Not_Ranked.
+func GetReviewRanking(admin_review string, client *mongo.Client, c context.Context)(string, int,error){
 		rankings, err := GetRankings(client, c)
 
 		if err != nil{
@@ -232,7 +333,7 @@ func GetReviewRanking(admin_review string, client *mongo.Client, c *gin.Context)
 		sentimentDelimited := ""
 
 		for _, ranking := range rankings {
-			if ranking.RankingValue != 999 {
+			if ranking.RankingValue != models.NotRankedValue {
 				sentimentDelimited = sentimentDelimited + ranking.RankingName + ","
 			}
 		}
@@ -267,19 +368,19 @@ func GetReviewRanking(admin_review string, client *mongo.Client, c *gin.Context)
 			return "", 0, err
 		}
 
-		rankVal := 0
+		response = strings.TrimSpace(response)
 
 		for _, ranking := range rankings {
-			if ranking.RankingName == response {
-				rankVal = ranking.RankingValue
-				break
+			if ranking.RankingName == response && ranking.RankingValue != models.NotRankedValue {
+				return ranking.RankingName, ranking.RankingValue, nil
 			}
 		}
 
-		return response, rankVal, nil
+		return models.NotRankedName, models.NotRankedValue, nil
 }
 
-func GetRankings(client *mongo.Client, c *gin.Context)([]models.Ranking, error){
+// GetRankings returns the ranking tiers, best first
+func GetRankings(client *mongo.Client, c context.Context)([]models.Ranking, error){
 	
 	var rankings []models.Ranking
 
@@ -288,7 +389,8 @@ func GetRankings(client *mongo.Client, c *gin.Context)([]models.Ranking, error){
 
 	var rankingCollection *mongo.Collection = database.OpenCollection("rankings", client)
 
-	cursor, err := rankingCollection.Find(ctx, bson.M{})
+	findOptions := options.Find().SetSort(bson.D{{Key: "ranking_value", Value: 1}})
+	cursor, err := rankingCollection.Find(ctx, bson.M{}, findOptions)
 
 	if err != nil {
 		return nil, err
@@ -344,7 +446,7 @@ func GetRecommendedMusics(client *mongo.Client) gin.HandlerFunc{
 
 			findOptions.SetLimit(recommendedMusicLimitVal)
 
-			filter := bson.M{"genre.genre_name" : bson.M{"$in":favorite_genres}}
+			filter := access.And(bson.M{"genre.genre_name" : bson.M{"$in":favorite_genres}}, access.Filter("access", callerAccess(c)))
 
 			var ctx, cancel = context.WithTimeout(c, 100*time.Second)
 			defer cancel()
@@ -367,7 +469,7 @@ func GetRecommendedMusics(client *mongo.Client) gin.HandlerFunc{
 						return
 			}
 
-			c.JSON(http.StatusOK, recommendedMusics)
+			c.JSON(http.StatusOK, models.NewMusicViews(recommendedMusics, callerAudience(c)))
 			
 	 }
 }
@@ -458,42 +560,17 @@ func EditMusic(client *mongo.Client) gin.HandlerFunc {
             return
         }
 
-        var updateData map[string]interface{}
-        
-        if err := c.ShouldBindJSON(&updateData); err != nil {
+        body, err := c.GetRawData()
+        if err != nil {
             c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
             return
         }
 
-        // Define allowed fields that can be updated (including music_id)
-        allowedFields := map[string]bool{
-            "music_id":  true,
-            "title":     true,
-            "album_img": true,
-            "youtube_id": true,
-            "genre":     true,
-        }
-
-        // Filter updateData to only include allowed fields
-        filteredUpdate := bson.M{}
-        for key, value := range updateData {
-            if allowedFields[key] {
-                // Special validation for genre field
-                if key == "genre" {
-                    // Validate genre structure
-                    validatedGenre, err := validateGenreField(value)
-                    if err != nil {
-                        c.JSON(http.StatusBadRequest, gin.H{
-                            "error": "Invalid genre data",
-                            "details": err.Error(),
-                        })
-                        return
-                    }
-                    filteredUpdate[key] = validatedGenre
-                } else {
-                    filteredUpdate[key] = value
-                }
-            }
+        var updateData map[string]json.RawMessage
+        
+        if err := json.Unmarshal(body, &updateData); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+            return
         }
 
         // Prevent changing the autogenerated _id field only
@@ -519,42 +596,105 @@ func EditMusic(client *mongo.Client) gin.HandlerFunc {
             return
         }
 
-        if len(filteredUpdate) == 0 {
-            // Check if they tried to update disallowed fields
-            if len(updateData) > 0 {
-                var attemptedFields []string
-                for key := range updateData {
-                    attemptedFields = append(attemptedFields, key)
-                }
-                c.JSON(http.StatusBadRequest, gin.H{
-                    "error": "No allowed fields provided for update",
-                    "attempted_fields": attemptedFields,
-                    "allowed_fields": []string{"music_id", "title", "album_img", "youtube_id", "genre"},
-                })
-                return
-            }
+        if _, exists := updateData["access"]; exists {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change access field. Use /admin/musics/:music_id/access endpoint"})
+            return
+        }
+
+        if len(updateData) == 0 {
             c.JSON(http.StatusBadRequest, gin.H{"error": "No fields provided for update"})
             return
         }
 
-        var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+        // Every field has a type, so unknown fields and wrong types are refused
+        var req models.MusicUpdate
+        decoder := json.NewDecoder(bytes.NewReader(body))
+        decoder.DisallowUnknownFields()
+        if err := decoder.Decode(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{
+                "error": "Invalid update data",
+                "details": err.Error(),
+                "allowed_fields": catalog.EditableFields,
+            })
+            return
+        }
+
+        edit, err := catalog.NewEdit(req)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+            return
+        }
+        if edit.Empty() {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "No fields provided for update"})
+            return
+        }
 
-        // If music_id is being updated, we need to handle it specially
-        var newMusicID string
-        if newID, exists := filteredUpdate["music_id"]; exists {
-            if idStr, ok := newID.(string); ok && idStr != "" {
-                newMusicID = idStr
-            } else {
-                c.JSON(http.StatusBadRequest, gin.H{"error": "music_id must be a non-empty string"})
+        // Genres must exist, given by ID or name like in /addmusic
+        if req.Genre != nil {
+            genres, err := catalog.LoadGenres(ctx, client)
+            if err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load genres"})
                 return
             }
+            resolved, _, err := genres.Resolve(*req.Genre)
+            if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid genre data", "details": err.Error()})
+                return
+            }
+            edit.Set["genre"] = resolved
+        }
+
+        // Referenced artists must exist
+        if artistIDs, ok := edit.Set["artist_ids"].([]string); ok {
+            missingArtists, err := catalog.MissingArtists(ctx, artistIDs, client)
+            if err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check artists"})
+                return
+            }
+            if len(missingArtists) > 0 {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown artist_ids", "artist_ids": missingArtists})
+                return
+            }
+        }
+
+        // The end has to come after the start, compared with the stored values
+        // when only one side changes
+        if status, err := checkYouTubeRange(ctx, musicID, edit.Set, client); err != nil {
+            c.JSON(status, gin.H{"error": "Invalid YouTube timestamps", "details": err.Error()})
+            return
         }
 
+        // A new music_id or video must not belong to another entry, a new
+        // title that looks like another one is only warned about
+        similar, status, err := checkEditDuplicates(ctx, musicID, edit.Set, client)
+        if err != nil {
+            c.JSON(status, gin.H{"error": err.Error(), "duplicates": similar})
+            return
+        }
+
+        var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+        // If music_id is being updated, the updated document is fetched by the new one
+        newMusicID, _ := edit.Set["music_id"].(string)
+
         filter := bson.M{"music_id": musicID}
-        update := bson.M{"$set": filteredUpdate}
 
-        result, err := musicCollection.UpdateOne(ctx, filter, update)
+        // A new music_id is carried over to everything that points at the
+        // entry, in the same transaction as the entry itself
+        var result *mongo.UpdateResult
+        err = database.RunTransaction(ctx, client, func(ctx context.Context) error {
+            var err error
+            result, err = musicCollection.UpdateOne(ctx, filter, edit.Document())
+            if err != nil || result.MatchedCount == 0 || newMusicID == "" || newMusicID == musicID {
+                return err
+            }
+            return renameMusicReferences(ctx, musicID, newMusicID, client)
+        })
 
+        if mongo.IsDuplicateKeyError(err) {
+            c.JSON(http.StatusConflict, gin.H{"error": "music_id or youtube_id already exists"})
+            return
+        }
         if err != nil {
             c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update music"})
             return
@@ -564,6 +704,7 @@ func EditMusic(client *mongo.Client) gin.HandlerFunc {
             c.JSON(http.StatusNotFound, gin.H{"error": "Music not found"})
             return
         }
+        refreshCatalogIndexes(client)
 
         // If music_id was changed, use the new ID to fetch the updated document
         var fetchFilter bson.M
@@ -586,65 +727,107 @@ func EditMusic(client *mongo.Client) gin.HandlerFunc {
             return
         }
 
-        c.JSON(http.StatusOK, updatedMusic)
+        setDuplicateWarning(c, similar)
+        c.JSON(http.StatusOK, models.NewMusicView(updatedMusic, callerAudience(c)))
     }
 }
 
-// Helper function so we can validate the genre field
-func validateGenreField(value interface{}) (interface{}, error) {
-    // Check if it's an array/slice
-    genresSlice, ok := value.([]interface{})
-    if !ok {
-        return nil, errors.New("genre must be an array")
+// Helper function to point playlists, share links and artist proposals at
+// the new music_id of a renamed entry
+func renameMusicReferences(ctx context.Context, from, into string, client *mongo.Client) error {
+    if err := mergeIntoPlaylists(ctx, from, into, client); err != nil {
+        return err
     }
 
-    validatedGenres := make([]map[string]interface{}, 0, len(genresSlice))
-    
-    for i, genreItem := range genresSlice {
-        genreMap, ok := genreItem.(map[string]interface{})
-        if !ok {
-            return nil, fmt.Errorf("genre item %d must be an object", i)
-        }
+    var shareCollection *mongo.Collection = database.OpenCollection("share_links", client)
+    var proposalCollection *mongo.Collection = database.OpenCollection("artist_proposals", client)
 
-        validatedGenre := make(map[string]interface{})
-        
-        // Validate genre_name
-        if genreName, exists := genreMap["genre_name"]; exists {
-            if nameStr, ok := genreName.(string); ok && nameStr != "" {
-                validatedGenre["genre_name"] = nameStr
-            } else {
-                return nil, fmt.Errorf("genre_name at index %d must be a non-empty string", i)
-            }
+    _, err := shareCollection.UpdateMany(ctx,
+        bson.M{"target_type": models.ShareTargetMusic, "target_id": from},
+        bson.M{"$set": bson.M{"target_id": into}},
+    )
+    if err != nil {
+        return err
+    }
+
+    _, err = proposalCollection.UpdateMany(ctx, bson.M{"music_id": from}, bson.M{"$set": bson.M{"music_id": into}})
+    return err
+}
+
+// Helper function to check an edit for duplicates. It refuses a music_id or
+// video that another entry already has and returns the entries a new title
+// looks like.
+func checkEditDuplicates(ctx context.Context, musicID string, update bson.M, client *mongo.Client) ([]catalog.Duplicate, int, error) {
+    newID, changesID := update["music_id"].(string)
+    newVideo, changesVideo := update["youtube_id"].(string)
+    newTitle, changesTitle := update["title"].(string)
+    if !changesID && !changesVideo && !changesTitle {
+        return nil, 0, nil
+    }
+
+    var music models.Music
+
+    var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+    if err := musicCollection.FindOne(ctx, bson.M{"music_id": musicID}).Decode(&music); err != nil {
+        return nil, http.StatusNotFound, errors.New("Music not found")
+    }
+
+    // Only the changed fields are checked, so older duplicates do not block other edits
+    edited := models.Music{Title: music.Title, Artist: music.Artist}
+    if changesID && newID != musicID {
+        edited.MusicID = newID
+    }
+    if changesVideo && newVideo != music.YouTubeID {
+        edited.YouTubeID = newVideo
+    }
+    if changesTitle {
+        edited.Title = newTitle
+    }
+
+    duplicates, similar, err := catalog.FindDuplicates(ctx, edited, musicID, client)
+    if err != nil {
+        return nil, http.StatusInternalServerError, errors.New("Failed to check for duplicates")
+    }
+    if len(duplicates) > 0 {
+        return duplicates, http.StatusConflict, errors.New("Another entry already has this music_id or youtube_id")
+    }
+    if !changesTitle {
+        return nil, 0, nil
+    }
+    return similar, 0, nil
+}
+
+// Helper function to check youtube_end > youtube_start after an edit. An end
+// of 0 means the video plays to the end.
+func checkYouTubeRange(ctx context.Context, musicID string, update bson.M, client *mongo.Client) (int, error) {
+    start, hasStart := update["youtube_start"].(int)
+    end, hasEnd := update["youtube_end"].(int)
+    if !hasStart && !hasEnd {
+        return 0, nil
+    }
+
+    if !hasStart || !hasEnd {
+        var music models.Music
+
+        var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+        if err := musicCollection.FindOne(ctx, bson.M{"music_id": musicID}).Decode(&music); err != nil {
+            return http.StatusNotFound, errors.New("music not found")
         }
-        
-        // Validate genre_id - convert to number if it's a string, because of course I messed this up too many times
-        if genreID, exists := genreMap["genre_id"]; exists {
-            switch v := genreID.(type) {
-            case string:
-                // Try to convert string to integer
-                if intVal, err := strconv.Atoi(v); err == nil {
-                    validatedGenre["genre_id"] = intVal
-                } else {
-                    return nil, fmt.Errorf("genre_id at index %d must be a number (could not convert '%s')", i, v)
-                }
-            case float64:
-                // JSON numbers come as float64 so convert to int
-                validatedGenre["genre_id"] = int(v)
-            case int:
-                validatedGenre["genre_id"] = v
-            default:
-                return nil, fmt.Errorf("genre_id at index %d must be a number, got %T", i, v)
-            }
+        if !hasStart {
+            start = music.YouTubeStart
         }
-        
-        if len(validatedGenre) > 0 {
-            validatedGenres = append(validatedGenres, validatedGenre)
+        if !hasEnd {
+            end = music.YouTubeEnd
         }
     }
-    
-    return validatedGenres, nil
-}
 
+    if end > 0 && end <= start {
+        return http.StatusBadRequest, errors.New("youtube_end must be after youtube_start")
+    }
+    return 0, nil
+}
 
 func DeleteMusic(client *mongo.Client) gin.HandlerFunc {
     return func(c *gin.Context){
@@ -685,10 +868,25 @@ func DeleteMusic(client *mongo.Client) gin.HandlerFunc {
             c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
             return
         }
+        refreshCatalogIndexes(client)
+
+        // Playlists must not keep pointing at the deleted album
+        playlists, err := removeFromPlaylists(ctx, musicID, client)
+
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Music deleted but could not be removed from playlists"})
+            return
+        }
+
+        if err := deleteShareLinks(ctx, models.ShareTargetMusic, musicID, client); err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Music deleted but its share links could not be removed"})
+            return
+        }
 
         c.JSON(http.StatusOK, gin.H{
             "message": "Music deleted",
             "deleted_count": result.DeletedCount,
+            "playlists_updated": playlists,
         })
     }
 }
\ No newline at end of file
@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/jobs"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/musicbrainz"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file enriches music entries with MusicBrainz release data. A background job searches each entry by title and artist, stores clear matches with their MBIDs, release date, labels and track durations, and keeps the candidates of ambiguous ones. Admins review those and confirm the right release or reject them. */
+
+const (
+	musicBrainzJobKind    = "musicbrainz"
+	musicBrainzCandidates = 5
+)
+
+var (
+	musicBrainzClient     *musicbrainz.Client
+	musicBrainzClientOnce sync.Once
+)
+
+// getMusicBrainzClient builds the client on first use, after main has loaded .env
+func getMusicBrainzClient() *musicbrainz.Client {
+	musicBrainzClientOnce.Do(func() {
+		musicBrainzClient = musicbrainz.NewClientFromEnv()
+	})
+	return musicBrainzClient
+}
+
+// musicBrainzQuery is the title and artist to search for. Without an
+// imported artist, titles like "Deftones - White Pony" are split.
+func musicBrainzQuery(music models.Music) (string, string) {
+	if music.Artist != "" {
+		return music.Title, music.Artist
+	}
+	if artist := artistFromTitle(music.Title); artist != "" {
+		return strings.TrimSpace(strings.SplitN(music.Title, " - ", 2)[1]), artist
+	}
+	return music.Title, ""
+}
+
+// enrichMusic looks a music entry up on MusicBrainz
+func enrichMusic(ctx context.Context, music models.Music) (models.MusicBrainzInfo, error) {
+	client := getMusicBrainzClient()
+	title, artist := musicBrainzQuery(music)
+
+	candidates, err := client.SearchReleases(ctx, title, artist, musicBrainzCandidates)
+	if err != nil {
+		return models.MusicBrainzInfo{}, err
+	}
+	if len(candidates) == 0 {
+		return models.MusicBrainzInfo{Status: models.MusicBrainzNotFound, UpdatedAt: time.Now()}, nil
+	}
+
+	best, ok := musicbrainz.Match(candidates, title, artist)
+	if !ok {
+		return models.MusicBrainzInfo{Status: models.MusicBrainzAmbiguous, Candidates: candidates, UpdatedAt: time.Now()}, nil
+	}
+
+	release, err := client.Release(ctx, best.ReleaseID)
+	if err != nil {
+		return models.MusicBrainzInfo{}, err
+	}
+	return release.Info(models.MusicBrainzMatched), nil
+}
+
+// startEnrichJob runs enrichMusic over the catalog, or over req.MusicIDs
+func startEnrichJob(c *gin.Context, req models.MusicBrainzEnrichRequest, client *mongo.Client) (jobs.Job, error) {
+	adminId, _ := utils.GetUserIdFromContext(c)
+
+	// Admin decisions are final, the job never overrides them
+	undecided := bson.M{"$nin": []string{models.MusicBrainzConfirmed, models.MusicBrainzRejected}}
+	filter := bson.M{"musicbrainz": bson.M{"$exists": false}}
+	if req.Rematch {
+		filter = bson.M{"musicbrainz.status": undecided}
+	}
+	if len(req.MusicIDs) > 0 {
+		filter["music_id"] = bson.M{"$in": req.MusicIDs}
+	}
+
+	return jobs.Start(musicBrainzJobKind, "enrich", adminId, func(ctx context.Context, progress *jobs.Progress) error {
+		defer refreshCatalogIndexes(client)
+
+		var musics []models.Music
+		if err := findAll(ctx, "musics", filter, &musics, client); err != nil {
+			return err
+		}
+		progress.SetTotal(len(musics))
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		for _, music := range musics {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			info, err := enrichMusic(ctx, music)
+			if err == nil {
+				// An admin may have decided on the entry while the job ran
+				update := bson.M{"$set": bson.M{"musicbrainz": info}}
+				_, err = musicCollection.UpdateOne(ctx, bson.M{"music_id": music.MusicID, "musicbrainz.status": undecided}, update)
+			}
+			progress.Step(music.MusicID, err)
+		}
+		return nil
+	})
+}
+
+// AdminEnrichMusicBrainz starts the enrichment job. The body is optional:
+// music_ids limits it to some entries, rematch revisits earlier results.
+func AdminEnrichMusicBrainz(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MusicBrainzEnrichRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+				return
+			}
+		}
+
+		job, err := startEnrichJob(c, req, client)
+		if err == jobs.ErrJobRunning {
+			c.JSON(http.StatusConflict, gin.H{"error": "A MusicBrainz job is already running"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrichment"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		recordAudit(c, ctx, "musicbrainz.enrich", "job", job.ID, bson.M{"music_ids": req.MusicIDs, "rematch": req.Rematch}, client)
+
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// AdminListMusicBrainzReview lists entries by MusicBrainz status, the
+// ambiguous ones with their candidates by default
+func AdminListMusicBrainzReview(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := c.DefaultQuery("status", models.MusicBrainzAmbiguous)
+		switch status {
+		case models.MusicBrainzMatched, models.MusicBrainzAmbiguous, models.MusicBrainzNotFound,
+			models.MusicBrainzConfirmed, models.MusicBrainzRejected:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		findOptions := options.Find().SetSort(bson.D{{Key: "title", Value: 1}})
+		cursor, err := musicCollection.Find(ctx, bson.M{"musicbrainz.status": status}, findOptions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch music"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var musics []models.Music
+		if err := cursor.All(ctx, &musics); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode music"})
+			return
+		}
+
+		response := make([]gin.H, 0, len(musics))
+		for _, music := range musics {
+			title, artist := musicBrainzQuery(music)
+			response = append(response, gin.H{
+				"music_id":    music.MusicID,
+				"title":       music.Title,
+				"query":       gin.H{"title": title, "artist": artist},
+				"musicbrainz": music.MusicBrainz,
+			})
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// AdminConfirmMusicBrainz sets the release of an entry by hand, whether it
+// is one of the candidates or not
+func AdminConfirmMusicBrainz(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MusicBrainzConfirm
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		musicId := c.Param("music_id")
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		count, err := musicCollection.CountDocuments(ctx, bson.M{"music_id": musicId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch music"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Music not found"})
+			return
+		}
+
+		release, err := getMusicBrainzClient().Release(ctx, strings.ToLower(req.ReleaseID))
+		if errors.Is(err, musicbrainz.ErrReleaseNotFound) || errors.Is(err, musicbrainz.ErrInvalidID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Release not found on MusicBrainz"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch release", "details": err.Error()})
+			return
+		}
+
+		info := release.Info(models.MusicBrainzConfirmed)
+		info.ConfirmedBy, _ = utils.GetUserIdFromContext(c)
+
+		_, err = musicCollection.UpdateOne(ctx, bson.M{"music_id": musicId}, bson.M{"$set": bson.M{"musicbrainz": info}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update music"})
+			return
+		}
+
+		recordAudit(c, ctx, "musicbrainz.confirm", "music", musicId, bson.M{"release_id": info.ReleaseID}, client)
+		refreshCatalogIndexes(client)
+
+		c.JSON(http.StatusOK, gin.H{"music_id": musicId, "musicbrainz": info})
+	}
+}
+
+// AdminRejectMusicBrainz drops an entry's match and keeps the job from matching it again
+func AdminRejectMusicBrainz(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		musicId := c.Param("music_id")
+		adminId, _ := utils.GetUserIdFromContext(c)
+
+		info := models.MusicBrainzInfo{Status: models.MusicBrainzRejected, UpdatedAt: time.Now(), ConfirmedBy: adminId}
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		result, err := musicCollection.UpdateOne(ctx, bson.M{"music_id": musicId}, bson.M{"$set": bson.M{"musicbrainz": info}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update music"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Music not found"})
+			return
+		}
+
+		recordAudit(c, ctx, "musicbrainz.reject", "music", musicId, nil, client)
+		refreshCatalogIndexes(client)
+
+		c.JSON(http.StatusOK, gin.H{"music_id": musicId, "musicbrainz": info})
+	}
+}
@@ -0,0 +1,532 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/access"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file is the curated playlists API. Anyone can browse the playlists their access allows, and a playlist is returned with its music entries in their saved order, rendered for the caller's audience. Admins create, edit and delete playlists and insert, remove and reorder their items. Items reference albums by music_id: deleting an album pulls it from every playlist, and entries that no longer exist are skipped when a playlist is read. */
+
+// findPlaylist loads one playlist by its playlist_id
+func findPlaylist(ctx context.Context, playlistId string, client *mongo.Client) (models.Playlist, error) {
+	var playlist models.Playlist
+
+	var playlistCollection *mongo.Collection = database.OpenCollection("playlists", client)
+
+	err := playlistCollection.FindOne(ctx, bson.M{"playlist_id": playlistId}).Decode(&playlist)
+	return playlist, err
+}
+
+// playlistOr404 loads the playlist from the URL or answers 404
+func playlistOr404(c *gin.Context, ctx context.Context, client *mongo.Client) (models.Playlist, bool) {
+	playlist, err := findPlaylist(ctx, c.Param("playlist_id"), client)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+		return playlist, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist"})
+		return playlist, false
+	}
+	return playlist, true
+}
+
+// playlistMusicIDs returns the music_ids of a playlist in order
+func playlistMusicIDs(playlist models.Playlist) []string {
+	ids := make([]string, 0, len(playlist.Items))
+	for _, item := range playlist.Items {
+		ids = append(ids, item.MusicID)
+	}
+	return ids
+}
+
+// checkPlaylistMusicIDs rejects duplicates and music_ids that are not in the catalog
+func checkPlaylistMusicIDs(ctx context.Context, musicIds []string, client *mongo.Client) error {
+	seen := make(map[string]bool, len(musicIds))
+	for _, musicId := range musicIds {
+		if seen[musicId] {
+			return fmt.Errorf("music_id %s is listed twice", musicId)
+		}
+		seen[musicId] = true
+	}
+
+	var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+	found, err := musicCollection.Distinct(ctx, "music_id", bson.M{"music_id": bson.M{"$in": musicIds}}).Raw()
+	if err != nil {
+		return err
+	}
+	values, err := found.Values()
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		delete(seen, value.StringValue())
+	}
+	for musicId := range seen {
+		return fmt.Errorf("music_id %s is not in the catalog", musicId)
+	}
+	return nil
+}
+
+// newPlaylistItems stamps music_ids as items added now
+func newPlaylistItems(musicIds []string) []models.PlaylistItem {
+	now := time.Now()
+	items := make([]models.PlaylistItem, 0, len(musicIds))
+	for _, musicId := range musicIds {
+		items = append(items, models.PlaylistItem{MusicID: musicId, AddedAt: now})
+	}
+	return items
+}
+
+// playlistView renders a playlist with its music entries in saved order for
+// an audience. Entries that allow rejects, or that were deleted, are skipped.
+func playlistView(ctx context.Context, playlist models.Playlist, audience string, allow func(models.Music) bool, client *mongo.Client) (gin.H, error) {
+	var musics []models.Music
+	if err := findAll(ctx, "musics", bson.M{"music_id": bson.M{"$in": playlistMusicIDs(playlist)}}, &musics, client); err != nil {
+		return nil, err
+	}
+
+	byId := make(map[string]models.Music, len(musics))
+	for _, music := range musics {
+		byId[music.MusicID] = music
+	}
+
+	entries := make([]gin.H, 0, len(playlist.Items))
+	for position, item := range playlist.Items {
+		music, ok := byId[item.MusicID]
+		if !ok || !allow(music) {
+			continue
+		}
+		entries = append(entries, gin.H{
+			"position": position,
+			"added_at": item.AddedAt,
+			"music":    models.NewMusicView(music, audience),
+		})
+	}
+
+	return gin.H{
+		"playlist_id": playlist.PlaylistID,
+		"title":       playlist.Title,
+		"description": playlist.Description,
+		"cover":       playlist.Cover,
+		"created_at":  playlist.CreatedAt,
+		"updated_at":  playlist.UpdatedAt,
+		"items":       entries,
+	}, nil
+}
+
+// playlistResponse renders the playlist for the caller, skipping the
+// entries they may not see
+func playlistResponse(c *gin.Context, ctx context.Context, playlist models.Playlist, client *mongo.Client) (gin.H, error) {
+	audience := callerAudience(c)
+	allow := func(music models.Music) bool { return canViewMusic(c, music) }
+
+	response, err := playlistView(ctx, playlist, audience, allow, client)
+	if err != nil {
+		return nil, err
+	}
+	if audience == models.AudienceAdmin {
+		response["access"] = playlist.Access
+	}
+	return response, nil
+}
+
+// respondWithPlaylist reloads the playlist and writes it in the public shape
+func respondWithPlaylist(c *gin.Context, ctx context.Context, status int, playlistId string, client *mongo.Client) {
+	playlist, err := findPlaylist(ctx, playlistId, client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated playlist"})
+		return
+	}
+
+	response, err := playlistResponse(c, ctx, playlist, client)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist entries"})
+		return
+	}
+	c.JSON(status, response)
+}
+
+// GetPlaylists lists playlists, most recently updated first
+func GetPlaylists(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var playlistCollection *mongo.Collection = database.OpenCollection("playlists", client)
+
+		findOptions := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+		cursor, err := playlistCollection.Find(ctx, access.Filter("access", callerAccess(c)), findOptions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlists"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var playlists []models.Playlist
+		if err := cursor.All(ctx, &playlists); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode playlists"})
+			return
+		}
+
+		response := make([]models.PlaylistSummary, 0, len(playlists))
+		for _, playlist := range playlists {
+			response = append(response, models.PlaylistSummary{
+				PlaylistID:  playlist.PlaylistID,
+				Title:       playlist.Title,
+				Description: playlist.Description,
+				Cover:       playlist.Cover,
+				ItemCount:   len(playlist.Items),
+				UpdatedAt:   playlist.UpdatedAt,
+			})
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// GetPlaylist returns a playlist with its music entries in saved order
+func GetPlaylist(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		playlist, ok := playlistOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		// Playlists the caller may not see look the same as missing ones
+		if !access.CanView(playlist.Access, callerAccess(c)) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+			return
+		}
+
+		response, err := playlistResponse(c, ctx, playlist, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist entries"})
+			return
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// AdminCreatePlaylist creates a playlist, optionally with its first items
+func AdminCreatePlaylist(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.PlaylistRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if len(req.MusicIDs) > 0 {
+			if err := checkPlaylistMusicIDs(ctx, req.MusicIDs, client); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		if req.Access != nil {
+			if status, err := checkAccessRule(ctx, req.Access, client); err != nil {
+				c.JSON(status, gin.H{"error": "Invalid access rule", "details": err.Error()})
+				return
+			}
+		}
+
+		adminId, _ := utils.GetUserIdFromContext(c)
+		now := time.Now()
+		playlist := models.Playlist{
+			PlaylistID:  bson.NewObjectID().Hex(),
+			Title:       req.Title,
+			Description: req.Description,
+			Cover:       req.Cover,
+			Items:       newPlaylistItems(req.MusicIDs),
+			Access:      req.Access,
+			CreatedBy:   adminId,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+
+		var playlistCollection *mongo.Collection = database.OpenCollection("playlists", client)
+
+		if _, err := playlistCollection.InsertOne(ctx, playlist); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create playlist"})
+			return
+		}
+
+		recordAudit(c, ctx, "playlist.create", "playlist", playlist.PlaylistID, bson.M{"title": playlist.Title}, client)
+
+		respondWithPlaylist(c, ctx, http.StatusCreated, playlist.PlaylistID, client)
+	}
+}
+
+// AdminUpdatePlaylist changes the title, description or cover
+func AdminUpdatePlaylist(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.PlaylistUpdate
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		set := bson.M{"updated_at": time.Now()}
+		if req.Title != nil {
+			set["title"] = *req.Title
+		}
+		if req.Description != nil {
+			set["description"] = *req.Description
+		}
+		if req.Cover != nil {
+			set["cover"] = *req.Cover
+		}
+		if len(set) == 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var playlistCollection *mongo.Collection = database.OpenCollection("playlists", client)
+
+		playlistId := c.Param("playlist_id")
+		result, err := playlistCollection.UpdateOne(ctx, bson.M{"playlist_id": playlistId}, bson.M{"$set": set})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update playlist"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Playlist not found"})
+			return
+		}
+
+		delete(set, "updated_at")
+		recordAudit(c, ctx, "playlist.update", "playlist", playlistId, set, client)
+
+		respondWithPlaylist(c, ctx, http.StatusOK, playlistId, client)
+	}
+}
+
+// AdminDeletePlaylist deletes a playlist. The albums in it are not touched.
+func AdminDeletePlaylist(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		playlist, ok := playlistOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		var playlistCollection *mongo.Collection = database.OpenCollection("playlists", client)
+
+		if _, err := playlistCollection.DeleteOne(ctx, bson.M{"playlist_id": playlist.PlaylistID}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete playlist"})
+			return
+		}
+
+		if err := deleteShareLinks(ctx, models.ShareTargetPlaylist, playlist.PlaylistID, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Playlist deleted but its share links could not be removed"})
+			return
+		}
+
+		recordAudit(c, ctx, "playlist.delete", "playlist", playlist.PlaylistID, bson.M{"title": playlist.Title, "items": len(playlist.Items)}, client)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Playlist deleted"})
+	}
+}
+
+// AdminAddPlaylistItems inserts albums at a position, or appends them
+func AdminAddPlaylistItems(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.PlaylistItemsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if err := checkPlaylistMusicIDs(ctx, req.MusicIDs, client); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		playlist, ok := playlistOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		each := bson.M{"$each": newPlaylistItems(req.MusicIDs)}
+		if req.Position != nil {
+			each["$position"] = *req.Position
+		}
+
+		var playlistCollection *mongo.Collection = database.OpenCollection("playlists", client)
+
+		// The filter keeps an album from being in the playlist twice
+		filter := bson.M{"playlist_id": playlist.PlaylistID, "items.music_id": bson.M{"$nin": req.MusicIDs}}
+		update := bson.M{"$push": bson.M{"items": each}, "$set": bson.M{"updated_at": time.Now()}}
+
+		result, err := playlistCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add playlist items"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Some of these albums are already in the playlist"})
+			return
+		}
+
+		recordAudit(c, ctx, "playlist.items.add", "playlist", playlist.PlaylistID, bson.M{"music_ids": req.MusicIDs}, client)
+
+		respondWithPlaylist(c, ctx, http.StatusOK, playlist.PlaylistID, client)
+	}
+}
+
+// AdminRemovePlaylistItem removes one album from a playlist
+func AdminRemovePlaylistItem(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		playlistId := c.Param("playlist_id")
+		musicId := c.Param("music_id")
+
+		var playlistCollection *mongo.Collection = database.OpenCollection("playlists", client)
+
+		filter := bson.M{"playlist_id": playlistId, "items.music_id": musicId}
+		update := bson.M{
+			"$pull": bson.M{"items": bson.M{"music_id": musicId}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		}
+
+		result, err := playlistCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove playlist item"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found in playlist"})
+			return
+		}
+
+		recordAudit(c, ctx, "playlist.items.remove", "playlist", playlistId, bson.M{"music_id": musicId}, client)
+
+		respondWithPlaylist(c, ctx, http.StatusOK, playlistId, client)
+	}
+}
+
+// AdminReorderPlaylist saves a new item order. The request must list every
+// item exactly once, and fails with 409 if the playlist changed meanwhile.
+func AdminReorderPlaylist(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.PlaylistOrder
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		playlist, ok := playlistOr404(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		current := make(map[string]models.PlaylistItem, len(playlist.Items))
+		for _, item := range playlist.Items {
+			current[item.MusicID] = item
+		}
+
+		if len(req.MusicIDs) != len(playlist.Items) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("music_ids must list all %d items of the playlist", len(playlist.Items))})
+			return
+		}
+
+		reordered := make([]models.PlaylistItem, 0, len(req.MusicIDs))
+		for _, musicId := range req.MusicIDs {
+			item, found := current[musicId]
+			if !found {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("music_id %s is not in the playlist or listed twice", musicId)})
+				return
+			}
+			delete(current, musicId)
+			reordered = append(reordered, item)
+		}
+
+		var playlistCollection *mongo.Collection = database.OpenCollection("playlists", client)
+
+		// Matching on updated_at keeps a concurrent change from being overwritten
+		filter := bson.M{"playlist_id": playlist.PlaylistID, "updated_at": playlist.UpdatedAt}
+		update := bson.M{"$set": bson.M{"items": reordered, "updated_at": time.Now()}}
+
+		result, err := playlistCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder playlist"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Playlist changed meanwhile, reload it and try again"})
+			return
+		}
+
+		recordAudit(c, ctx, "playlist.reorder", "playlist", playlist.PlaylistID, nil, client)
+
+		respondWithPlaylist(c, ctx, http.StatusOK, playlist.PlaylistID, client)
+	}
+}
+
+// removeFromPlaylists pulls a deleted album out of every playlist
+func removeFromPlaylists(ctx context.Context, musicId string, client *mongo.Client) (int64, error) {
+	var playlistCollection *mongo.Collection = database.OpenCollection("playlists", client)
+
+	filter := bson.M{"items.music_id": musicId}
+	update := bson.M{
+		"$pull": bson.M{"items": bson.M{"music_id": musicId}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	result, err := playlistCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
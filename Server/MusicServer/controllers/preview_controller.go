@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/youtube"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/* This file handles POST /addmusic/preview. An admin sends a YouTube URL or ID and gets back a draft music entry prefilled from the video's metadata, to correct and send to /addmusic. Nothing is stored. METADATA_PROVIDER=fake swaps the oEmbed lookup for offline sample data. */
+
+const (
+	metadataTimeout    = 5 * time.Second
+	metadataCacheTTL   = 6 * time.Hour
+	metadataCacheLimit = 500
+)
+
+var (
+	metadataProvider     youtube.MetadataProvider
+	metadataProviderOnce sync.Once
+)
+
+// getMetadataProvider builds the provider on first use, after main has loaded .env
+func getMetadataProvider() youtube.MetadataProvider {
+	metadataProviderOnce.Do(func() {
+		var provider youtube.MetadataProvider
+		if strings.EqualFold(os.Getenv("METADATA_PROVIDER"), "fake") {
+			provider = youtube.FakeProvider{}
+		} else {
+			provider = youtube.NewOEmbedProvider(os.Getenv("YOUTUBE_OEMBED_URL"), metadataTimeout)
+		}
+		metadataProvider = youtube.NewCachedProvider(provider, metadataCacheTTL, metadataCacheLimit)
+	})
+	return metadataProvider
+}
+
+// previewResponse is the draft music entry prefilled from the video
+func previewResponse(video youtube.Video, metadata youtube.Metadata) gin.H {
+	draft := models.Music{
+		Title:        metadata.Title,
+		AlbumImg:     metadata.ThumbnailURL,
+		YouTubeID:    video.ID,
+		YouTubeStart: video.Start,
+		YouTubeEnd:   video.End,
+		Genre:        []models.Genre{},
+		Ranking:      models.Ranking{RankingValue: models.NotRankedValue, RankingName: models.NotRankedName},
+	}
+
+	return gin.H{
+		"draft":    draft,
+		"metadata": metadata,
+		// The music_id and genres cannot come from YouTube
+		"missing": []string{"music_id", "genre"},
+	}
+}
+
+// AddMusicPreview returns a draft music entry for a YouTube URL or ID
+func AddMusicPreview(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			YouTubeID string `json:"youtube_id" validate:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		video, err := youtube.Parse(req.YouTubeID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid youtube_id", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		metadata, err := getMetadataProvider().Lookup(ctx, video.ID)
+		switch {
+		case errors.Is(err, youtube.ErrVideoNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found on YouTube"})
+			return
+		case errors.Is(err, youtube.ErrVideoNotEmbeddable):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Video is private or cannot be embedded"})
+			return
+		case err != nil:
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch video metadata", "details": err.Error()})
+			return
+		}
+
+		response := previewResponse(video, metadata)
+
+		// Warn when the video is already in the catalog
+		var existing models.Music
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		err = musicCollection.FindOne(ctx, bson.M{"youtube_id": video.ID}).Decode(&existing)
+		if err == nil {
+			response["existing_music_id"] = existing.MusicID
+		} else if err != mongo.ErrNoDocuments {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the catalog"})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/youtube"
+)
+
+/* This file checks POST /addmusic/preview against the fake metadata provider. The answers that need no catalog lookup go through the handler, the draft itself is checked on previewResponse. */
+
+// failingProvider fails every lookup with err
+type failingProvider struct {
+	err error
+}
+
+func (p failingProvider) Lookup(ctx context.Context, videoID string) (youtube.Metadata, error) {
+	return youtube.Metadata{}, p.err
+}
+
+// useMetadataProvider makes the preview use provider instead of the configured one
+func useMetadataProvider(provider youtube.MetadataProvider) {
+	metadataProviderOnce.Do(func() {})
+	metadataProvider = youtube.NewCachedProvider(provider, time.Hour, 10)
+}
+
+func TestAddMusicPreviewErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		provider youtube.MetadataProvider
+		body     string
+		status   int
+	}{
+		{"no body", youtube.FakeProvider{}, ``, http.StatusBadRequest},
+		{"no video", youtube.FakeProvider{}, `{}`, http.StatusBadRequest},
+		{"invalid video", youtube.FakeProvider{}, `{"youtube_id":"https://example.com/watch?v=x"}`, http.StatusBadRequest},
+		{"missing video", youtube.FakeProvider{Missing: map[string]bool{"dQw4w9WgXcQ": true}}, `{"youtube_id":"https://youtu.be/dQw4w9WgXcQ"}`, http.StatusNotFound},
+		{"private video", failingProvider{youtube.ErrVideoNotEmbeddable}, `{"youtube_id":"dQw4w9WgXcQ"}`, http.StatusUnprocessableEntity},
+		{"provider down", failingProvider{errors.New("connection refused")}, `{"youtube_id":"dQw4w9WgXcQ"}`, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMetadataProvider(tt.provider)
+
+			router := gin.New()
+			router.POST("/addmusic/preview", AddMusicPreview(nil))
+
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/addmusic/preview", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.status {
+				t.Errorf("status = %d, want %d (%s)", recorder.Code, tt.status, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestPreviewResponse(t *testing.T) {
+	video, err := youtube.Parse("https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=1m5s")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	metadata, err := youtube.FakeProvider{}.Lookup(context.Background(), video.ID)
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+
+	response := previewResponse(video, metadata)
+
+	draft := response["draft"].(models.Music)
+	if draft.Title != "Sample Album dQw4w9WgXcQ" || draft.AlbumImg != metadata.ThumbnailURL {
+		t.Errorf("draft = %+v", draft)
+	}
+	if draft.YouTubeID != "dQw4w9WgXcQ" || draft.YouTubeStart != 65 {
+		t.Errorf("draft video = %s from %d, want dQw4w9WgXcQ from 65", draft.YouTubeID, draft.YouTubeStart)
+	}
+	if draft.Ranking.RankingValue != models.NotRankedValue || len(draft.Genre) != 0 {
+		t.Errorf("draft ranking %+v, genres %v", draft.Ranking, draft.Genre)
+	}
+	if missing := response["missing"].([]string); len(missing) != 2 {
+		t.Errorf("missing = %v", missing)
+	}
+}
@@ -0,0 +1,356 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/* This file is the data-subject tooling (GDPR). It builds a downloadable ZIP archive of everything stored about a user and deletes an account together with its dependent records. Users can do this for themselves through /me, admins can do it on a user's behalf and those actions are written to the audit trail. */
+
+// exportProfile is the profile as it appears in the export, without secrets
+type exportProfile struct {
+	models.UserResponse
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"update_at"`
+}
+
+// findAll decodes every document matching the filter into out
+func findAll(ctx context.Context, collectionName string, filter bson.M, out interface{}, client *mongo.Client) error {
+	var collection *mongo.Collection = database.OpenCollection(collectionName, client)
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	return cursor.All(ctx, out)
+}
+
+// collectUserData gathers every record we hold about the user, keyed by file name
+func collectUserData(ctx context.Context, user models.User, client *mongo.Client) (map[string]interface{}, error) {
+	var sessions []models.Session
+	if err := findAll(ctx, "sessions", bson.M{"user_id": user.UserID}, &sessions, client); err != nil {
+		return nil, err
+	}
+
+	var activity []models.AuditLog
+	activityFilter := bson.M{"$or": []bson.M{{"actor_id": user.UserID}, {"target_id": user.UserID}}}
+	if err := findAll(ctx, "audit_logs", activityFilter, &activity, client); err != nil {
+		return nil, err
+	}
+
+	var challenges []models.MFAChallenge
+	if err := findAll(ctx, "mfa_challenges", bson.M{"user_id": user.UserID}, &challenges, client); err != nil {
+		return nil, err
+	}
+
+	var invites []models.Invite
+	if err := findAll(ctx, "invites", bson.M{"used_by.user_id": user.UserID}, &invites, client); err != nil {
+		return nil, err
+	}
+
+	inviteUsage := make([]gin.H, 0, len(invites))
+	for _, invite := range invites {
+		for _, use := range invite.UsedBy {
+			if use.UserID == user.UserID {
+				inviteUsage = append(inviteUsage, gin.H{"code": invite.Code, "role": invite.Role, "groups": invite.Groups, "used_at": use.UsedAt})
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"invites.json": inviteUsage,
+		"profile.json": exportProfile{
+			UserResponse: toUserResponse(user),
+			CreatedAt:    user.CreatedAt,
+			UpdatedAt:    user.UpdatedAt,
+		},
+		"favorites.json": gin.H{"favorite_genres": user.FavoriteGenres},
+		// Every session is one login, so this doubles as the login history
+		"sessions.json":       sessions,
+		"mfa_challenges.json": challenges,
+		"activity.json":       activity,
+	}, nil
+}
+
+// writeUserExport sends the user's data as a ZIP attachment. The archive is
+// built in memory first, so a failure can still be answered with JSON.
+func writeUserExport(c *gin.Context, ctx context.Context, user models.User, client *mongo.Client) error {
+	files, err := collectUserData(ctx, user, client)
+	if err != nil {
+		return err
+	}
+
+	files["manifest.json"] = gin.H{
+		"user_id":      user.UserID,
+		"generated_at": time.Now().UTC(),
+		"files":        []string{"profile.json", "favorites.json", "sessions.json", "mfa_challenges.json", "activity.json", "invites.json"},
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for name, content := range files {
+		file, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(content); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	fileName := fmt.Sprintf("tunepeep-export-%s-%s.zip", user.UserID, time.Now().UTC().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, "application/zip", buffer.Bytes())
+	return nil
+}
+
+// deleteUserData removes the user document and the records that depend on it
+// in one transaction, so a failure leaves the account as it was.
+// Audit entries are kept (they only hold the opaque user_id) as a record of the deletion itself.
+func deleteUserData(ctx context.Context, userId string, client *mongo.Client) (bson.M, error) {
+	var deleted bson.M
+
+	err := database.RunTransaction(ctx, client, func(ctx context.Context) error {
+		var sessionCollection *mongo.Collection = database.OpenCollection("sessions", client)
+		sessions, err := sessionCollection.DeleteMany(ctx, bson.M{"user_id": userId})
+		if err != nil {
+			return err
+		}
+
+		var challengeCollection *mongo.Collection = database.OpenCollection("mfa_challenges", client)
+		challenges, err := challengeCollection.DeleteMany(ctx, bson.M{"user_id": userId})
+		if err != nil {
+			return err
+		}
+
+		// Invite usage history stores the email, drop the user's entries
+		var inviteCollection *mongo.Collection = database.OpenCollection("invites", client)
+		invites, err := inviteCollection.UpdateMany(ctx, bson.M{"used_by.user_id": userId}, bson.M{"$pull": bson.M{"used_by": bson.M{"user_id": userId}}})
+		if err != nil {
+			return err
+		}
+
+		// Access rules that name the user
+		shared := int64(0)
+		for _, collectionName := range []string{"musics", "playlists"} {
+			var collection *mongo.Collection = database.OpenCollection(collectionName, client)
+			result, err := collection.UpdateMany(ctx, bson.M{"access.users": userId}, bson.M{"$pull": bson.M{"access.users": userId}})
+			if err != nil {
+				return err
+			}
+			shared += result.ModifiedCount
+		}
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+		users, err := userCollection.DeleteOne(ctx, bson.M{"user_id": userId})
+		if err != nil {
+			return err
+		}
+
+		deleted = bson.M{
+			"users":          users.DeletedCount,
+			"sessions":       sessions.DeletedCount,
+			"mfa_challenges": challenges.DeletedCount,
+			"invite_usage":   invites.ModifiedCount,
+			"access_rules":   shared,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// isLastAdmin stops the only remaining admin account from being removed.
+// Disabled admins cannot log in, so they do not count.
+func isLastAdmin(ctx context.Context, user models.User, client *mongo.Client) (bool, error) {
+	if user.Role != "ADMIN" {
+		return false, nil
+	}
+
+	var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+	filter := bson.M{"role": "ADMIN", "disabled": bson.M{"$ne": true}, "user_id": bson.M{"$ne": user.UserID}}
+	count, err := userCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// clearAuthCookies removes the token cookies from the browser
+func clearAuthCookies(c *gin.Context) {
+	for _, name := range []string{"access_token", "refresh_token"} {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteNoneMode,
+		})
+	}
+}
+
+// ExportMe downloads the caller's own data
+func ExportMe(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := currentUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		if err := writeUserExport(c, ctx, user, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export user data"})
+			return
+		}
+	}
+}
+
+// DeleteMe deletes the caller's account after re-checking the password (and MFA code if enabled)
+func DeleteMe(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Password     string `json:"password" validate:"required"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := currentUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+			return
+		}
+
+		if user.MFAEnabled {
+			if err := verifySecondFactor(ctx, user, req.Code, req.RecoveryCode, client); err != nil {
+				c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		lastAdmin, err := isLastAdmin(ctx, user, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check admin accounts"})
+			return
+		}
+		if lastAdmin {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete the last admin account"})
+			return
+		}
+
+		deleted, err := deleteUserData(ctx, user.UserID, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+			return
+		}
+
+		clearAuthCookies(c)
+		c.JSON(http.StatusOK, gin.H{"message": "Account deleted", "deleted": deleted})
+	}
+}
+
+// AdminExportUser downloads a user's data on their behalf
+func AdminExportUser(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		adminId, _ := utils.GetUserIdFromContext(c)
+
+		user, err := findUserByID(ctx, c.Param("user_id"), client)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		// Record the access before any data leaves the server
+		if err := utils.WriteAuditLog(ctx, adminId, "user.export", "user", user.UserID, nil, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write audit log"})
+			return
+		}
+
+		if err := writeUserExport(c, ctx, user, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export user data"})
+			return
+		}
+	}
+}
+
+// AdminDeleteUser deletes a user's account on their behalf. Admins delete
+// their own account through /me, which asks for the password.
+func AdminDeleteUser(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, adminId, ok := targetUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		lastAdmin, err := isLastAdmin(ctx, user, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check admin accounts"})
+			return
+		}
+		if lastAdmin {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete the last admin account"})
+			return
+		}
+
+		deleted, err := deleteUserData(ctx, user.UserID, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+			return
+		}
+
+		if err := utils.WriteAuditLog(ctx, adminId, "user.delete", "user", user.UserID, bson.M{"deleted": deleted}, client); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Account deleted but audit log could not be written"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Account deleted", "deleted": deleted})
+	}
+}
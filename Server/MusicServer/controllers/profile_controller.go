@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/* This file is the self-service profile API (/me). Users can read their profile, change their name and favorite genres, and change their password, which logs out every other session. */
+
+// resolveGenres checks the requested genres against the genres collection
+// and returns the stored versions, so names always match the catalog
+func resolveGenres(ctx context.Context, requested []models.Genre, client *mongo.Client) ([]models.Genre, error) {
+	ids := make([]int, 0, len(requested))
+	for _, genre := range requested {
+		ids = append(ids, genre.GenreID)
+	}
+
+	var genreCollection *mongo.Collection = database.OpenCollection("genres", client)
+
+	cursor, err := genreCollection.Find(ctx, bson.M{"genre_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var known []models.Genre
+	if err := cursor.All(ctx, &known); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]models.Genre, len(known))
+	for _, genre := range known {
+		byID[genre.GenreID] = genre
+	}
+
+	resolved := make([]models.Genre, 0, len(requested))
+	seen := make(map[int]bool, len(requested))
+	var unknown []string
+	for _, genre := range requested {
+		stored, ok := byID[genre.GenreID]
+		if !ok || (genre.GenreName != "" && !strings.EqualFold(genre.GenreName, stored.GenreName)) {
+			unknown = append(unknown, fmt.Sprintf("%d:%s", genre.GenreID, genre.GenreName))
+			continue
+		}
+		if !seen[stored.GenreID] {
+			seen[stored.GenreID] = true
+			resolved = append(resolved, stored)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown genres: %s", strings.Join(unknown, ", "))
+	}
+	return resolved, nil
+}
+
+// GetMe returns the caller's profile
+func GetMe(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := currentUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, toUserResponse(user))
+	}
+}
+
+// UpdateMe changes the caller's name and/or favorite genres
+func UpdateMe(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ProfileUpdate
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := currentUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		set := bson.M{}
+		if req.FirstName != nil {
+			set["first_name"] = *req.FirstName
+		}
+		if req.LastName != nil {
+			set["last_name"] = *req.LastName
+		}
+		if req.FavoriteGenres != nil {
+			genres, err := resolveGenres(ctx, req.FavoriteGenres, client)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid favorite_genres", "details": err.Error()})
+				return
+			}
+			set["favorite_genres"] = genres
+		}
+
+		if len(set) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No fields provided for update"})
+			return
+		}
+		set["update_at"] = time.Now()
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		if _, err := userCollection.UpdateOne(ctx, bson.M{"user_id": user.UserID}, bson.M{"$set": set}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+			return
+		}
+
+		updated, err := findUserByID(ctx, user.UserID, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated profile"})
+			return
+		}
+
+		c.JSON(http.StatusOK, toUserResponse(updated))
+	}
+}
+
+// ChangePassword checks the current password, stores the new one and
+// revokes every session except the one making the request
+func ChangePassword(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.PasswordChange
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		user, ok := currentUser(c, ctx, client)
+		if !ok {
+			return
+		}
+
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+
+		hashedPassword, err := HashPassword(req.NewPassword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to hash password"})
+			return
+		}
+
+		var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+		update := bson.M{"$set": bson.M{"password": hashedPassword, "update_at": time.Now()}}
+		if _, err := userCollection.UpdateOne(ctx, bson.M{"user_id": user.UserID}, update); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+			return
+		}
+
+		sessionId, _ := utils.GetSessionIdFromContext(c)
+		revoked, err := utils.RevokeUserSessions(ctx, user.UserID, sessionId, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed but other sessions could not be revoked"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Password changed", "revoked_sessions": revoked})
+	}
+}
@@ -0,0 +1,576 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/jobs"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/* This file is the admin API for the ranking taxonomy. Tiers are ordered by ranking_value (1 is the best) and their names are what the LLM picks from when it ranks a review. Albums embed a copy of their tier, so every rename, reorder or delete runs as a job that re-maps the albums in the same transaction as the taxonomy change, and a full re-rank job can run every review through the LLM again. The 999 Not_Ranked tier is a fixed sentinel that cannot be changed. */
+
+// Only one ranking job runs at a time, and the taxonomy is locked while it does
+const rankingJobKind = "rankings"
+
+// rankingValueParam reads :ranking_value from the URL and rejects the sentinel
+func rankingValueParam(c *gin.Context) (int, bool) {
+	value, err := strconv.Atoi(c.Param("ranking_value"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ranking_value must be a number"})
+		return 0, false
+	}
+	if value == models.NotRankedValue {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The Not_Ranked tier cannot be changed"})
+		return 0, false
+	}
+	return value, true
+}
+
+// rankingTiers returns the tiers without the Not_Ranked sentinel, best first
+func rankingTiers(ctx context.Context, client *mongo.Client) ([]models.Ranking, error) {
+	rankings, err := GetRankings(client, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tiers := make([]models.Ranking, 0, len(rankings))
+	for _, ranking := range rankings {
+		if ranking.RankingValue != models.NotRankedValue {
+			tiers = append(tiers, ranking)
+		}
+	}
+	return tiers, nil
+}
+
+// findRanking returns the tier with the value from the list
+func findRanking(tiers []models.Ranking, value int) (models.Ranking, bool) {
+	for _, tier := range tiers {
+		if tier.RankingValue == value {
+			return tier, true
+		}
+	}
+	return models.Ranking{}, false
+}
+
+// checkRankingName makes sure a tier name is one word the LLM can answer with
+// and is not used by another tier
+func checkRankingName(ctx context.Context, name string, exceptValue int, client *mongo.Client) (int, error) {
+	if strings.ContainsAny(name, " ,\t\n") {
+		return http.StatusBadRequest, errors.New("Ranking names must be a single word without commas")
+	}
+	if strings.EqualFold(name, models.NotRankedName) {
+		return http.StatusBadRequest, errors.New("Not_Ranked is reserved")
+	}
+
+	var rankingCollection *mongo.Collection = database.OpenCollection("rankings", client)
+
+	filter := bson.M{
+		"ranking_name":  bson.Regex{Pattern: "^" + regexp.QuoteMeta(name) + "$", Options: "i"},
+		"ranking_value": bson.M{"$ne": exceptValue},
+	}
+	count, err := rankingCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Failed to check existing rankings")
+	}
+	if count > 0 {
+		return http.StatusConflict, errors.New("Another ranking already has this name")
+	}
+	return 0, nil
+}
+
+// rankingUsage counts the albums in each tier
+func rankingUsage(ctx context.Context, client *mongo.Client) (map[int]int64, error) {
+	var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$ranking.ranking_value", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := musicCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Value int   `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	usage := make(map[int]int64, len(groups))
+	for _, group := range groups {
+		usage[group.Value] = group.Count
+	}
+	return usage, nil
+}
+
+// rankingLocked answers 409 while a ranking job is still updating albums
+func rankingLocked(c *gin.Context) bool {
+	if jobs.Running(rankingJobKind) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A ranking job is still running, try again when it has finished"})
+		return true
+	}
+	return false
+}
+
+// startRemapJob changes the taxonomy with write and moves every album from an
+// old tier value to its new tier in the same transaction, so albums never keep
+// a tier that no longer exists. Once the transaction starts the job can no
+// longer be canceled; the returned error is jobs.ErrJobRunning or the error of
+// the transaction.
+func startRemapJob(c *gin.Context, mapping map[int]models.Ranking, write func(ctx context.Context) error, client *mongo.Client) (jobs.Job, error) {
+	adminId, _ := utils.GetUserIdFromContext(c)
+
+	written := make(chan error, 1)
+	job, err := jobs.Start(rankingJobKind, "remap", adminId, func(ctx context.Context, progress *jobs.Progress) error {
+		err := remapRankings(ctx, progress, mapping, write, client)
+		written <- err
+		return err
+	})
+	if err != nil {
+		return job, err
+	}
+	return job, <-written
+}
+
+// remapRankings runs the taxonomy write and the album remap as one transaction
+func remapRankings(ctx context.Context, progress *jobs.Progress, mapping map[int]models.Ranking, write func(ctx context.Context) error, client *mongo.Client) error {
+	progress.Commit()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	oldValues := make([]int, 0, len(mapping))
+	for value := range mapping {
+		oldValues = append(oldValues, value)
+	}
+
+	var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+	var moved int
+	err := database.RunTransaction(ctx, client, func(ctx context.Context) error {
+		if err := write(ctx); err != nil {
+			return err
+		}
+
+		filter := bson.M{"ranking.ranking_value": bson.M{"$in": oldValues}}
+		result, err := musicCollection.UpdateMany(ctx, filter, remapPipeline(mapping))
+		if err != nil {
+			return err
+		}
+		moved = int(result.ModifiedCount)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	refreshCatalogIndexes(client)
+
+	progress.SetTotal(moved)
+	progress.Advance(moved)
+	return nil
+}
+
+// remapPipeline sets each album's tier from the mapping in a single pass, so
+// a reorder that swaps two values does not move an album twice
+func remapPipeline(mapping map[int]models.Ranking) mongo.Pipeline {
+	branches := bson.A{}
+	for old, ranking := range mapping {
+		branches = append(branches, bson.M{
+			"case": bson.M{"$eq": bson.A{"$ranking.ranking_value", old}},
+			"then": bson.M{"$literal": ranking},
+		})
+	}
+
+	remapped := bson.M{"$switch": bson.M{"branches": branches, "default": "$ranking"}}
+	return mongo.Pipeline{{{Key: "$set", Value: bson.M{"ranking": remapped}}}}
+}
+
+// remapJobError answers a remap job that did not start or whose taxonomy write failed
+func remapJobError(c *gin.Context, err error, message string) {
+	if errors.Is(err, jobs.ErrJobRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A ranking job is still running, try again when it has finished"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+}
+
+// startRerankJob runs every admin review through the LLM again
+func startRerankJob(c *gin.Context, client *mongo.Client) (jobs.Job, error) {
+	adminId, _ := utils.GetUserIdFromContext(c)
+
+	return jobs.Start(rankingJobKind, "rerank", adminId, func(ctx context.Context, progress *jobs.Progress) error {
+		defer refreshCatalogIndexes(client)
+
+		var musics []models.Music
+		if err := findAll(ctx, "musics", bson.M{}, &musics, client); err != nil {
+			return err
+		}
+		progress.SetTotal(len(musics))
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		for _, music := range musics {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			// Albums without a review belong in Not_Ranked
+			ranking := models.Ranking{RankingValue: models.NotRankedValue, RankingName: models.NotRankedName}
+			var err error
+			if strings.TrimSpace(music.AdminReview) != "" {
+				itemCtx, cancel := context.WithTimeout(ctx, 100*time.Second)
+				ranking.RankingName, ranking.RankingValue, err = GetReviewRanking(music.AdminReview, client, itemCtx)
+				cancel()
+			}
+
+			if err == nil && ranking != music.Ranking {
+				update := bson.M{"$set": bson.M{"ranking": ranking}}
+				_, err = musicCollection.UpdateOne(ctx, bson.M{"music_id": music.MusicID}, update)
+			}
+			progress.Step(music.MusicID, err)
+		}
+		return nil
+	})
+}
+
+// AdminListRankings lists the tiers, best first, with how many albums are in each
+func AdminListRankings(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		rankings, err := GetRankings(client, ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching rankings"})
+			return
+		}
+
+		usage, err := rankingUsage(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error counting ranking usage"})
+			return
+		}
+
+		response := make([]gin.H, 0, len(rankings))
+		for _, ranking := range rankings {
+			response = append(response, gin.H{
+				"ranking_value": ranking.RankingValue,
+				"ranking_name":  ranking.RankingName,
+				"sentinel":      ranking.RankingValue == models.NotRankedValue,
+				"musics":        usage[ranking.RankingValue],
+			})
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// AdminCreateRanking adds a tier below the current lowest one. Use the
+// reorder endpoint to move it up.
+func AdminCreateRanking(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.RankingRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		if rankingLocked(c) {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		if status, err := checkRankingName(ctx, req.RankingName, 0, client); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		tiers, err := rankingTiers(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching rankings"})
+			return
+		}
+
+		ranking := models.Ranking{RankingValue: 1, RankingName: req.RankingName}
+		if len(tiers) > 0 {
+			ranking.RankingValue = tiers[len(tiers)-1].RankingValue + 1
+		}
+		if ranking.RankingValue >= models.NotRankedValue {
+			c.JSON(http.StatusConflict, gin.H{"error": "No ranking values left, reorder the tiers to compact them"})
+			return
+		}
+
+		var rankingCollection *mongo.Collection = database.OpenCollection("rankings", client)
+
+		if _, err := rankingCollection.InsertOne(ctx, ranking); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ranking"})
+			return
+		}
+
+		recordAudit(c, ctx, "ranking.create", "ranking", strconv.Itoa(ranking.RankingValue), bson.M{"ranking_name": ranking.RankingName}, client)
+
+		c.JSON(http.StatusCreated, ranking)
+	}
+}
+
+// AdminRenameRanking renames a tier and re-maps the albums in it
+func AdminRenameRanking(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := rankingValueParam(c)
+		if !ok {
+			return
+		}
+
+		var req models.RankingRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		if rankingLocked(c) {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		tiers, err := rankingTiers(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching rankings"})
+			return
+		}
+
+		old, found := findRanking(tiers, value)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ranking not found"})
+			return
+		}
+
+		if status, err := checkRankingName(ctx, req.RankingName, value, client); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		var rankingCollection *mongo.Collection = database.OpenCollection("rankings", client)
+
+		rename := func(ctx context.Context) error {
+			update := bson.M{"$set": bson.M{"ranking_name": req.RankingName}}
+			_, err := rankingCollection.UpdateOne(ctx, bson.M{"ranking_value": value}, update)
+			return err
+		}
+
+		ranking := models.Ranking{RankingValue: value, RankingName: req.RankingName}
+		job, err := startRemapJob(c, map[int]models.Ranking{value: ranking}, rename, client)
+		if err != nil {
+			remapJobError(c, err, "Failed to rename ranking")
+			return
+		}
+
+		details := bson.M{"from": old.RankingName, "to": req.RankingName, "job_id": job.ID}
+		recordAudit(c, ctx, "ranking.rename", "ranking", strconv.Itoa(value), details, client)
+
+		c.JSON(http.StatusAccepted, gin.H{"ranking": ranking, "job": job})
+	}
+}
+
+// AdminReorderRankings gives the tiers new values 1..n in the order sent and
+// re-maps the albums. The order must list every tier except Not_Ranked.
+func AdminReorderRankings(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.RankingOrder
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		if rankingLocked(c) {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		tiers, err := rankingTiers(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching rankings"})
+			return
+		}
+
+		if len(req.Order) != len(tiers) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("order must list all %d ranking tiers except Not_Ranked", len(tiers))})
+			return
+		}
+
+		mapping := map[int]models.Ranking{}
+		reordered := make([]models.Ranking, 0, len(tiers))
+		for i, value := range req.Order {
+			tier, found := findRanking(tiers, value)
+			if !found {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ranking_value %d is not a ranking tier", value)})
+				return
+			}
+			if _, seen := mapping[value]; seen {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ranking_value %d is listed twice", value)})
+				return
+			}
+
+			moved := models.Ranking{RankingValue: i + 1, RankingName: tier.RankingName}
+			mapping[value] = moved
+			reordered = append(reordered, moved)
+		}
+
+		for value, ranking := range mapping {
+			if value == ranking.RankingValue {
+				delete(mapping, value)
+			}
+		}
+
+		// Names are unique, so tiers are matched by name while their values move
+		reorder := func(ctx context.Context) error {
+			var rankingCollection *mongo.Collection = database.OpenCollection("rankings", client)
+
+			for _, tier := range reordered {
+				update := bson.M{"$set": bson.M{"ranking_value": tier.RankingValue}}
+				if _, err := rankingCollection.UpdateOne(ctx, bson.M{"ranking_name": tier.RankingName}, update); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		// When no value moves the order is already as sent
+		response := gin.H{"rankings": reordered}
+		details := bson.M{"order": req.Order}
+		if len(mapping) > 0 {
+			job, err := startRemapJob(c, mapping, reorder, client)
+			if err != nil {
+				remapJobError(c, err, "Failed to reorder rankings")
+				return
+			}
+			response["job"] = job
+			details["job_id"] = job.ID
+		}
+
+		recordAudit(c, ctx, "ranking.reorder", "ranking", "", details, client)
+
+		c.JSON(http.StatusAccepted, response)
+	}
+}
+
+// AdminDeleteRanking deletes a tier. Its albums move to ?reassign_to=<ranking_value>,
+// or to Not_Ranked when no tier is given.
+func AdminDeleteRanking(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := rankingValueParam(c)
+		if !ok {
+			return
+		}
+
+		if rankingLocked(c) {
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		tiers, err := rankingTiers(ctx, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching rankings"})
+			return
+		}
+
+		deleted, found := findRanking(tiers, value)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ranking not found"})
+			return
+		}
+
+		target := models.Ranking{RankingValue: models.NotRankedValue, RankingName: models.NotRankedName}
+		if reassignTo := c.Query("reassign_to"); reassignTo != "" {
+			targetValue, err := strconv.Atoi(reassignTo)
+			if err != nil || targetValue == value {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "reassign_to must be another ranking_value"})
+				return
+			}
+			if targetValue != models.NotRankedValue {
+				if target, found = findRanking(tiers, targetValue); !found {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Target ranking not found"})
+					return
+				}
+			}
+		}
+
+		var rankingCollection *mongo.Collection = database.OpenCollection("rankings", client)
+
+		remove := func(ctx context.Context) error {
+			_, err := rankingCollection.DeleteOne(ctx, bson.M{"ranking_value": value})
+			return err
+		}
+
+		job, err := startRemapJob(c, map[int]models.Ranking{value: target}, remove, client)
+		if err != nil {
+			remapJobError(c, err, "Failed to delete ranking")
+			return
+		}
+
+		details := bson.M{"ranking_name": deleted.RankingName, "reassigned_to": target.RankingName, "job_id": job.ID}
+		recordAudit(c, ctx, "ranking.delete", "ranking", strconv.Itoa(value), details, client)
+
+		c.JSON(http.StatusAccepted, gin.H{"deleted": deleted, "reassigned_to": target, "job": job})
+	}
+}
+
+// AdminRerankMusics re-ranks every album from its review with the current taxonomy
+func AdminRerankMusics(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := startRerankJob(c, client)
+		if err == jobs.ErrJobRunning {
+			c.JSON(http.StatusConflict, gin.H{"error": "A ranking job is already running"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start re-ranking"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		recordAudit(c, ctx, "ranking.rerank", "job", job.ID, nil, client)
+
+		c.JSON(http.StatusAccepted, job)
+	}
+}
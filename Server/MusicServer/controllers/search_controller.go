@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/search"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/* This file provides catalog search and typeahead suggestions. The in-memory indexes from the search package are rebuilt from the musics and genres collections at startup and again after every change to the catalog. GET /search queries the full-text index and GET /suggest the prefix index. Anonymous visitors only search titles and genres so reviews never leak through snippets, and suggestions are filtered by what the caller may see. The indexes are rebuilt in the background, so hits are checked against the stored entries before they are returned. */
+
+const (
+	defaultSearchLimit  = 20
+	maxSearchLimit      = 50
+	defaultSuggestLimit = 8
+	maxSuggestLimit     = 20
+)
+
+// Titles and artists weigh most, then genres, then the admin's review text
+var catalogIndex = search.NewIndex(map[string]float64{
+	"title":        3,
+	"artist":       3,
+	"genre":        2,
+	"admin_review": 1,
+})
+
+// Typeahead over titles, artists and genre names
+var suggestIndex = search.NewPrefixIndex()
+
+// catalogSnapshot maps music_id to the entry the indexes were built from
+var (
+	catalogMu       sync.RWMutex
+	catalogSnapshot = map[string]models.Music{}
+)
+
+// rebuildMu keeps background rebuilds from overtaking each other
+var rebuildMu sync.Mutex
+
+// musicArtist is the imported artist, or the one read from the title
+func musicArtist(music models.Music) string {
+	if music.Artist != "" {
+		return music.Artist
+	}
+	return artistFromTitle(music.Title)
+}
+
+// artistFromTitle reads the artist from titles like "Deftones - White Pony"
+func artistFromTitle(title string) string {
+	parts := strings.SplitN(title, " - ", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return strings.TrimSpace(parts[0])
+}
+
+// catalogSuggestions lists the typeahead entries for the catalog
+func catalogSuggestions(musics []models.Music, genres []models.Genre) []search.Suggestion {
+	suggestions := make([]search.Suggestion, 0, 2*len(musics)+len(genres))
+	for _, music := range musics {
+		suggestions = append(suggestions, search.Suggestion{Text: music.Title, Kind: "title", Refs: []string{music.MusicID}})
+		if artist := musicArtist(music); artist != "" {
+			suggestions = append(suggestions, search.Suggestion{Text: artist, Kind: "artist", Refs: []string{music.MusicID}})
+		}
+	}
+	for _, genre := range genres {
+		suggestions = append(suggestions, search.Suggestion{Text: genre.GenreName, Kind: "genre"})
+	}
+	return suggestions
+}
+
+// musicSearchDocument turns a music entry into a searchable document
+func musicSearchDocument(music models.Music) search.Document {
+	genres := make([]string, 0, len(music.Genre))
+	for _, genre := range music.Genre {
+		genres = append(genres, genre.GenreName)
+	}
+
+	return search.Document{
+		ID: music.MusicID,
+		Fields: map[string]string{
+			"title":        music.Title,
+			"artist":       music.Artist,
+			"genre":        strings.Join(genres, ", "),
+			"admin_review": strings.TrimSpace(music.AdminReview),
+		},
+		Payload: music,
+	}
+}
+
+// RebuildCatalogIndexes reloads the catalog and rebuilds the in-memory indexes
+func RebuildCatalogIndexes(client *mongo.Client) error {
+	rebuildMu.Lock()
+	defer rebuildMu.Unlock()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var musics []models.Music
+	if err := findAll(ctx, "musics", bson.M{}, &musics, client); err != nil {
+		return err
+	}
+
+	var genres []models.Genre
+	if err := findAll(ctx, "genres", bson.M{}, &genres, client); err != nil {
+		return err
+	}
+
+	docs := make([]search.Document, 0, len(musics))
+	snapshot := make(map[string]models.Music, len(musics))
+	for _, music := range musics {
+		docs = append(docs, musicSearchDocument(music))
+		snapshot[music.MusicID] = music
+	}
+	catalogIndex.Rebuild(docs)
+	suggestIndex.Rebuild(catalogSuggestions(musics, genres))
+
+	catalogMu.Lock()
+	catalogSnapshot = snapshot
+	catalogMu.Unlock()
+
+	return nil
+}
+
+// refreshCatalogIndexes rebuilds the indexes in the background after a catalog change
+func refreshCatalogIndexes(client *mongo.Client) {
+	go func() {
+		if err := RebuildCatalogIndexes(client); err != nil {
+			log.Println("Warning: failed to rebuild catalog indexes:", err)
+		}
+	}()
+}
+
+// currentMusics loads the stored version of indexed entries by music_id. The
+// indexes are rebuilt in the background, so until then a hit may carry an
+// old access rule or youtube_id, or point at a deleted entry.
+func currentMusics(ctx context.Context, musicIds []string, client *mongo.Client) (map[string]models.Music, error) {
+	current := make(map[string]models.Music, len(musicIds))
+	if len(musicIds) == 0 {
+		return current, nil
+	}
+
+	var musics []models.Music
+	if err := findAll(ctx, "musics", bson.M{"music_id": bson.M{"$in": musicIds}}, &musics, client); err != nil {
+		return nil, err
+	}
+	for _, music := range musics {
+		current[music.MusicID] = music
+	}
+	return current, nil
+}
+
+// SearchMusics is GET /search?q=
+func SearchMusics(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := strings.TrimSpace(c.Query("q"))
+		if q == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSearchLimit)))
+		if err != nil || limit < 1 {
+			limit = defaultSearchLimit
+		}
+		if limit > maxSearchLimit {
+			limit = maxSearchLimit
+		}
+
+		audience := callerAudience(c)
+
+		opts := search.Options{
+			Limit: limit,
+			Allow: func(doc search.Document) bool {
+				return canViewMusic(c, doc.Payload.(models.Music))
+			},
+		}
+		if audience == models.AudienceAnonymous {
+			opts.Fields = []string{"title", "artist", "genre"}
+		}
+
+		hits := catalogIndex.Search(q, opts)
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		musicIds := make([]string, 0, len(hits))
+		for _, hit := range hits {
+			musicIds = append(musicIds, hit.ID)
+		}
+		current, err := currentMusics(ctx, musicIds, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching the catalog"})
+			return
+		}
+
+		results := make([]gin.H, 0, len(hits))
+		for _, hit := range hits {
+			// The stored entry decides, it may have changed since the last rebuild
+			music, ok := current[hit.ID]
+			if !ok || !canViewMusic(c, music) {
+				continue
+			}
+			results = append(results, gin.H{
+				"music":      models.NewMusicView(music, audience),
+				"score":      hit.Score,
+				"highlights": hit.Highlights,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"query":   q,
+			"total":   len(results),
+			"results": results,
+		})
+	}
+}
+
+// SuggestMusics is GET /suggest?prefix=, typeahead over titles, artists and genres
+func SuggestMusics(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		prefix := c.Query("prefix")
+		if strings.TrimSpace(prefix) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "prefix is required"})
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSuggestLimit)))
+		if err != nil || limit < 1 {
+			limit = defaultSuggestLimit
+		}
+		if limit > maxSuggestLimit {
+			limit = maxSuggestLimit
+		}
+
+		catalogMu.RLock()
+		snapshot := catalogSnapshot
+		catalogMu.RUnlock()
+
+		// firstVisible is the first album behind an entry the caller can see
+		firstVisible := func(suggestion search.Suggestion, musics map[string]models.Music) (string, bool) {
+			for _, musicId := range suggestion.Refs {
+				if music, ok := musics[musicId]; ok && canViewMusic(c, music) {
+					return musicId, true
+				}
+			}
+			return "", false
+		}
+
+		// An entry is shown when the caller can see at least one album behind it
+		allow := func(suggestion search.Suggestion) bool {
+			if len(suggestion.Refs) == 0 {
+				return true
+			}
+			_, ok := firstVisible(suggestion, snapshot)
+			return ok
+		}
+
+		suggestions := suggestIndex.Suggest(prefix, limit, allow)
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var musicIds []string
+		for _, suggestion := range suggestions {
+			musicIds = append(musicIds, suggestion.Refs...)
+		}
+		current, err := currentMusics(ctx, musicIds, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching suggestions"})
+			return
+		}
+
+		results := make([]gin.H, 0, len(suggestions))
+		for _, suggestion := range suggestions {
+			// The snapshot may be behind, the stored entries decide what is shown
+			if _, ok := firstVisible(suggestion, current); len(suggestion.Refs) > 0 && !ok {
+				continue
+			}
+
+			result := gin.H{"text": suggestion.Text, "kind": suggestion.Kind}
+			if suggestion.Kind == "title" {
+				// A title shared by several albums must not point at a hidden one
+				result["music_id"], _ = firstVisible(suggestion, current)
+			}
+			results = append(results, result)
+		}
+
+		c.JSON(http.StatusOK, results)
+	}
+}
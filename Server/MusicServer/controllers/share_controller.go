@@ -0,0 +1,328 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file handles share links. Admins create a signed link to one music entry or playlist with an expiry and an optional use limit, and can revoke it. GET /shared/:token needs no account: it checks the signature, counts the access and returns only the linked entry, or the linked playlist and its entries, in the playable view. */
+
+const defaultShareLifetime = 7 * 24 * time.Hour
+
+var errShareLinkUnusable = errors.New("share link is expired, used up or revoked")
+
+// shareStatus describes a share link for listings
+func shareStatus(link models.ShareLink, now time.Time) string {
+	switch {
+	case link.RevokedAt != nil:
+		return "revoked"
+	case !link.ExpiresAt.After(now):
+		return "expired"
+	case link.MaxUses > 0 && link.Uses >= link.MaxUses:
+		return "used_up"
+	}
+	return "active"
+}
+
+// shareLinkResponse adds the token, URL path and status to a share link
+func shareLinkResponse(link models.ShareLink) (gin.H, error) {
+	token, err := utils.GenerateShareToken(link.ShareID, link.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"share":  link,
+		"token":  token,
+		"path":   "/shared/" + token,
+		"status": shareStatus(link, time.Now()),
+	}, nil
+}
+
+// shareTargetExists checks that the music entry or playlist exists
+func shareTargetExists(ctx context.Context, targetType, targetId string, client *mongo.Client) (bool, error) {
+	collectionName, idField := "musics", "music_id"
+	if targetType == models.ShareTargetPlaylist {
+		collectionName, idField = "playlists", "playlist_id"
+	}
+
+	var collection *mongo.Collection = database.OpenCollection(collectionName, client)
+
+	count, err := collection.CountDocuments(ctx, bson.M{idField: targetId})
+	return count > 0, err
+}
+
+// consumeShareLink atomically counts one access of a usable link
+func consumeShareLink(ctx context.Context, shareId string, client *mongo.Client) (models.ShareLink, error) {
+	var shareCollection *mongo.Collection = database.OpenCollection("share_links", client)
+
+	now := time.Now()
+	filter := bson.M{
+		"share_id":   shareId,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+		"$or": []bson.M{
+			{"max_uses": 0},
+			{"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}},
+		},
+	}
+	update := bson.M{
+		"$inc": bson.M{"uses": 1},
+		"$set": bson.M{"last_access_at": now},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var link models.ShareLink
+	err := shareCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&link)
+	if err == mongo.ErrNoDocuments {
+		return link, errShareLinkUnusable
+	}
+	return link, err
+}
+
+// deleteShareLinks removes the links to a music entry or playlist that was deleted
+func deleteShareLinks(ctx context.Context, targetType, targetId string, client *mongo.Client) error {
+	var shareCollection *mongo.Collection = database.OpenCollection("share_links", client)
+
+	_, err := shareCollection.DeleteMany(ctx, bson.M{"target_type": targetType, "target_id": targetId})
+	return err
+}
+
+// GetSharedResource is GET /shared/:token, the public side of a share link
+func GetSharedResource(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Every request is a counted access, nothing may be served from a cache
+		c.Header("Cache-Control", "no-store")
+
+		shareId, err := utils.ValidateShareToken(c.Param("token"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid or expired share link"})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		link, err := consumeShareLink(ctx, shareId, client)
+		if err == errShareLinkUnusable {
+			c.JSON(http.StatusGone, gin.H{"error": "This share link is no longer valid"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open share link"})
+			return
+		}
+
+		response := gin.H{"target_type": link.TargetType, "expires_at": link.ExpiresAt}
+		if link.MaxUses > 0 {
+			response["uses_left"] = link.MaxUses - link.Uses
+		}
+
+		if link.TargetType == models.ShareTargetPlaylist {
+			playlist, err := findPlaylist(ctx, link.TargetID, client)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Shared playlist not found"})
+				return
+			}
+
+			// The link grants the whole playlist, whatever its entries' own rules
+			allowAll := func(models.Music) bool { return true }
+			view, err := playlistView(ctx, playlist, models.AudienceMember, allowAll, client)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch playlist entries"})
+				return
+			}
+			response["playlist"] = view
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		var music models.Music
+
+		var musicCollection *mongo.Collection = database.OpenCollection("musics", client)
+
+		if err := musicCollection.FindOne(ctx, bson.M{"music_id": link.TargetID}).Decode(&music); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Shared album not found"})
+			return
+		}
+		response["music"] = models.NewMusicPlayable(music)
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// AdminCreateShareLink creates a signed link to a music entry or playlist
+func AdminCreateShareLink(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ShareLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "details": err.Error()})
+			return
+		}
+
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		exists, err := shareTargetExists(ctx, req.TargetType, req.TargetID, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check share target"})
+			return
+		}
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share target not found"})
+			return
+		}
+
+		adminId, _ := utils.GetUserIdFromContext(c)
+
+		// JWT expiry has second precision, keep the stored expiry the same
+		now := time.Now().Truncate(time.Second)
+		link := models.ShareLink{
+			ShareID:    bson.NewObjectID().Hex(),
+			TargetType: req.TargetType,
+			TargetID:   req.TargetID,
+			MaxUses:    req.MaxUses,
+			ExpiresAt:  now.Add(defaultShareLifetime),
+			CreatedBy:  adminId,
+			CreatedAt:  now,
+		}
+		if req.ExpiresInHours > 0 {
+			link.ExpiresAt = now.Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		}
+
+		var shareCollection *mongo.Collection = database.OpenCollection("share_links", client)
+
+		if _, err := shareCollection.InsertOne(ctx, link); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+			return
+		}
+
+		details := bson.M{"target_type": link.TargetType, "target_id": link.TargetID, "max_uses": link.MaxUses, "expires_at": link.ExpiresAt}
+		recordAudit(c, ctx, "share.create", "share_link", link.ShareID, details, client)
+
+		response, err := shareLinkResponse(link)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign share link"})
+			return
+		}
+		c.JSON(http.StatusCreated, response)
+	}
+}
+
+// AdminListShareLinks lists share links, newest first, with optional
+// target_type, target_id and status filters
+func AdminListShareLinks(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if targetType := c.Query("target_type"); targetType != "" {
+			filter["target_type"] = targetType
+		}
+		if targetId := c.Query("target_id"); targetId != "" {
+			filter["target_id"] = targetId
+		}
+
+		var shareCollection *mongo.Collection = database.OpenCollection("share_links", client)
+
+		findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+		cursor, err := shareCollection.Find(ctx, filter, findOptions)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch share links"})
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var links []models.ShareLink
+		if err := cursor.All(ctx, &links); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode share links"})
+			return
+		}
+
+		status := c.Query("status")
+		now := time.Now()
+
+		response := make([]gin.H, 0, len(links))
+		for _, link := range links {
+			if status != "" && status != shareStatus(link, now) {
+				continue
+			}
+			item, err := shareLinkResponse(link)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign share link"})
+				return
+			}
+			response = append(response, item)
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// AdminGetShareLink returns one share link with its access count and last access
+func AdminGetShareLink(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		var link models.ShareLink
+
+		var shareCollection *mongo.Collection = database.OpenCollection("share_links", client)
+
+		if err := shareCollection.FindOne(ctx, bson.M{"share_id": c.Param("share_id")}).Decode(&link); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+			return
+		}
+
+		response, err := shareLinkResponse(link)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign share link"})
+			return
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// AdminRevokeShareLink stops a share link from working. It stays listed for reference.
+func AdminRevokeShareLink(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+		defer cancel()
+
+		shareId := c.Param("share_id")
+
+		var shareCollection *mongo.Collection = database.OpenCollection("share_links", client)
+
+		filter := bson.M{"share_id": shareId, "revoked_at": bson.M{"$exists": false}}
+		result, err := shareCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found or already revoked"})
+			return
+		}
+
+		recordAudit(c, ctx, "share.revoke", "share_link", shareId, nil, client)
+
+		c.JSON(http.StatusOK, gin.H{"message": "Share link revoked"})
+	}
+}
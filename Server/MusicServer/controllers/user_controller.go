@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -17,7 +18,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-/* This file is for authentication and user management. It handles user registration with password hashing, login with token generation, logout, and refresh token management. The controllers interact with MongoDB for HTTP-only cookies for token storage. */
+/* This file is for authentication and user management. It handles user registration with password hashing, two-step login with token generation, logout, and refresh token management. The controllers interact with MongoDB for HTTP-only cookies for token storage. */
 
 func HashPassword(password string)(string,error){
 	HashPassword, err := bcrypt.GenerateFromPassword([]byte(password),bcrypt.DefaultCost)
@@ -35,6 +36,10 @@ func RegisterUser(client *mongo.Client) gin.HandlerFunc{
 					return
 				}
 
+				// Accounts always start as USER, only an invite can grant another role
+				user.Role = "USER"
+				user.Groups = []string{}
+
 				validate := validator.New()
 
 				if err := validate.Struct(user); err !=nil{
@@ -52,6 +57,21 @@ func RegisterUser(client *mongo.Client) gin.HandlerFunc{
 				var ctx, cancel = context.WithTimeout(c, 100*time.Second)
 				defer cancel()
 
+				mode, err := GetRegistrationMode(ctx, client)
+
+				if err != nil{
+					c.JSON(http.StatusInternalServerError, gin.H{"error":"Failed to read registration mode"})
+					return
+				}
+				if mode == models.RegistrationClosed {
+					c.JSON(http.StatusForbidden, gin.H{"error":"Registration is closed"})
+					return
+				}
+				if mode == models.RegistrationInviteOnly && user.InviteCode == "" {
+					c.JSON(http.StatusForbidden, gin.H{"error":"Invite code required"})
+					return
+				}
+
 				var userCollection *mongo.Collection = database.OpenCollection("users", client)
 
 				count, err := userCollection.CountDocuments(ctx,bson.M{"email":user.Email})
@@ -68,10 +88,29 @@ func RegisterUser(client *mongo.Client) gin.HandlerFunc{
 				user.CreatedAt = time.Now()
 				user.UpdatedAt = time.Now()
 				user.Password = hashedPassword
+				// MFA can only be turned on through enrollment
+				user.MFAEnabled = false
+				user.Disabled = false
+
+				// An invite code (required in invite-only mode) sets role and access
+				var invite *models.Invite
+				if user.InviteCode != "" {
+					invite, err = consumeInvite(ctx, user.InviteCode, user.UserID, user.Email, client)
+					if err != nil{
+						c.JSON(http.StatusForbidden, gin.H{"error":"Invalid or expired invite code"})
+						return
+					}
+					user.Role = invite.Role
+					user.Groups = invite.Groups
+				}
 
 				result, err := userCollection.InsertOne(ctx, user)
 
 				if err != nil {
+					// Give the invite use back, the account was never created
+					if invite != nil {
+						releaseInvite(ctx, invite.Code, user.UserID, client)
+					}
 											c.JSON(http.StatusInternalServerError, gin.H{"error":"Failed to create user"})
 					return
 				}
@@ -108,51 +147,102 @@ func LoginUser(client *mongo.Client) gin.HandlerFunc{
 				return
 			}
 
-			token, refreshToken, err := utils.GenerateAllTokens(foundUser.Email, foundUser.FirstName, foundUser.LastName, foundUser.Role, foundUser.UserID)
-			
-			if err != nil{
-				c.JSON(http.StatusInternalServerError, gin.H{"error":"Failed to generate tokens"})
+			if foundUser.Disabled {
+				c.JSON(http.StatusForbidden, gin.H{"error":"Account disabled"})
 				return
 			}
 
-			err = utils.UpdateAllTokens(foundUser.UserID, token, refreshToken, client)
+			// Two-step login: a correct password only earns a short-lived
+			// challenge token when a second factor is required
+			if foundUser.MFAEnabled {
+				mfaToken, err := utils.GenerateMFAChallengeToken(c, foundUser.UserID, utils.MFAPurposeVerify, client)
+				if err != nil{
+					c.JSON(http.StatusInternalServerError, gin.H{"error":"Failed to generate MFA challenge"})
+					return
+				}
+				c.JSON(http.StatusAccepted, gin.H{"mfa_required": true, "mfa_token": mfaToken})
+				return
+			}
 
+			// Admin accounts must enroll in MFA before they get any tokens
+			if foundUser.Role == "ADMIN" {
+				mfaToken, err := utils.GenerateMFAChallengeToken(c, foundUser.UserID, utils.MFAPurposeEnroll, client)
+				if err != nil{
+					c.JSON(http.StatusInternalServerError, gin.H{"error":"Failed to generate MFA challenge"})
+					return
+				}
+				c.JSON(http.StatusAccepted, gin.H{"mfa_enrollment_required": true, "mfa_token": mfaToken})
+				return
+			}
+
+			response, err := issueTokens(c, foundUser, client)
 			if err != nil{
-				c.JSON(http.StatusInternalServerError, gin.H{"error":"Failed to update tokens"})
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
 
+			c.JSON(http.StatusOK, response)
+	}
+}
 
-			// HTTP-only cookies
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:  "access_token",
-			Value: token,
-			Path:  "/",
-			MaxAge:   86400,
-			Secure:  true,
-			HttpOnly: true,
-			SameSite: http.SameSiteNoneMode,
-		})
+// issueTokens generates and stores new tokens for the user, sets them as
+// HTTP-only cookies and returns the login response body
+func issueTokens(c *gin.Context, foundUser models.User, client *mongo.Client) (models.UserResponse, error) {
+	sessionId, err := utils.CreateSession(c, foundUser.UserID, client)
 
-		// HTTP-only cookies
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:  "refresh_token",
-			Value: refreshToken,
-			Path:  "/",
-			MaxAge:   604800,
-			Secure: true,
-			HttpOnly: true,
-			SameSite: http.SameSiteNoneMode,
-		})
-	
-			c.JSON(http.StatusOK, models.UserResponse{
-				UserId: foundUser.UserID,
-				FirstName: foundUser.FirstName,
-				LastName: foundUser.LastName,
-				Email: foundUser.Email,
-				Role: foundUser.Role,
-				FavoriteGenres: foundUser.FavoriteGenres,
-			})
+	if err != nil{
+		return models.UserResponse{}, errors.New("Failed to create session")
+	}
+
+	token, refreshToken, err := utils.GenerateAllTokens(foundUser.Email, foundUser.FirstName, foundUser.LastName, foundUser.Role, foundUser.UserID, sessionId)
+
+	if err != nil{
+		return models.UserResponse{}, errors.New("Failed to generate tokens")
+	}
+
+	err = utils.UpdateAllTokens(foundUser.UserID, token, refreshToken, client)
+
+	if err != nil{
+		return models.UserResponse{}, errors.New("Failed to update tokens")
+	}
+
+	// HTTP-only cookies
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:  "access_token",
+		Value: token,
+		Path:  "/",
+		MaxAge:   86400,
+		Secure:  true,
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+	})
+
+	// HTTP-only cookies
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:  "refresh_token",
+		Value: refreshToken,
+		Path:  "/",
+		MaxAge:   604800,
+		Secure: true,
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+	})
+
+	return toUserResponse(foundUser), nil
+}
+
+// toUserResponse converts the stored user into its public API shape
+func toUserResponse(user models.User) models.UserResponse {
+	return models.UserResponse{
+		UserId: user.UserID,
+		FirstName: user.FirstName,
+		LastName: user.LastName,
+		Email: user.Email,
+		Role: user.Role,
+		FavoriteGenres: user.FavoriteGenres,
+		MFAEnabled: user.MFAEnabled,
+		Disabled: user.Disabled,
+		Groups: user.Groups,
 	}
 }
 
@@ -171,6 +261,18 @@ func LogoutHandler(client *mongo.Client) gin.HandlerFunc {
 
 		fmt.Println("User ID from Logout request:", UserLogout.UserId)
 
+		// Revoke the session behind the refresh token so its tokens stop working
+		if refreshToken, cookieErr := c.Cookie("refresh_token"); cookieErr == nil {
+			if claims, claimErr := utils.ValidateRefreshToken(refreshToken); claimErr == nil {
+				var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+				defer cancel()
+				if revokeErr := utils.RevokeSession(ctx, claims.SessionId, client); revokeErr != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Error logging out"})
+					return
+				}
+			}
+		}
+
 		err = utils.UpdateAllTokens(UserLogout.UserId, "", "", client) // Clear tokens in the database
 
 		if err != nil {
@@ -232,7 +334,39 @@ func RefreshTokenHandler(client *mongo.Client) gin.HandlerFunc {
 			return
 		}
 
-		newToken, newRefreshToken, _ := utils.GenerateAllTokens(user.Email, user.FirstName, user.LastName, user.Role, user.UserID)
+		if user.Disabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+			return
+		}
+
+		// A refresh only works while its session is still active
+		active, err := utils.ExtendSession(ctx, claim.SessionId, user.UserID, client)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verifying session"})
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired, please log in again"})
+			return
+		}
+
+		// An admin without MFA (e.g. promoted while logged in) must enroll
+		// first, so the session ends here and the enrollment challenge is returned
+		if user.Role == "ADMIN" && !user.MFAEnabled {
+			if err := utils.RevokeSession(ctx, claim.SessionId, client); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking session"})
+				return
+			}
+			mfaToken, err := utils.GenerateMFAChallengeToken(c, user.UserID, utils.MFAPurposeEnroll, client)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate MFA challenge"})
+				return
+			}
+			c.JSON(http.StatusForbidden, gin.H{"error": "MFA enrollment required", "mfa_enrollment_required": true, "mfa_token": mfaToken})
+			return
+		}
+
+		newToken, newRefreshToken, _ := utils.GenerateAllTokens(user.Email, user.FirstName, user.LastName, user.Role, user.UserID, claim.SessionId)
 		err = utils.UpdateAllTokens(user.UserID, newToken, newRefreshToken, client)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating tokens"})
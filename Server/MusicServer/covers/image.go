@@ -0,0 +1,133 @@
+package covers
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"net/http"
+
+	// Register the decoders for image.Decode
+	_ "image/gif"
+	_ "image/png"
+)
+
+/*
+This file checks and resizes cover images using only the standard library. Uploads are sniffed rather than trusted by extension, their dimensions are read before decoding so a tiny file cannot expand into a huge bitmap, and thumbnails are area-averaged so downscaled 2K artwork stays smooth.
+*/
+
+const (
+	MaxBytes     = 10 << 20 // largest accepted file
+	MaxDimension = 6000     // largest accepted width or height
+	jpegQuality  = 85
+)
+
+var (
+	ErrTooLarge        = errors.New("image is larger than 10 MB")
+	ErrUnsupportedType = errors.New("image must be a PNG, JPEG or GIF")
+	ErrTooManyPixels   = errors.New("image is wider or taller than 6000 pixels")
+	ErrCorrupt         = errors.New("image could not be decoded")
+)
+
+// Accepted content types and the extension originals are stored with
+var contentTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+}
+
+// Check sniffs the content type of data and checks its size and dimensions.
+// It returns the extension the original is stored with.
+func Check(data []byte) (string, error) {
+	if len(data) > MaxBytes {
+		return "", ErrTooLarge
+	}
+
+	ext, ok := contentTypes[http.DetectContentType(data)]
+	if !ok {
+		return "", ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrCorrupt
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return "", ErrCorrupt
+	}
+	if config.Width > MaxDimension || config.Height > MaxDimension {
+		return "", ErrTooManyPixels
+	}
+	return ext, nil
+}
+
+// Decode checks data and decodes it
+func Decode(data []byte) (image.Image, error) {
+	if _, err := Check(data); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return img, nil
+}
+
+// Resize scales img down to fit in a width x width box, keeping the aspect
+// ratio. Smaller images are returned unchanged.
+func Resize(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= width && srcH <= width {
+		return img
+	}
+
+	dstW, dstH := width, srcH*width/srcW
+	if srcH > srcW {
+		dstW, dstH = srcW*width/srcH, width
+	}
+	dstW, dstH = max(dstW, 1), max(dstH, 1)
+
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			// Average every source pixel that falls in this destination pixel
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(src.Pix[offset])
+					g += int(src.Pix[offset+1])
+					b += int(src.Pix[offset+2])
+					a += int(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG flattens img onto white, since JPEG has no transparency
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
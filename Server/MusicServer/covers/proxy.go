@@ -0,0 +1,332 @@
+package covers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+/*
+This file is a caching proxy for external cover URLs. An image is fetched once, from allowlisted hosts only, and kept on disk as the same JPEG thumbnails an upload gets. The cache has a size limit and evicts the least recently used images. Connections to private, shared (100.64.0.0/10), loopback and link-local addresses are refused even if an allowlisted name resolves to one, and failed fetches are remembered for a while so a dead link is not retried on every request.
+*/
+
+const (
+	proxyTimeout    = 10 * time.Second
+	proxyFailureTTL = 10 * time.Minute
+	maxRedirects    = 3
+)
+
+var (
+	ErrHostNotAllowed = errors.New("image host is not allowed")
+	ErrInvalidURL     = errors.New("image URL must be an absolute http or https URL")
+	ErrUpstream       = errors.New("image could not be fetched")
+)
+
+type proxyEntry struct {
+	size     int64
+	lastUsed time.Time
+}
+
+// Proxy fetches and caches external cover images
+type Proxy struct {
+	dir      string
+	hosts    map[string]bool
+	maxBytes int64
+	client   *http.Client
+
+	mu       sync.Mutex
+	entries  map[string]*proxyEntry
+	total    int64
+	failures map[string]time.Time
+	fetching map[string]*sync.WaitGroup
+}
+
+// NewProxy caches up to maxBytes of thumbnails under dir. hosts are the
+// allowed host names; "*.example.com" allows every subdomain of example.com.
+func NewProxy(dir string, hosts []string, maxBytes int64) *Proxy {
+	p := &Proxy{
+		dir:      dir,
+		hosts:    make(map[string]bool),
+		maxBytes: maxBytes,
+		entries:  make(map[string]*proxyEntry),
+		failures: make(map[string]time.Time),
+		fetching: make(map[string]*sync.WaitGroup),
+	}
+	for _, host := range hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			p.hosts[host] = true
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: proxyTimeout, Control: refusePrivateAddresses}
+	p.client = &http.Client{
+		Timeout:   proxyTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, Proxy: nil},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return p.checkURL(req.URL)
+		},
+	}
+
+	p.load()
+	return p
+}
+
+// sharedAddressSpace is 100.64.0.0/10 (RFC 6598), used by carrier-grade NAT
+// and some cloud networks for internal addresses; IsPrivate does not cover it
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// refusePrivateAddresses runs on the resolved address, so a public name
+// pointing at an internal service is caught too
+func refusePrivateAddresses(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip) ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return ErrHostNotAllowed
+	}
+	return nil
+}
+
+// Allowed reports whether a URL may be fetched
+func (p *Proxy) Allowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && p.checkURL(u) == nil
+}
+
+func (p *Proxy) checkURL(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if p.hosts[host] {
+		return nil
+	}
+	for parent := host; strings.Contains(parent, "."); {
+		parent = parent[strings.Index(parent, ".")+1:]
+		if p.hosts["*."+parent] {
+			return nil
+		}
+	}
+	return ErrHostNotAllowed
+}
+
+// File returns a cached thumbnail of rawURL, fetching the image first when
+// it is not cached yet
+func (p *Proxy) File(ctx context.Context, rawURL, variant string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+	if err := p.checkURL(u); err != nil {
+		return "", err
+	}
+
+	width := 0
+	for _, size := range Sizes {
+		if size.Name == variant {
+			width = size.Width
+		}
+	}
+	if width == 0 {
+		return "", ErrNotFound
+	}
+
+	sum := sha256.Sum256([]byte(u.String()))
+	key := hex.EncodeToString(sum[:])
+	path := filepath.Join(p.dir, key, variant+".jpg")
+
+	for {
+		p.mu.Lock()
+		if entry, ok := p.entries[key]; ok {
+			entry.lastUsed = time.Now()
+			p.mu.Unlock()
+
+			// Keep the last use on disk for the index rebuilt after a restart
+			os.Chtimes(path, entry.lastUsed, entry.lastUsed)
+			return path, nil
+		}
+		if until, ok := p.failures[key]; ok && time.Now().Before(until) {
+			p.mu.Unlock()
+			return "", ErrUpstream
+		}
+
+		// Only one request fetches a given image, the others wait for it
+		if wait, ok := p.fetching[key]; ok {
+			p.mu.Unlock()
+			wait.Wait()
+			continue
+		}
+		wait := &sync.WaitGroup{}
+		wait.Add(1)
+		p.fetching[key] = wait
+		p.mu.Unlock()
+
+		size, err := p.fetch(ctx, u.String(), key)
+
+		p.mu.Lock()
+		delete(p.fetching, key)
+		if err != nil {
+			p.forgetFailures()
+			p.failures[key] = time.Now().Add(proxyFailureTTL)
+		} else {
+			p.entries[key] = &proxyEntry{size: size, lastUsed: time.Now()}
+			p.total += size
+			p.evict(key)
+		}
+		p.mu.Unlock()
+		wait.Done()
+
+		if err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+}
+
+// fetch downloads an image and writes its thumbnails, returning their total size
+func (p *Proxy) fetch(ctx context.Context, rawURL, key string) (int64, error) {
+	// The fetch outlives a canceled request, other callers may be waiting on it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), proxyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, ErrInvalidURL
+	}
+	req.Header.Set("Accept", "image/png,image/jpeg,image/gif")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUpstream, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%w: status %d", ErrUpstream, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxBytes+1))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUpstream, err)
+	}
+
+	img, err := Decode(data)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUpstream, err)
+	}
+
+	dir := filepath.Join(p.dir, key)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, size := range Sizes {
+		thumbnail, err := EncodeJPEG(Resize(img, size.Width))
+		if err != nil {
+			return 0, err
+		}
+		if err := writeFile(filepath.Join(dir, size.Name+".jpg"), thumbnail); err != nil {
+			return 0, err
+		}
+		total += int64(len(thumbnail))
+	}
+	return total, nil
+}
+
+// forgetFailures drops failures that are no longer remembered. The caller holds p.mu.
+func (p *Proxy) forgetFailures() {
+	now := time.Now()
+	for key, until := range p.failures {
+		if !now.Before(until) {
+			delete(p.failures, key)
+		}
+	}
+}
+
+// evict removes least recently used images until the cache fits, keeping
+// the one just added. The caller holds p.mu.
+func (p *Proxy) evict(keep string) {
+	if p.total <= p.maxBytes {
+		return
+	}
+
+	keys := make([]string, 0, len(p.entries))
+	for key := range p.entries {
+		if key != keep {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return p.entries[keys[i]].lastUsed.Before(p.entries[keys[j]].lastUsed)
+	})
+
+	for _, key := range keys {
+		if p.total <= p.maxBytes {
+			return
+		}
+		if err := os.RemoveAll(filepath.Join(p.dir, key)); err != nil {
+			continue
+		}
+		p.total -= p.entries[key].size
+		delete(p.entries, key)
+	}
+}
+
+// load rebuilds the index from the cache directory after a restart, using
+// file modification times as the last use
+func (p *Proxy) load() {
+	dirs, err := os.ReadDir(p.dir)
+	if err != nil {
+		return
+	}
+
+	for _, dir := range dirs {
+		if !dir.IsDir() || !ValidHash(dir.Name()) {
+			continue
+		}
+
+		entry := &proxyEntry{}
+		complete := true
+		for _, size := range Sizes {
+			info, err := os.Stat(filepath.Join(p.dir, dir.Name(), size.Name+".jpg"))
+			if err != nil {
+				complete = false
+				break
+			}
+			entry.size += info.Size()
+			if info.ModTime().After(entry.lastUsed) {
+				entry.lastUsed = info.ModTime()
+			}
+		}
+
+		// Half written by a crash, fetch it again when asked
+		if !complete {
+			os.RemoveAll(filepath.Join(p.dir, dir.Name()))
+			continue
+		}
+		p.entries[dir.Name()] = entry
+		p.total += entry.size
+	}
+	p.evict("")
+}
@@ -0,0 +1,156 @@
+package covers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+/*
+This file keeps uploaded covers on local disk. Every cover lives in a directory named after the SHA-256 of the original file, holding the original and one JPEG per thumbnail size. Because the name is the content hash, a URL never changes meaning and can be cached forever, and uploading the same image twice stores it once.
+*/
+
+// Size is one thumbnail width
+type Size struct {
+	Name  string
+	Width int
+}
+
+// Thumbnail sizes generated for every upload, smallest first
+var Sizes = []Size{
+	{Name: "small", Width: 160},
+	{Name: "medium", Width: 320},
+	{Name: "large", Width: 640},
+}
+
+// Original is the variant name of the uploaded file itself
+const Original = "original"
+
+var ErrNotFound = errors.New("cover not found")
+
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ValidHash reports whether hash looks like a cover's content hash
+func ValidHash(hash string) bool {
+	return hashPattern.MatchString(hash)
+}
+
+// Variants lists the names a cover can be served as
+func Variants() []string {
+	names := []string{Original}
+	for _, size := range Sizes {
+		names = append(names, size.Name)
+	}
+	return names
+}
+
+// Paths returns the URL path of every variant of a cover
+func Paths(hash string) map[string]string {
+	paths := map[string]string{Original: "/covers/" + hash + "/" + Original}
+	for _, size := range Sizes {
+		paths[size.Name] = "/covers/" + hash + "/" + size.Name
+	}
+	return paths
+}
+
+// Store saves covers under a directory
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Save checks an uploaded image, stores it with its thumbnails and returns
+// its content hash
+func (s *Store) Save(data []byte) (string, error) {
+	ext, err := Check(data)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	dir := filepath.Join(s.dir, hash)
+
+	// Same content, already stored
+	if _, err := s.File(hash, Original); err == nil {
+		return hash, nil
+	}
+
+	img, err := Decode(data)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// Thumbnails first, so a stored original always has its thumbnails
+	for _, size := range Sizes {
+		thumbnail, err := EncodeJPEG(Resize(img, size.Width))
+		if err != nil {
+			return "", err
+		}
+		if err := writeFile(filepath.Join(dir, size.Name+".jpg"), thumbnail); err != nil {
+			return "", err
+		}
+	}
+	if err := writeFile(filepath.Join(dir, Original+ext), data); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// File returns the path on disk of one variant of a cover
+func (s *Store) File(hash, variant string) (string, error) {
+	if !ValidHash(hash) {
+		return "", ErrNotFound
+	}
+
+	var path string
+	if variant == Original {
+		matches, _ := filepath.Glob(filepath.Join(s.dir, hash, Original+".*"))
+		if len(matches) == 0 {
+			return "", ErrNotFound
+		}
+		path = matches[0]
+	} else {
+		for _, size := range Sizes {
+			if size.Name == variant {
+				path = filepath.Join(s.dir, hash, size.Name+".jpg")
+			}
+		}
+	}
+
+	if path == "" {
+		return "", ErrNotFound
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrNotFound
+	}
+	return path, nil
+}
+
+// writeFile writes through a temporary file so readers never see half a file
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
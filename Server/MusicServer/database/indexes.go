@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/* This file creates the indexes the server relies on at startup. The unique indexes on music entries back up the duplicate checks of the handlers, which cannot stop two requests that add the same entry at the same time. MFA challenges are dropped by a TTL index once they expire. */
+
+// Reference: https://www.mongodb.com/docs/drivers/go/current/fundamentals/indexes/
+
+// EnsureIndexes creates the indexes that are missing. Creating an index that
+// already exists does nothing. A unique index cannot be created while the
+// collection holds duplicates, those have to be merged first.
+func EnsureIndexes(ctx context.Context, client *mongo.Client) error {
+	var musicCollection *mongo.Collection = OpenCollection("musics", client)
+
+	_, err := musicCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "music_id", Value: 1}},
+			Options: options.Index().SetName("music_id_unique").SetUnique(true),
+		},
+		{
+			// Only entries that have a video, older ones may not
+			Keys: bson.D{{Key: "youtube_id", Value: 1}},
+			Options: options.Index().SetName("youtube_id_unique").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"youtube_id": bson.M{"$gt": ""}}),
+		},
+		{
+			// The cover proxy only serves URLs that are some entry's cover
+			Keys:    bson.D{{Key: "album_img", Value: 1}},
+			Options: options.Index().SetName("album_img"),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var genreCollection *mongo.Collection = OpenCollection("genres", client)
+
+	// New genre ids are the highest one plus one, two requests can pick the same
+	_, err = genreCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "genre_id", Value: 1}},
+		Options: options.Index().SetName("genre_id_unique").SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	var challengeCollection *mongo.Collection = OpenCollection("mfa_challenges", client)
+
+	// Expired challenges are of no use, MongoDB removes them in the background
+	_, err = challengeCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+	})
+	return err
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/* This file runs multi-document changes inside a MongoDB transaction, so cascading updates either apply everywhere or nowhere. Transactions need a replica set or sharded cluster (every MongoDB Atlas cluster is one). */
+
+// Reference: https://www.mongodb.com/docs/drivers/go/current/fundamentals/transactions/
+
+// RunTransaction runs fn in a transaction. Every operation inside fn must use
+// the context it receives, that context carries the session.
+func RunTransaction(ctx context.Context, client *mongo.Client, fn func(ctx context.Context) error) error {
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
@@ -0,0 +1,358 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/youtube"
+)
+
+/*
+This file writes music entries as CSV, JSON, M3U8 or XSPF one at a time, so exports of any size stream straight to the response without being built in memory. What an entry shows depends on Options: the video fields are left out for callers who may not play the album and the admin review for everyone but admins. CSV exports use the columns of the bulk import, so an admin's export can be imported again.
+*/
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatM3U8 = "m3u8"
+	FormatXSPF = "xspf"
+)
+
+var (
+	ErrUnknownFormat = errors.New("format must be csv, json, m3u8 or xspf")
+	ErrNeedsVideo    = errors.New("m3u8 and xspf exports link to the videos, which need a login")
+)
+
+var contentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatJSON: "application/json; charset=utf-8",
+	FormatM3U8: "application/vnd.apple.mpegurl",
+	FormatXSPF: "application/xspf+xml",
+}
+
+// ContentType is the MIME type of a format, empty for unknown formats
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Options decide which fields an export shows
+type Options struct {
+	Title  string // playlist title for M3U8 and XSPF
+	Video  bool   // include the YouTube video and its watch URL
+	Review bool   // include the admin review
+}
+
+// Entry is a music entry as exported
+type Entry struct {
+	MusicID      string   `json:"music_id"`
+	Title        string   `json:"title"`
+	Artist       string   `json:"artist,omitempty"`
+	ReleaseYear  int      `json:"release_year,omitempty"`
+	Label        string   `json:"label,omitempty"`
+	Country      string   `json:"country,omitempty"`
+	TrackCount   int      `json:"track_count,omitempty"`
+	Explicit     *bool    `json:"explicit,omitempty"`
+	AlbumImg     string   `json:"album_img"`
+	Genres       []string `json:"genre"`
+	Ranking      string   `json:"ranking"`
+	DurationMs   int      `json:"duration_ms,omitempty"`
+	YouTubeID    string   `json:"youtube_id,omitempty"`
+	YouTubeStart int      `json:"youtube_start,omitempty"`
+	YouTubeEnd   int      `json:"youtube_end,omitempty"`
+	URL          string   `json:"url,omitempty"`
+	AdminReview  string   `json:"admin_review,omitempty"`
+}
+
+// NewEntry flattens a music entry, leaving out what opts hides
+func NewEntry(music models.Music, opts Options) Entry {
+	entry := Entry{
+		MusicID:     music.MusicID,
+		Title:       music.Title,
+		Artist:      music.Artist,
+		ReleaseYear: music.ReleaseYear,
+		Label:       music.Label,
+		Country:     music.Country,
+		TrackCount:  music.TrackCount,
+		Explicit:    music.Explicit,
+		AlbumImg:    music.AlbumImg,
+		Genres:      make([]string, 0, len(music.Genre)),
+		Ranking:     music.Ranking.RankingName,
+	}
+	for _, genre := range music.Genre {
+		entry.Genres = append(entry.Genres, genre.GenreName)
+	}
+	// The stated total wins over the sum of the track durations
+	for _, track := range music.Tracks {
+		entry.DurationMs += track.DurationMs
+	}
+	if music.DurationSeconds > 0 {
+		entry.DurationMs = music.DurationSeconds * 1000
+	}
+
+	if opts.Video {
+		entry.YouTubeID = music.YouTubeID
+		entry.YouTubeStart = music.YouTubeStart
+		entry.YouTubeEnd = music.YouTubeEnd
+		entry.URL = youtube.WatchURL(music.YouTubeID, music.YouTubeStart)
+	}
+	// A blank review means "not reviewed yet"
+	if opts.Review {
+		entry.AdminReview = strings.TrimSpace(music.AdminReview)
+	}
+	return entry
+}
+
+// displayName is "Artist - Title", or the title alone
+func (e Entry) displayName() string {
+	name := e.Title
+	if e.Artist != "" {
+		name = e.Artist + " - " + e.Title
+	}
+	// Line breaks would end an M3U directive early
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// seconds is the playing time, the clip when it has an end, else the album, -1 when unknown
+func (e Entry) seconds() int {
+	switch {
+	case e.YouTubeEnd > e.YouTubeStart:
+		return e.YouTubeEnd - e.YouTubeStart
+	case e.DurationMs > 0:
+		return e.DurationMs / 1000
+	}
+	return -1
+}
+
+// Writer writes entries in one format. Flush sends what is buffered so far,
+// Close must be called to finish the file.
+type Writer interface {
+	Write(entry Entry) error
+	Flush() error
+	Close() error
+}
+
+// NewWriter starts an export in format on w
+func NewWriter(format string, w io.Writer, opts Options) (Writer, error) {
+	buffered := bufio.NewWriter(w)
+
+	switch format {
+	case FormatCSV:
+		// csv.Writer buffers on its own
+		return newCSVWriter(w, opts)
+	case FormatJSON:
+		return newJSONWriter(buffered)
+	case FormatM3U8, FormatXSPF:
+		if !opts.Video {
+			return nil, ErrNeedsVideo
+		}
+		if format == FormatM3U8 {
+			return newM3U8Writer(buffered, opts)
+		}
+		return newXSPFWriter(buffered, opts)
+	}
+	return nil, ErrUnknownFormat
+}
+
+type csvWriter struct {
+	out  *csv.Writer
+	opts Options
+}
+
+func newCSVWriter(w io.Writer, opts Options) (Writer, error) {
+	header := []string{"music_id", "title", "album_img", "youtube_id", "genre", "artist", "release_year",
+		"label", "country", "track_count", "duration_seconds", "explicit", "ranking"}
+	if opts.Video {
+		header = append(header, "youtube_start", "youtube_end", "url")
+	} else {
+		header = append(header[:3], header[4:]...)
+	}
+	if opts.Review {
+		header = append(header, "admin_review")
+	}
+
+	out := csv.NewWriter(w)
+	return &csvWriter{out: out, opts: opts}, out.Write(header)
+}
+
+// number is a CSV cell that is empty for 0
+func number(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+func (cw *csvWriter) Write(entry Entry) error {
+	explicit := ""
+	if entry.Explicit != nil {
+		explicit = strconv.FormatBool(*entry.Explicit)
+	}
+
+	record := []string{entry.MusicID, entry.Title, entry.AlbumImg}
+	if cw.opts.Video {
+		record = append(record, entry.YouTubeID)
+	}
+	record = append(record, strings.Join(entry.Genres, "|"), entry.Artist, number(entry.ReleaseYear),
+		entry.Label, entry.Country, number(entry.TrackCount), number(entry.DurationMs/1000), explicit, entry.Ranking)
+	if cw.opts.Video {
+		record = append(record, number(entry.YouTubeStart), number(entry.YouTubeEnd), entry.URL)
+	}
+	if cw.opts.Review {
+		record = append(record, entry.AdminReview)
+	}
+	return cw.out.Write(record)
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.out.Flush()
+	return cw.out.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	return cw.Flush()
+}
+
+// jsonWriter streams a JSON array, one entry per line
+type jsonWriter struct {
+	out   *bufio.Writer
+	count int
+}
+
+func newJSONWriter(w *bufio.Writer) (Writer, error) {
+	_, err := w.WriteString("[")
+	return &jsonWriter{out: w}, err
+}
+
+func (jw *jsonWriter) Write(entry Entry) error {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(entry); err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if jw.count == 0 {
+		separator = "\n"
+	}
+	jw.count++
+
+	if _, err := jw.out.WriteString(separator); err != nil {
+		return err
+	}
+	_, err := jw.out.Write(bytes.TrimRight(data.Bytes(), "\n"))
+	return err
+}
+
+func (jw *jsonWriter) Flush() error {
+	return jw.out.Flush()
+}
+
+func (jw *jsonWriter) Close() error {
+	if _, err := jw.out.WriteString("\n]\n"); err != nil {
+		return err
+	}
+	return jw.out.Flush()
+}
+
+// m3u8Writer writes an extended M3U playlist of YouTube watch URLs
+type m3u8Writer struct {
+	out *bufio.Writer
+}
+
+func newM3U8Writer(w *bufio.Writer, opts Options) (Writer, error) {
+	header := "#EXTM3U\n"
+	if title := strings.Join(strings.Fields(opts.Title), " "); title != "" {
+		header += "#PLAYLIST:" + title + "\n"
+	}
+	_, err := w.WriteString(header)
+	return &m3u8Writer{out: w}, err
+}
+
+func (mw *m3u8Writer) Write(entry Entry) error {
+	if entry.URL == "" {
+		return nil
+	}
+	_, err := fmt.Fprintf(mw.out, "#EXTINF:%d,%s\n%s\n", entry.seconds(), entry.displayName(), entry.URL)
+	return err
+}
+
+func (mw *m3u8Writer) Flush() error {
+	return mw.out.Flush()
+}
+
+func (mw *m3u8Writer) Close() error {
+	return mw.out.Flush()
+}
+
+// xspfTrack is a <track> of an XSPF playlist
+type xspfTrack struct {
+	XMLName  xml.Name `xml:"track"`
+	Location string   `xml:"location"`
+	Title    string   `xml:"title"`
+	Creator  string   `xml:"creator,omitempty"`
+	Image    string   `xml:"image,omitempty"`
+	Duration int      `xml:"duration,omitempty"`
+}
+
+// xspfWriter writes an XSPF playlist of YouTube watch URLs
+type xspfWriter struct {
+	out     *bufio.Writer
+	encoder *xml.Encoder
+}
+
+func newXSPFWriter(w *bufio.Writer, opts Options) (Writer, error) {
+	xw := &xspfWriter{out: w, encoder: xml.NewEncoder(w)}
+	xw.encoder.Indent("    ", "  ")
+
+	if _, err := w.WriteString(xml.Header + `<playlist version="1" xmlns="http://xspf.org/ns/0/">` + "\n"); err != nil {
+		return nil, err
+	}
+	if opts.Title != "" {
+		w.WriteString("  <title>")
+		if err := xml.EscapeText(w, []byte(opts.Title)); err != nil {
+			return nil, err
+		}
+		w.WriteString("</title>\n")
+	}
+	_, err := w.WriteString("  <trackList>")
+	return xw, err
+}
+
+func (xw *xspfWriter) Write(entry Entry) error {
+	if entry.URL == "" {
+		return nil
+	}
+
+	track := xspfTrack{Location: entry.URL, Title: entry.Title, Creator: entry.Artist, Image: entry.AlbumImg}
+	if seconds := entry.seconds(); seconds > 0 {
+		track.Duration = seconds * 1000
+	}
+	return xw.encoder.Encode(track)
+}
+
+func (xw *xspfWriter) Flush() error {
+	if err := xw.encoder.Flush(); err != nil {
+		return err
+	}
+	return xw.out.Flush()
+}
+
+func (xw *xspfWriter) Close() error {
+	if err := xw.encoder.Flush(); err != nil {
+		return err
+	}
+	if _, err := xw.out.WriteString("\n  </trackList>\n</playlist>\n"); err != nil {
+		return err
+	}
+	return xw.out.Flush()
+}
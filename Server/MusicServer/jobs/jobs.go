@@ -0,0 +1,245 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+This file is a small in-process registry for long running background jobs such as re-ranking the whole catalog. A job runs in its own goroutine and reports its progress here, so admins can poll it over the API. Jobs live in memory only: they do not survive a restart, and only the most recent ones are kept.
+*/
+
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCanceled  = "canceled"
+)
+
+// How many finished jobs are kept, and how many item errors per job
+const (
+	maxFinishedJobs = 50
+	maxJobErrors    = 20
+)
+
+var (
+	ErrJobRunning   = errors.New("a job of this kind is already running")
+	ErrJobNotFound  = errors.New("job not found")
+	ErrJobCommitted = errors.New("job has committed its changes and can no longer be canceled")
+)
+
+// Job is a snapshot of a job's progress
+type Job struct {
+	ID         string     `json:"job_id"`
+	Kind       string     `json:"kind"`
+	Action     string     `json:"action"`
+	StartedBy  string     `json:"started_by"`
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Done       int        `json:"done"`
+	Failed     int        `json:"failed"`
+	Errors     []string   `json:"errors,omitempty"`
+	Cancelable bool       `json:"cancelable"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Progress is handed to the job function to report on its work
+type Progress struct {
+	id string
+}
+
+type entry struct {
+	job    Job
+	cancel context.CancelFunc
+}
+
+var (
+	mu       sync.Mutex
+	registry = map[string]*entry{}
+)
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Start runs fn in the background. Only one job of a kind may run at a
+// time, so two re-rankings never race over the same documents.
+func Start(kind, action, startedBy string, fn func(ctx context.Context, progress *Progress) error) (Job, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, e := range registry {
+		if e.job.Kind == kind && e.job.Status == StatusRunning {
+			return Job{}, ErrJobRunning
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &entry{
+		job: Job{
+			ID:         newJobID(),
+			Kind:       kind,
+			Action:     action,
+			StartedBy:  startedBy,
+			Status:     StatusRunning,
+			Cancelable: true,
+			StartedAt:  time.Now(),
+		},
+		cancel: cancel,
+	}
+	registry[e.job.ID] = e
+	prune()
+
+	progress := &Progress{id: e.job.ID}
+	go func() {
+		defer cancel()
+		err := fn(ctx, progress)
+		finish(ctx, progress.id, err)
+	}()
+
+	return e.job, nil
+}
+
+// finish records how the job ended
+func finish(ctx context.Context, id string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	e := registry[id]
+	now := time.Now()
+	e.job.FinishedAt = &now
+	switch {
+	case ctx.Err() == context.Canceled:
+		e.job.Status = StatusCanceled
+	case err != nil:
+		e.job.Status = StatusFailed
+		e.job.Error = err.Error()
+	default:
+		e.job.Status = StatusSucceeded
+	}
+}
+
+// prune drops the oldest finished jobs beyond maxFinishedJobs
+func prune() {
+	var finished []*entry
+	for _, e := range registry {
+		if e.job.Status != StatusRunning {
+			finished = append(finished, e)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].job.StartedAt.Before(finished[j].job.StartedAt) })
+	for _, e := range finished[:len(finished)-maxFinishedJobs] {
+		delete(registry, e.job.ID)
+	}
+}
+
+// update applies a change to the job under the lock
+func (p *Progress) update(change func(job *Job)) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if e, ok := registry[p.id]; ok {
+		change(&e.job)
+	}
+}
+
+// SetTotal sets how many items the job will process
+func (p *Progress) SetTotal(total int) {
+	p.update(func(job *Job) { job.Total = total })
+}
+
+// Step records one processed item. A non-nil err counts it as failed.
+func (p *Progress) Step(item string, err error) {
+	p.update(func(job *Job) {
+		job.Done++
+		if err == nil {
+			return
+		}
+		job.Failed++
+		if len(job.Errors) < maxJobErrors {
+			job.Errors = append(job.Errors, item+": "+err.Error())
+		}
+	})
+}
+
+// Advance records n items processed at once, for work done in a single write
+func (p *Progress) Advance(n int) {
+	p.update(func(job *Job) { job.Done += n })
+}
+
+// Commit marks the point from which the job has to run to its end, such as
+// a write that must not be left half done. Cancel refuses the job afterwards.
+func (p *Progress) Commit() {
+	p.update(func(job *Job) { job.Cancelable = false })
+}
+
+// Get returns a job by ID
+func Get(id string) (Job, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	e, ok := registry[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return e.job, nil
+}
+
+// List returns the known jobs, newest first, optionally of one kind
+func List(kind string) []Job {
+	mu.Lock()
+	defer mu.Unlock()
+
+	list := make([]Job, 0, len(registry))
+	for _, e := range registry {
+		if kind == "" || e.job.Kind == kind {
+			list = append(list, e.job)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.After(list[j].StartedAt) })
+	return list
+}
+
+// Running reports whether a job of the kind is in progress
+func Running(kind string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, e := range registry {
+		if e.job.Kind == kind && e.job.Status == StatusRunning {
+			return true
+		}
+	}
+	return false
+}
+
+// Cancel asks a running job to stop. The job stops at its next context check.
+// A job that has committed is not canceled and ErrJobCommitted is returned.
+func Cancel(id string) (Job, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	e, ok := registry[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	if e.job.Status == StatusRunning && !e.job.Cancelable {
+		return e.job, ErrJobCommitted
+	}
+	if e.job.Status == StatusRunning {
+		e.cancel()
+	}
+	return e.job, nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	controller "github.com/omicreativedev/TunePeep/Server/MusicServer/controllers"
 	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
 	"github.com/omicreativedev/TunePeep/Server/MusicServer/routes"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -59,7 +60,7 @@ func main() {
 	config.AllowOrigins = origins
 	config.AllowMethods = []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"}
 	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
-	config.ExposeHeaders = []string{"Content-Length", "Set-Cookie"}
+	config.ExposeHeaders = []string{"Content-Length", "Set-Cookie", "X-Total-Count", "X-Possible-Duplicates"}
 	config.AllowCredentials = true
 	config.MaxAge = 12 * time.Hour
 
@@ -85,11 +86,23 @@ func main() {
 		}
 	}()
 
+	// Create the database indexes, the unique ones refuse duplicate music
+	if err := database.EnsureIndexes(context.Background(), client); err != nil {
+		log.Println("Warning: unable to create database indexes:", err)
+	}
+
+	// Build the in-memory catalog indexes (search) from the database
+	if err := controller.RebuildCatalogIndexes(client); err != nil {
+		log.Println("Warning: unable to build catalog indexes:", err)
+	}
+
 	// Set up application routes
 	// Unprotected routes (public access)
 	routes.SetupUnProtectedRoutes(router, client)
 	// Protected routes (require authentication)
 	routes.SetupProtectedRoutes(router, client)
+	// Admin routes (require authentication and the ADMIN role)
+	routes.SetupAdminRoutes(router, client)
 	
 	// Start the HTTP server on port 8080
 	// Server not working: this error message will display if 
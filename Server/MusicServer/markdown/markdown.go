@@ -0,0 +1,91 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+/*
+This file cleans free-form Markdown, such as liner notes, before it is stored. Clients render it as Markdown, so raw HTML is dropped and links may only lead to http, https and mailto addresses or relative paths; other links point to "#" instead. Everything else is kept as written.
+*/
+
+var (
+	htmlComment = regexp.MustCompile(`(?s)<!--.*?(-->|$)`)
+	// Elements whose content must go with them
+	htmlBlock = regexp.MustCompile(`(?is)<(?:script|style|iframe|object|template)\b.*?</\s*(?:script|style|iframe|object|template)\s*>`)
+	htmlTag   = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?>`)
+
+	inlineLink    = regexp.MustCompile(`(\]\(\s*)(<[^<>\n]*>|[^\s()]*)`)
+	referenceLink = regexp.MustCompile(`(?m)^( {0,3}\[[^\]\n]+\]:[ \t]*)(<[^<>\n]*>|\S+)`)
+	autolink      = regexp.MustCompile(`<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^<>\s]*)>`)
+
+	scheme = regexp.MustCompile(`^([a-z][a-z0-9+.-]*):`)
+)
+
+var safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// Sanitize removes raw HTML and unsafe links from Markdown
+func Sanitize(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.Map(func(r rune) rune {
+		if r < ' ' && r != '\n' && r != '\t' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, text)
+
+	// Removing a tag can join the pieces around it into a new one
+	for {
+		cleaned := htmlComment.ReplaceAllString(text, "")
+		cleaned = htmlBlock.ReplaceAllString(cleaned, "")
+		cleaned = htmlTag.ReplaceAllString(cleaned, "")
+		if cleaned == text {
+			break
+		}
+		text = cleaned
+	}
+
+	text = inlineLink.ReplaceAllStringFunc(text, func(match string) string {
+		parts := inlineLink.FindStringSubmatch(match)
+		return parts[1] + safeDestination(parts[2])
+	})
+	text = referenceLink.ReplaceAllStringFunc(text, func(match string) string {
+		parts := referenceLink.FindStringSubmatch(match)
+		return parts[1] + safeDestination(parts[2])
+	})
+	text = autolink.ReplaceAllStringFunc(text, func(match string) string {
+		target := match[1 : len(match)-1]
+		if SafeURL(target) {
+			return match
+		}
+		return target
+	})
+
+	return strings.TrimSpace(text)
+}
+
+// safeDestination returns a link destination, or "#" when it is unsafe
+func safeDestination(destination string) string {
+	target := strings.TrimSuffix(strings.TrimPrefix(destination, "<"), ">")
+	if SafeURL(target) {
+		return destination
+	}
+	return "#"
+}
+
+// SafeURL reports whether a link target is relative or uses an allowed
+// scheme. Entities, escapes and blanks are removed first, as a browser
+// would ignore them.
+func SafeURL(target string) bool {
+	target = html.UnescapeString(target)
+	target = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '\\' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, target)
+
+	match := scheme.FindStringSubmatch(strings.ToLower(target))
+	return match == nil || safeSchemes[match[1]]
+}
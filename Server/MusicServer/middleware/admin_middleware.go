@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+)
+
+/* This file is a Gin middleware for admin-only routes. It must run after AuthMiddleWare, reads the role stored in the request context and returns 403 Forbidden for anyone who is not an ADMIN. */
+
+func AdminMiddleWare() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+
+		if err != nil { // AuthMiddleWare did not run
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Role not found in context"}) // Send 401 error + text
+			c.Abort() // Abort the request
+			return // Exit the function early
+		}
+
+		if role != "ADMIN" { // Logged in, but not allowed
+			c.JSON(http.StatusForbidden, gin.H{"error": "User must be admin role"}) // Send 403 error + text
+			c.Abort() // Abort the request
+			return // Exit the function early
+		}
+
+		c.Next() // Proceed to the route handler
+	}
+}
@@ -1,49 +1,106 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/omicreativedev/TunePeep/Server/MusicServer/utils"
+	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
-/* This file is a Gin middleware that protects API routes by validating JWT access tokens. It gets tokens from incoming requests, verifies their authenticity and expiration, and stores user claims in the request context. The middleware returns 401 Unauthorized responses for missing, invalid, or expired tokens. */
+/* This file is a Gin middleware that protects API routes by validating JWT access tokens. It gets tokens from incoming requests, verifies their authenticity, expiration and session, and stores user claims in the request context. The middleware returns 401 Unauthorized responses for missing, invalid, or expired tokens and for revoked sessions, and 403 Forbidden for disabled accounts. OptionalAuthMiddleWare does the same checks for public routes but lets anonymous visitors through. */
+
+// authenticate runs every token check. It returns the claims and the user's
+// access groups, or the HTTP status and message to reject the request with.
+func authenticate(c *gin.Context, client *mongo.Client) (*utils.SignedDetails, []string, int, string) {
+	// Get the JSON Web Token from GetAccessToken() in utils/tokenUtil.go, or the error if there's an error
+	token, err := utils.GetAccessToken(c)
+
+	// Check if there's an error while gtting tha token
+	if err != nil { // If the error is not null
+		return nil, nil, http.StatusUnauthorized, err.Error() // Send 401 error with message
+	}
+
+	if token == "" { // If token is empty
+		return nil, nil, http.StatusUnauthorized, "No token provided" // Send 401 error + text
+	}
+
+	// Send the token to utils/tokenUtil.go to verify and decode
+	// Return as claims, or the error if there's an error
+	claims, err := utils.ValidateToken(token)
+
+	if err != nil { // If token is invalid or expired
+		return nil, nil, http.StatusUnauthorized, "Invalid token" // Send 401 error + text
+	}
+
+	// Check the session behind the token has not been revoked (logout, password change)
+	var ctx, cancel = context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	active, err := utils.IsSessionActive(ctx, claims.SessionId, claims.UserId, client)
+
+	if err != nil { // Database problem
+		return nil, nil, http.StatusInternalServerError, "Unable to verify session" // Send 500 error + text
+	}
+
+	if !active { // Session revoked or expired
+		return nil, nil, http.StatusUnauthorized, "Session expired, please log in again" // Send 401 error + text
+	}
+
+	// Disabled accounts are locked out even if their session is still alive
+	account, err := utils.GetAccountStatus(ctx, claims.UserId, client)
+
+	if err == mongo.ErrNoDocuments { // Account deleted
+		return nil, nil, http.StatusUnauthorized, "Account not found" // Send 401 error + text
+	}
+
+	if err != nil { // Database problem
+		return nil, nil, http.StatusInternalServerError, "Unable to verify account" // Send 500 error + text
+	}
+
+	if account.Disabled { // Account disabled by an admin
+		return nil, nil, http.StatusForbidden, "Account disabled" // Send 403 error + text
+	}
+
+	return claims, account.Groups, 0, ""
+}
+
+// setClaims stores the UserID, Role, SessionID and access groups in the request context (for use later)
+func setClaims(c *gin.Context, claims *utils.SignedDetails, groups []string) {
+	c.Set("userId", claims.UserId)
+	c.Set("role", claims.Role)
+	c.Set("sessionId", claims.SessionId)
+	c.Set("groups", groups)
+}
 
 // Returns gin.HandlerFunc (which IS func(*gin.Context))
-func AuthMiddleWare() gin.HandlerFunc {
+func AuthMiddleWare(client *mongo.Client) gin.HandlerFunc {
 	// This IS the gin.HandlerFunc being returned
 	return func(c *gin.Context) {
-		// Auth logic here
-		// Get the JSON Web Token from GetAccessToken() in utils/tokenUtil.go, or the error if there's an error
-		token, err := utils.GetAccessToken(c)
+		claims, groups, status, message := authenticate(c, client)
 
-		// Check if there's an error while gtting tha token
-		if err != nil { // If the error is not null
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()}) // Send 401 error with message
+		if claims == nil { // One of the checks failed
+			c.JSON(status, gin.H{"error": message}) // Send the error + text
 			c.Abort() // Abort the request
 			return // Exit the function early
 		}
 
-		if token == "" { // If token is empty
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "No token provided"}) // Send 401 error + text
-			c.Abort() // Abort the request
-			return // Exit the function early
-		}
+		setClaims(c, claims, groups)
 
-		// Send the token to utils/tokenUtil.go to verify and decode
-		// Return as claims, or the error if there's an error
-		claims, err := utils.ValidateToken(token)
+		c.Next() // All checks passed. Proceed to the route handler
+	}
+}
 
-		if err != nil { // If token is invalid or expired
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"}) // Send 401 error + text
-			c.Abort() // Abort the request
-			return // Exit the function early
+// OptionalAuthMiddleWare identifies logged in users on public routes.
+// Anyone without a valid token is treated as an anonymous visitor.
+func OptionalAuthMiddleWare(client *mongo.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, groups, _, _ := authenticate(c, client); claims != nil {
+			setClaims(c, claims, groups)
 		}
 
-		// Store the UserID and Role in the request context (for use later)
-		c.Set("userId", claims.UserId)
-		c.Set("role", claims.Role)
-
-		c.Next() // All checks passed. Proceed to the route handler
+		c.Next() // Proceed to the route handler either way
 	}
-}
\ No newline at end of file
+}
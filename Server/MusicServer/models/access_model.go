@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/* This file defines access rules for music entries and playlists, and the user groups those rules can name. Group membership is stored on the user (users.groups) and the groups collection holds the groups admins manage. */
+
+// Who a resource is visible to
+const (
+	VisibilityPublic     = "public"
+	VisibilityMembers    = "members"
+	VisibilityRestricted = "restricted"
+)
+
+// Access is the rule stored on a resource. Users and Groups only matter for
+// restricted resources; a restricted resource with neither is admin only.
+type Access struct {
+	Visibility string   `json:"visibility" bson:"visibility" validate:"required,oneof=public members restricted"`
+	Users      []string `json:"users" bson:"users" validate:"omitempty,max=500,dive,required"`
+	Groups     []string `json:"groups" bson:"groups" validate:"omitempty,max=100,dive,required"`
+}
+
+type Group struct {
+	ID          bson.ObjectID `json:"-" bson:"_id,omitempty"`
+	Name        string        `json:"name" bson:"name"`
+	Description string        `json:"description" bson:"description"`
+	CreatedBy   string        `json:"created_by" bson:"created_by"`
+	CreatedAt   time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+type GroupRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=50"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+type GroupUpdate struct {
+	Description string `json:"description" validate:"max=500"`
+}
+
+type GroupMembers struct {
+	UserIDs []string `json:"user_ids" validate:"required,min=1,max=500,dive,required"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/* This file defines artists. Music entries reference their artists by artist_id in artist_ids, so an artist's albums can be listed. The free-text artist credit on an entry stays as it is displayed. The artist migration splits old "Artist - Title" titles into proposals that admins review before they are applied. */
+
+type Artist struct {
+	ID        bson.ObjectID  `json:"-" bson:"_id,omitempty"`
+	ArtistID  string         `json:"artist_id" bson:"artist_id"`
+	Name      string         `json:"name" bson:"name"`
+	Bio       string         `json:"bio" bson:"bio"`
+	Image     string         `json:"image" bson:"image"`
+	Links     []ExternalLink `json:"links" bson:"links"`
+	CreatedAt time.Time      `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at" bson:"updated_at"`
+}
+
+// ArtistSummary is an artist in listings
+type ArtistSummary struct {
+	ArtistID   string `json:"artist_id"`
+	Name       string `json:"name"`
+	Image      string `json:"image"`
+	AlbumCount int    `json:"album_count"`
+}
+
+type ArtistRequest struct {
+	Name  string         `json:"name" validate:"required,min=1,max=200"`
+	Bio   string         `json:"bio" validate:"max=5000"`
+	Image string         `json:"image" validate:"omitempty,url"`
+	Links []ExternalLink `json:"links" validate:"omitempty,max=20,dive"`
+}
+
+// ArtistUpdate only changes the fields that are sent
+type ArtistUpdate struct {
+	Name  *string         `json:"name" validate:"omitempty,min=1,max=200"`
+	Bio   *string         `json:"bio" validate:"omitempty,max=5000"`
+	Image *string         `json:"image" validate:"omitempty,url"`
+	Links *[]ExternalLink `json:"links" validate:"omitempty,max=20,dive"`
+}
+
+// Artist migration proposal states
+const (
+	ArtistProposalPending  = "pending"
+	ArtistProposalApplied  = "applied"
+	ArtistProposalRejected = "rejected"
+)
+
+// ArtistProposal is the migration's suggestion for one music entry: the
+// title without the artist, and the artists by name
+type ArtistProposal struct {
+	MusicID       string     `json:"music_id" bson:"music_id"`
+	OriginalTitle string     `json:"original_title" bson:"original_title"`
+	Title         string     `json:"title" bson:"title"`
+	Artists       []string   `json:"artists" bson:"artists"`
+	Status        string     `json:"status" bson:"status"`
+	CreatedAt     time.Time  `json:"created_at" bson:"created_at"`
+	ReviewedBy    string     `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+}
+
+// ArtistProposalUpdate corrects a proposal before it is applied
+type ArtistProposalUpdate struct {
+	Title   string   `json:"title" validate:"required,min=2,max=500"`
+	Artists []string `json:"artists" validate:"required,min=1,max=10,dive,required,max=200"`
+}
+
+// ArtistProposalReview applies or rejects proposals
+type ArtistProposalReview struct {
+	MusicIDs []string `json:"music_ids" validate:"required,min=1,max=500,dive,required"`
+}
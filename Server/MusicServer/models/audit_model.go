@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/* This file defines the audit trail entry. Admin actions that change or expose another user's data are recorded here with who did it, what they did and to which record. */
+
+type AuditLog struct {
+	ID         bson.ObjectID `json:"-" bson:"_id,omitempty"`
+	Action     string        `json:"action" bson:"action"`
+	ActorID    string        `json:"actor_id" bson:"actor_id"`
+	TargetType string        `json:"target_type" bson:"target_type"`
+	TargetID   string        `json:"target_id" bson:"target_id"`
+	Details    bson.M        `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt  time.Time     `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/* This file defines invite codes and the registration mode setting. In invite-only mode a new account needs a valid invite code, which decides the account's role and any pre-assigned access groups. */
+
+// Registration modes
+const (
+	RegistrationOpen       = "open"
+	RegistrationInviteOnly = "invite-only"
+	RegistrationClosed     = "closed"
+)
+
+type InviteUse struct {
+	UserID string    `json:"user_id" bson:"user_id"`
+	Email  string    `json:"email" bson:"email"`
+	UsedAt time.Time `json:"used_at" bson:"used_at"`
+}
+
+type Invite struct {
+	ID        bson.ObjectID `json:"-" bson:"_id,omitempty"`
+	Code      string        `json:"code" bson:"code"`
+	MaxUses   int           `json:"max_uses" bson:"max_uses"`
+	Uses      int           `json:"uses" bson:"uses"`
+	Role      string        `json:"role" bson:"role"`
+	Groups    []string      `json:"groups" bson:"groups"`
+	ExpiresAt time.Time     `json:"expires_at" bson:"expires_at"`
+	CreatedBy string        `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+	RevokedAt *time.Time    `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	UsedBy    []InviteUse   `json:"used_by" bson:"used_by"`
+}
+
+type InviteRequest struct {
+	MaxUses        int      `json:"max_uses" validate:"omitempty,min=1,max=10000"`
+	ExpiresInHours int      `json:"expires_in_hours" validate:"omitempty,min=1,max=8760"`
+	Role           string   `json:"role" validate:"omitempty,oneof=ADMIN USER"`
+	Groups         []string `json:"groups" validate:"omitempty,dive,min=1,max=100"`
+}
+
+type RegistrationSetting struct {
+	Mode string `json:"mode" bson:"mode" validate:"required,oneof=open invite-only closed"`
+}
@@ -1,28 +1,166 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/youtube"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-/* This file defines the music-related data structures. It contains the main Music model along with types for Genre and Ranking. These structs include BSON and JSON field mappings for MongoDB and API responses. */
+/* This file defines the music-related data structures. It contains the main Music model with its optional album metadata, types for Genre, Track and Ranking (including the Not_Ranked sentinel), the typed edit request and the admin requests that manage them. These structs include BSON and JSON field mappings for MongoDB and API responses. */
 
 type Genre struct {
 	GenreID int `bson:"genre_id" json:"genre_id" validate:"required"`
 	GenreName string `bson:"genre_name" json:"genre_name" validate:"required,min=2,max=100"`
 }
 
+// Albums without a review sit in the Not_Ranked tier. It is a fixed sentinel
+// that always sorts last and cannot be edited, reordered or deleted.
+const (
+	NotRankedValue = 999
+	NotRankedName  = "Not_Ranked"
+)
+
 type Ranking struct  {
 	RankingValue int `bson:"ranking_value" json:"ranking_value" validate:"required"`
 	RankingName string `bson:"ranking_name" json:"ranking_name" validate:"required"`
 }
 
+type Track struct {
+	Number int `bson:"number" json:"number" validate:"min=1"`
+	Title string `bson:"title" json:"title" validate:"required,max=500"`
+	DurationMs int `bson:"duration_ms,omitempty" json:"duration_ms,omitempty" validate:"min=0"`
+}
+
+// ExternalLink is a labelled link to another site, such as a label's page
+type ExternalLink struct {
+	Label string `json:"label" bson:"label" validate:"required,max=50"`
+	URL string `json:"url" bson:"url" validate:"required,url"`
+}
+
 type Music struct {
 	ID bson.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
 	MusicID string `bson:"music_id" json:"music_id" validate:"required"`
 	Title string `bson:"title" json:"title" validate:"required,min=2,max=500"`
 	AlbumImg string `bson:"album_img" json:"album_img" validate:"required,url"`
-	YouTubeID string `bson:"youtube_id" json:"youtube_id" validate:"required"`
+	Cover string `bson:"cover,omitempty" json:"cover,omitempty"` // content hash of an uploaded cover
+	Artist string `bson:"artist,omitempty" json:"artist,omitempty" validate:"max=500"`
+	ArtistIDs []string `bson:"artist_ids,omitempty" json:"artist_ids,omitempty" validate:"omitempty,max=10,dive,required"`
+	ReleaseYear int `bson:"release_year,omitempty" json:"release_year,omitempty" validate:"omitempty,min=1000,max=9999"`
+	Tracks []Track `bson:"tracks,omitempty" json:"tracks,omitempty" validate:"dive"`
+	Label string `bson:"label,omitempty" json:"label,omitempty" validate:"max=200"`
+	Country string `bson:"country,omitempty" json:"country,omitempty" validate:"omitempty,iso3166_1_alpha2"` // ISO 3166 code, upper case
+	TrackCount int `bson:"track_count,omitempty" json:"track_count,omitempty" validate:"omitempty,min=1,max=500"`
+	DurationSeconds int `bson:"duration_seconds,omitempty" json:"duration_seconds,omitempty" validate:"omitempty,min=1,max=86400"`
+	Explicit *bool `bson:"explicit,omitempty" json:"explicit,omitempty"` // nil when unknown
+	Links []ExternalLink `bson:"links,omitempty" json:"links,omitempty" validate:"omitempty,max=20,dive"`
+	LinerNotes string `bson:"liner_notes,omitempty" json:"liner_notes,omitempty" validate:"max=20000"` // sanitized Markdown
+	YouTubeID string `bson:"youtube_id" json:"youtube_id" validate:"required,youtube_id"`
+	YouTubeStart int `bson:"youtube_start,omitempty" json:"youtube_start,omitempty" validate:"min=0"`
+	YouTubeEnd int `bson:"youtube_end,omitempty" json:"youtube_end,omitempty" validate:"omitempty,gtfield=YouTubeStart"`
 	Genre []Genre `bson:"genre" json:"genre" validate:"required,dive"`
 	AdminReview string `bson:"admin_review" json:"admin_review" validate:"required"`
 	Ranking Ranking `bson:"ranking" json:"ranking" validate:"required"`
-}
\ No newline at end of file
+	Access *Access `bson:"access,omitempty" json:"access,omitempty"`
+	MusicBrainz *MusicBrainzInfo `bson:"musicbrainz,omitempty" json:"musicbrainz,omitempty"`
+}
+
+// MusicRequest is a new music entry as sent to /addmusic or in an import
+// file. The YouTube reference may be a URL and genres may name only an ID
+// or a name; catalog.NewMusic and catalog.Genres.Resolve turn it into a Music.
+type MusicRequest struct {
+	MusicID string `json:"music_id"`
+	Title string `json:"title"`
+	AlbumImg string `json:"album_img"`
+	YouTubeID string `json:"youtube_id"`
+	YouTubeStart *int `json:"youtube_start,omitempty"`
+	YouTubeEnd *int `json:"youtube_end,omitempty"`
+	Genre []Genre `json:"genre"`
+	AdminReview string `json:"admin_review,omitempty"`
+	Ranking Ranking `json:"ranking,omitempty"`
+	Access *Access `json:"access,omitempty"`
+	Artist string `json:"artist,omitempty"`
+	ArtistIDs []string `json:"artist_ids,omitempty"`
+	ReleaseYear int `json:"release_year,omitempty"`
+	Tracks []Track `json:"tracks,omitempty"`
+	Label string `json:"label,omitempty"`
+	Country string `json:"country,omitempty"`
+	TrackCount int `json:"track_count,omitempty"`
+	DurationSeconds int `json:"duration_seconds,omitempty"`
+	Explicit *bool `json:"explicit,omitempty"`
+	Links []ExternalLink `json:"links,omitempty"`
+	LinerNotes string `json:"liner_notes,omitempty"`
+	// Fill empty fields from the Spotify album with this music_id
+	ImportMetadata bool `json:"import_metadata,omitempty"`
+}
+
+// Seconds is a video timestamp sent as whole seconds or as text like "1m30s"
+type Seconds int
+
+func (s *Seconds) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		seconds, err := youtube.ParseTime(text)
+		*s = Seconds(seconds)
+		return err
+	}
+
+	var number float64
+	if err := json.Unmarshal(data, &number); err != nil {
+		return errors.New("must be a number of seconds")
+	}
+	if number < 0 || number != float64(int(number)) {
+		return errors.New("must be a whole number of seconds, 0 or more")
+	}
+	*s = Seconds(number)
+	return nil
+}
+
+// MusicUpdate is a PATCH /edit/:music_id body. Only the fields sent are
+// changed; an empty value removes an optional field. The review, ranking
+// and access rule have endpoints of their own.
+type MusicUpdate struct {
+	MusicID *string `json:"music_id" validate:"omitnil,min=1,max=200"`
+	Title *string `json:"title" validate:"omitnil,min=2,max=500"`
+	AlbumImg *string `json:"album_img" validate:"omitnil,url"`
+	YouTubeID *string `json:"youtube_id" validate:"omitnil,min=1"`
+	YouTubeStart *Seconds `json:"youtube_start"`
+	YouTubeEnd *Seconds `json:"youtube_end"`
+	Genre *[]Genre `json:"genre" validate:"omitnil,min=1"`
+	Artist *string `json:"artist" validate:"omitnil,max=500"`
+	ArtistIDs *[]string `json:"artist_ids" validate:"omitnil,max=10,dive,required"`
+	ReleaseYear *int `json:"release_year" validate:"omitnil,min=1000,max=9999"`
+	Tracks *[]Track `json:"tracks" validate:"omitnil,dive"`
+	Label *string `json:"label" validate:"omitnil,max=200"`
+	Country *string `json:"country" validate:"omitnil,iso3166_1_alpha2"`
+	TrackCount *int `json:"track_count" validate:"omitnil,min=1,max=500"`
+	DurationSeconds *int `json:"duration_seconds" validate:"omitnil,min=1,max=86400"`
+	Explicit *bool `json:"explicit"`
+	Links *[]ExternalLink `json:"links" validate:"omitnil,max=20,dive"`
+	LinerNotes *string `json:"liner_notes" validate:"omitnil,max=20000"`
+}
+
+type GenreRequest struct {
+	GenreName string `json:"genre_name" validate:"required,min=2,max=100"`
+}
+
+type GenreMerge struct {
+	IntoGenreID int `json:"into_genre_id" validate:"required"`
+}
+
+type RankingRequest struct {
+	RankingName string `json:"ranking_name" validate:"required,min=2,max=50"`
+}
+
+// RankingOrder lists every tier's ranking_value, best tier first
+type RankingOrder struct {
+	Order []int `json:"order" validate:"required,min=1,dive,required"`
+}
+
+// DuplicateMerge folds the Merge entries into Keep and deletes them. Entries
+// are named by their _id, older entries may share a music_id.
+type DuplicateMerge struct {
+	Keep string `json:"keep" validate:"required,mongodb"`
+	Merge []string `json:"merge" validate:"required,min=1,max=50,dive,required,mongodb"`
+}
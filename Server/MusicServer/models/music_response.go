@@ -0,0 +1,166 @@
+package models
+
+import (
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/covers"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/* This file defines the API response shapes for music entries, one per audience. Anonymous visitors only get a teaser without the YouTube ID, links or liner notes, logged in members get what they need to play the album, and admins get every stored field. Handlers convert models.Music into one of these instead of serializing the storage model directly. */
+
+// Audiences a music entry can be rendered for
+const (
+	AudienceAnonymous = "anonymous"
+	AudienceMember    = "member"
+	AudienceAdmin     = "admin"
+)
+
+type MusicTeaser struct {
+	ID       bson.ObjectID `json:"_id,omitempty"`
+	MusicID  string        `json:"music_id"`
+	Title    string        `json:"title"`
+	AlbumImg string        `json:"album_img"`
+	// Thumbnail and original paths of an uploaded cover
+	Covers      map[string]string `json:"covers,omitempty"`
+	Artist      string            `json:"artist,omitempty"`
+	ArtistIDs   []string          `json:"artist_ids,omitempty"`
+	ReleaseYear int               `json:"release_year,omitempty"`
+	Label       string            `json:"label,omitempty"`
+	Country     string            `json:"country,omitempty"`
+	TrackCount  int               `json:"track_count,omitempty"`
+	Duration    int               `json:"duration_seconds,omitempty"`
+	Explicit    *bool             `json:"explicit,omitempty"`
+	Genre       []Genre           `json:"genre"`
+	Ranking     Ranking           `json:"ranking"`
+}
+
+type MusicPlayable struct {
+	MusicTeaser
+	YouTubeID    string           `json:"youtube_id"`
+	YouTubeStart int              `json:"youtube_start,omitempty"`
+	YouTubeEnd   int              `json:"youtube_end,omitempty"`
+	Tracks       []Track          `json:"tracks,omitempty"`
+	Links        []ExternalLink   `json:"links,omitempty"`
+	LinerNotes   string           `json:"liner_notes,omitempty"`
+	MusicBrainz  *MusicBrainzInfo `json:"musicbrainz,omitempty"`
+	AdminReview  string           `json:"admin_review"`
+}
+
+type MusicAdminView struct {
+	ID           bson.ObjectID     `json:"_id,omitempty"`
+	MusicID      string            `json:"music_id"`
+	Title        string            `json:"title"`
+	AlbumImg     string            `json:"album_img"`
+	Cover        string            `json:"cover,omitempty"`
+	Covers       map[string]string `json:"covers,omitempty"`
+	Artist       string            `json:"artist,omitempty"`
+	ArtistIDs    []string          `json:"artist_ids,omitempty"`
+	ReleaseYear  int               `json:"release_year,omitempty"`
+	Label        string            `json:"label,omitempty"`
+	Country      string            `json:"country,omitempty"`
+	TrackCount   int               `json:"track_count,omitempty"`
+	Duration     int               `json:"duration_seconds,omitempty"`
+	Explicit     *bool             `json:"explicit,omitempty"`
+	Links        []ExternalLink    `json:"links,omitempty"`
+	LinerNotes   string            `json:"liner_notes,omitempty"`
+	Tracks       []Track           `json:"tracks,omitempty"`
+	YouTubeID    string            `json:"youtube_id"`
+	YouTubeStart int               `json:"youtube_start,omitempty"`
+	YouTubeEnd   int               `json:"youtube_end,omitempty"`
+	Genre        []Genre           `json:"genre"`
+	AdminReview  string            `json:"admin_review"`
+	Ranking      Ranking           `json:"ranking"`
+	Access       *Access           `json:"access,omitempty"`
+	MusicBrainz  *MusicBrainzInfo  `json:"musicbrainz,omitempty"`
+}
+
+func NewMusicTeaser(music Music) MusicTeaser {
+	return MusicTeaser{
+		ID:          music.ID,
+		MusicID:     music.MusicID,
+		Title:       music.Title,
+		AlbumImg:    music.AlbumImg,
+		Covers:      coverPaths(music),
+		Artist:      music.Artist,
+		ArtistIDs:   music.ArtistIDs,
+		ReleaseYear: music.ReleaseYear,
+		Label:       music.Label,
+		Country:     music.Country,
+		TrackCount:  music.TrackCount,
+		Duration:    music.DurationSeconds,
+		Explicit:    music.Explicit,
+		Genre:       music.Genre,
+		Ranking:     music.Ranking,
+	}
+}
+
+// coverPaths lists the URL paths of an uploaded cover, nil without one
+func coverPaths(music Music) map[string]string {
+	if music.Cover == "" {
+		return nil
+	}
+	return covers.Paths(music.Cover)
+}
+
+func NewMusicPlayable(music Music) MusicPlayable {
+	return MusicPlayable{
+		MusicTeaser:  NewMusicTeaser(music),
+		YouTubeID:    music.YouTubeID,
+		YouTubeStart: music.YouTubeStart,
+		YouTubeEnd:   music.YouTubeEnd,
+		Tracks:       music.Tracks,
+		Links:        music.Links,
+		LinerNotes:   music.LinerNotes,
+		MusicBrainz:  music.MusicBrainz.Public(),
+		AdminReview:  music.AdminReview,
+	}
+}
+
+func NewMusicAdminView(music Music) MusicAdminView {
+	return MusicAdminView{
+		ID:           music.ID,
+		MusicID:      music.MusicID,
+		Title:        music.Title,
+		AlbumImg:     music.AlbumImg,
+		Cover:        music.Cover,
+		Covers:       coverPaths(music),
+		Artist:       music.Artist,
+		ArtistIDs:    music.ArtistIDs,
+		ReleaseYear:  music.ReleaseYear,
+		Label:        music.Label,
+		Country:      music.Country,
+		TrackCount:   music.TrackCount,
+		Duration:     music.DurationSeconds,
+		Explicit:     music.Explicit,
+		Links:        music.Links,
+		LinerNotes:   music.LinerNotes,
+		Tracks:       music.Tracks,
+		YouTubeID:    music.YouTubeID,
+		YouTubeStart: music.YouTubeStart,
+		YouTubeEnd:   music.YouTubeEnd,
+		Genre:        music.Genre,
+		AdminReview:  music.AdminReview,
+		Ranking:      music.Ranking,
+		Access:       music.Access,
+		MusicBrainz:  music.MusicBrainz,
+	}
+}
+
+// NewMusicView renders a music entry for the given audience
+func NewMusicView(music Music, audience string) interface{} {
+	switch audience {
+	case AudienceAdmin:
+		return NewMusicAdminView(music)
+	case AudienceMember:
+		return NewMusicPlayable(music)
+	}
+	return NewMusicTeaser(music)
+}
+
+// NewMusicViews renders a list of music entries for the given audience
+func NewMusicViews(musics []Music, audience string) []interface{} {
+	views := make([]interface{}, 0, len(musics))
+	for _, music := range musics {
+		views = append(views, NewMusicView(music, audience))
+	}
+	return views
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+)
+
+/* This file defines the MusicBrainz facts stored on a music entry. The enrichment job matches an entry to a release; clear matches are stored right away, ambiguous ones keep their candidates until an admin confirms one. */
+
+// Where an entry stands with MusicBrainz
+const (
+	MusicBrainzMatched   = "matched"
+	MusicBrainzAmbiguous = "ambiguous"
+	MusicBrainzNotFound  = "not_found"
+	MusicBrainzConfirmed = "confirmed"
+	MusicBrainzRejected  = "rejected"
+)
+
+type MusicBrainzArtist struct {
+	ID   string `bson:"id" json:"id"`
+	Name string `bson:"name" json:"name"`
+}
+
+// MusicBrainzCandidate is a release the job could not choose between
+type MusicBrainzCandidate struct {
+	ReleaseID string `bson:"release_id" json:"release_id"`
+	Title     string `bson:"title" json:"title"`
+	Artist    string `bson:"artist" json:"artist"`
+	Date      string `bson:"date,omitempty" json:"date,omitempty"`
+	Country   string `bson:"country,omitempty" json:"country,omitempty"`
+	Score     int    `bson:"score" json:"score"`
+}
+
+type MusicBrainzInfo struct {
+	Status         string                 `bson:"status" json:"status"`
+	ReleaseID      string                 `bson:"release_id,omitempty" json:"release_id,omitempty"`
+	ReleaseGroupID string                 `bson:"release_group_id,omitempty" json:"release_group_id,omitempty"`
+	ReleaseDate    string                 `bson:"release_date,omitempty" json:"release_date,omitempty"`
+	Labels         []string               `bson:"labels,omitempty" json:"labels,omitempty"`
+	Artists        []MusicBrainzArtist    `bson:"artists,omitempty" json:"artists,omitempty"`
+	Tracks         []Track                `bson:"tracks,omitempty" json:"tracks,omitempty"`
+	Candidates     []MusicBrainzCandidate `bson:"candidates,omitempty" json:"candidates,omitempty"`
+	UpdatedAt      time.Time              `bson:"updated_at" json:"updated_at"`
+	ConfirmedBy    string                 `bson:"confirmed_by,omitempty" json:"confirmed_by,omitempty"`
+}
+
+type MusicBrainzEnrichRequest struct {
+	MusicIDs []string `json:"music_ids"`
+	// Also revisit entries that were matched or not found before. Confirmed
+	// and rejected entries are never touched by the job.
+	Rematch bool `json:"rematch"`
+}
+
+type MusicBrainzConfirm struct {
+	ReleaseID string `json:"release_id" validate:"required,uuid"`
+}
+
+// Public is the part members see: only a matched or confirmed release,
+// without the review details
+func (info *MusicBrainzInfo) Public() *MusicBrainzInfo {
+	if info == nil || (info.Status != MusicBrainzMatched && info.Status != MusicBrainzConfirmed) {
+		return nil
+	}
+	public := *info
+	public.Candidates = nil
+	public.ConfirmedBy = ""
+	return &public
+}
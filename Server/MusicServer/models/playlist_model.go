@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/* This file defines curated playlists. A playlist keeps an ordered list of items that reference music entries by music_id, so album details are always read fresh from the musics collection. Like music entries, a playlist can carry an access rule. */
+
+type PlaylistItem struct {
+	MusicID string    `json:"music_id" bson:"music_id"`
+	AddedAt time.Time `json:"added_at" bson:"added_at"`
+}
+
+type Playlist struct {
+	ID          bson.ObjectID  `json:"-" bson:"_id,omitempty"`
+	PlaylistID  string         `json:"playlist_id" bson:"playlist_id"`
+	Title       string         `json:"title" bson:"title"`
+	Description string         `json:"description" bson:"description"`
+	Cover       string         `json:"cover" bson:"cover"`
+	Items       []PlaylistItem `json:"items" bson:"items"`
+	Access      *Access        `json:"access,omitempty" bson:"access,omitempty"`
+	CreatedBy   string         `json:"created_by" bson:"created_by"`
+	CreatedAt   time.Time      `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at" bson:"updated_at"`
+}
+
+// PlaylistSummary is a playlist in listings, without its items
+type PlaylistSummary struct {
+	PlaylistID  string    `json:"playlist_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Cover       string    `json:"cover"`
+	ItemCount   int       `json:"item_count"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type PlaylistRequest struct {
+	Title       string   `json:"title" validate:"required,min=2,max=200"`
+	Description string   `json:"description" validate:"max=2000"`
+	Cover       string   `json:"cover" validate:"omitempty,url"`
+	MusicIDs    []string `json:"music_ids" validate:"omitempty,max=500,dive,required"`
+	Access      *Access  `json:"access"`
+}
+
+// PlaylistUpdate only changes the fields that are sent
+type PlaylistUpdate struct {
+	Title       *string `json:"title" validate:"omitempty,min=2,max=200"`
+	Description *string `json:"description" validate:"omitempty,max=2000"`
+	Cover       *string `json:"cover" validate:"omitempty,url"`
+}
+
+// PlaylistItemsRequest inserts items at Position (0 is the top), or appends
+// them when Position is missing
+type PlaylistItemsRequest struct {
+	MusicIDs []string `json:"music_ids" validate:"required,min=1,max=500,dive,required"`
+	Position *int     `json:"position" validate:"omitempty,min=0"`
+}
+
+// PlaylistOrder lists every music_id of the playlist in the new order
+type PlaylistOrder struct {
+	MusicIDs []string `json:"music_ids" validate:"required,dive,required"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/* This file defines the login session data structure. A session is created on every successful login, its ID is carried inside the JWT tokens, and revoking it logs that device out even if its tokens have not expired yet. An MFA challenge is the step between the password and the session: it counts the codes tried with one challenge token and is used up once the second factor is accepted. */
+
+type Session struct {
+	ID         bson.ObjectID `json:"-" bson:"_id,omitempty"`
+	SessionID  string        `json:"session_id" bson:"session_id"`
+	UserID     string        `json:"user_id" bson:"user_id"`
+	UserAgent  string        `json:"user_agent" bson:"user_agent"`
+	IPAddress  string        `json:"ip_address" bson:"ip_address"`
+	CreatedAt  time.Time     `json:"created_at" bson:"created_at"`
+	LastUsedAt time.Time     `json:"last_used_at" bson:"last_used_at"`
+	ExpiresAt  time.Time     `json:"expires_at" bson:"expires_at"`
+	RevokedAt  *time.Time    `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+type MFAChallenge struct {
+	ID          bson.ObjectID `json:"-" bson:"_id,omitempty"`
+	ChallengeID string        `json:"challenge_id" bson:"challenge_id"`
+	UserID      string        `json:"user_id" bson:"user_id"`
+	Purpose     string        `json:"purpose" bson:"purpose"`
+	Attempts    int           `json:"attempts" bson:"attempts"`
+	CreatedAt   time.Time     `json:"created_at" bson:"created_at"`
+	ExpiresAt   time.Time     `json:"expires_at" bson:"expires_at"`
+	UsedAt      *time.Time    `json:"used_at,omitempty" bson:"used_at,omitempty"`
+}
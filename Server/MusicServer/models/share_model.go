@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/* This file defines share links. A share link lets someone without an account open one music entry or playlist through a signed URL, until it expires, runs out of uses or is revoked. */
+
+// What a share link can point at
+const (
+	ShareTargetMusic    = "music"
+	ShareTargetPlaylist = "playlist"
+)
+
+type ShareLink struct {
+	ID           bson.ObjectID `json:"-" bson:"_id,omitempty"`
+	ShareID      string        `json:"share_id" bson:"share_id"`
+	TargetType   string        `json:"target_type" bson:"target_type"`
+	TargetID     string        `json:"target_id" bson:"target_id"`
+	MaxUses      int           `json:"max_uses" bson:"max_uses"` // 0 means unlimited
+	Uses         int           `json:"uses" bson:"uses"`
+	ExpiresAt    time.Time     `json:"expires_at" bson:"expires_at"`
+	CreatedBy    string        `json:"created_by" bson:"created_by"`
+	CreatedAt    time.Time     `json:"created_at" bson:"created_at"`
+	RevokedAt    *time.Time    `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	LastAccessAt *time.Time    `json:"last_access_at,omitempty" bson:"last_access_at,omitempty"`
+}
+
+type ShareLinkRequest struct {
+	TargetType     string `json:"target_type" validate:"required,oneof=music playlist"`
+	TargetID       string `json:"target_id" validate:"required"`
+	MaxUses        int    `json:"max_uses" validate:"omitempty,min=1,max=10000"`
+	ExpiresInHours int    `json:"expires_in_hours" validate:"omitempty,min=1,max=2160"`
+}
@@ -6,7 +6,7 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-/* This file defines the data structures for user management. It contains the main User model for MongoDB along with helper types for authentication (UserLogin) and API responses (UserResponse). The structs include validation tags and BSON mappings for proper database serialization. */
+/* This file defines the data structures for user management. It contains the main User model for MongoDB along with helper types for authentication (UserLogin), two-factor authentication (MFA*) API responses (UserResponse, AdminUserResponse) and self-service profile changes (ProfileUpdate, PasswordChange). The structs include validation tags and BSON mappings for proper database serialization. */
 
 type User struct {
 	ID              bson.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
@@ -21,6 +21,18 @@ type User struct {
 	Token           string        `json:"token" bson:"token"`
 	RefreshToken    string        `json:"refresh_token" bson:"refresh_token"`
 	FavoriteGenres []Genre       `json:"favorite_genres" bson:"favorite_genres" validate:"required,dive"`
+	MFAEnabled      bool          `json:"mfa_enabled" bson:"mfa_enabled"`
+	MFASecret       string        `json:"-" bson:"mfa_secret,omitempty"`
+	MFAPendingSecret string       `json:"-" bson:"mfa_pending_secret,omitempty"`
+	MFAPendingAttempts int        `json:"-" bson:"mfa_pending_attempts,omitempty"` // wrong codes for the pending secret
+	MFALastStep     int64         `json:"-" bson:"mfa_last_step,omitempty"` // last accepted TOTP time step
+	MFAFailures     int           `json:"-" bson:"mfa_failures,omitempty"` // codes tried since the last accepted one
+	MFALockedUntil  *time.Time    `json:"-" bson:"mfa_locked_until,omitempty"`
+	RecoveryCodes   []string      `json:"-" bson:"mfa_recovery_codes,omitempty"` // bcrypt hashes
+	Disabled        bool          `json:"disabled" bson:"disabled"`
+	DisabledAt      *time.Time    `json:"disabled_at,omitempty" bson:"disabled_at,omitempty"`
+	Groups          []string      `json:"groups" bson:"groups"`
+	InviteCode      string        `json:"invite_code,omitempty" bson:"-"` // only read at registration
 }
 type UserLogin struct {
 	Email    string `json:"email" validate:"required,email"`
@@ -32,7 +44,40 @@ type UserResponse struct {
 	LastName        string  `json:"last_name"`
 	Email           string  `json:"email"`
 	Role            string  `json:"role"`
-	Token           string  `json:"token"`
-	RefreshToken    string  `json:"refresh_token"`
 	FavoriteGenres []Genre `json:"favorite_genres"`
+	MFAEnabled      bool    `json:"mfa_enabled"`
+	Disabled        bool    `json:"disabled"`
+	Groups          []string `json:"groups"`
+}
+type AdminUserResponse struct {
+	UserResponse
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"update_at"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+type RoleUpdate struct {
+	Role string `json:"role" validate:"required,oneof=ADMIN USER"`
+}
+type MFACode struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+type MFAChallengeRequest struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+type MFAEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	QRPayload       string `json:"qr_payload"`
+}
+type ProfileUpdate struct {
+	FirstName      *string `json:"first_name" validate:"omitempty,min=2,max=100"`
+	LastName       *string `json:"last_name" validate:"omitempty,min=2,max=100"`
+	FavoriteGenres []Genre  `json:"favorite_genres" validate:"omitempty,dive"`
+}
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
 }
@@ -0,0 +1,79 @@
+package musicbrainz
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+)
+
+/*
+This file decides whether a search result is a safe match for a music entry. An album usually has many releases (countries, reissues) that all score 100, so those are treated as one answer and the earliest is kept. Only when the good candidates disagree on title or artist, or none is close enough, is the choice left to an admin.
+*/
+
+// Lowest search score accepted without an admin
+const minAutoScore = 90
+
+// normalize keeps only lower case letters and digits so punctuation and
+// spacing differences do not matter
+func normalize(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Match picks the release for title and artist from search candidates. ok is
+// false when the candidates are too weak or disagree.
+func Match(candidates []models.MusicBrainzCandidate, title, artist string) (best models.MusicBrainzCandidate, ok bool) {
+	wantTitle, wantArtist := normalize(title), normalize(artist)
+
+	var strong []models.MusicBrainzCandidate
+	for _, candidate := range candidates {
+		if candidate.Score < minAutoScore || normalize(candidate.Title) != wantTitle {
+			continue
+		}
+		if wantArtist != "" && normalize(candidate.Artist) != wantArtist {
+			continue
+		}
+		strong = append(strong, candidate)
+	}
+	if len(strong) == 0 {
+		return best, false
+	}
+
+	// Without an artist to check, different artists' albums of the same name are ambiguous
+	for _, candidate := range strong[1:] {
+		if normalize(candidate.Artist) != normalize(strong[0].Artist) {
+			return best, false
+		}
+	}
+
+	// The earliest dated release stands for the original album
+	sort.SliceStable(strong, func(i, j int) bool {
+		if strong[i].Date == "" || strong[j].Date == "" {
+			return strong[j].Date == "" && strong[i].Date != ""
+		}
+		return strong[i].Date < strong[j].Date
+	})
+	return strong[0], true
+}
+
+// Info turns a release into the facts stored on a music entry
+func (r Release) Info(status string) models.MusicBrainzInfo {
+	return models.MusicBrainzInfo{
+		Status:         status,
+		ReleaseID:      r.ID,
+		ReleaseGroupID: r.ReleaseGroupID,
+		ReleaseDate:    r.Date,
+		Labels:         r.Labels,
+		Artists:        r.Artists,
+		Tracks:         r.Tracks,
+		UpdatedAt:      time.Now(),
+	}
+}
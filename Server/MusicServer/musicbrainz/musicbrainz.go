@@ -0,0 +1,282 @@
+package musicbrainz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+)
+
+/*
+This file is a client for the MusicBrainz web service. MusicBrainz allows one request per second per application and asks for a descriptive User-Agent, so every request waits its turn on a shared limiter and a 503 is retried once after the advertised delay. The base URL can be changed to point at a mirror or a local stub.
+*/
+
+const (
+	DefaultURL       = "https://musicbrainz.org/ws/2"
+	DefaultUserAgent = "TunePeep/1.0 ( https://github.com/omicreativedev/TunePeep )"
+
+	requestTimeout  = 15 * time.Second
+	requestInterval = time.Second
+	maxRetryWait    = 10 * time.Second
+)
+
+var (
+	ErrReleaseNotFound = errors.New("release not found on MusicBrainz")
+	ErrInvalidID       = errors.New("not a valid MusicBrainz ID")
+)
+
+var mbidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// ValidID reports whether id is a well formed MBID
+func ValidID(id string) bool {
+	return mbidPattern.MatchString(id)
+}
+
+// Release is a release with the facts stored on a music entry
+type Release struct {
+	ID             string
+	Title          string
+	Date           string
+	ReleaseGroupID string
+	Labels         []string
+	Artists        []models.MusicBrainzArtist
+	Tracks         []models.Track
+}
+
+// limiter spaces requests out to one per interval
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the caller may send a request
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Client talks to the MusicBrainz web service
+type Client struct {
+	baseURL   string
+	userAgent string
+	http      *http.Client
+	limiter   *limiter
+}
+
+// NewClient uses baseURL (DefaultURL when empty) and identifies as userAgent
+func NewClient(baseURL, userAgent string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	return &Client{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		userAgent: userAgent,
+		http:      &http.Client{Timeout: requestTimeout},
+		limiter:   &limiter{interval: requestInterval},
+	}
+}
+
+// NewClientFromEnv reads the optional MUSICBRAINZ_URL and MUSICBRAINZ_USER_AGENT
+func NewClientFromEnv() *Client {
+	return NewClient(os.Getenv("MUSICBRAINZ_URL"), os.Getenv("MUSICBRAINZ_USER_AGENT"))
+}
+
+// get fetches path with query into target, waiting on the rate limiter
+func (c *Client) get(ctx context.Context, path string, query url.Values, target interface{}) error {
+	query.Set("fmt", "json")
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("User-Agent", c.userAgent)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			err = json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(target)
+			resp.Body.Close()
+			if err != nil {
+				return fmt.Errorf("musicbrainz response: %w", err)
+			}
+			return nil
+
+		case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest:
+			resp.Body.Close()
+			return ErrReleaseNotFound
+
+		// Over the rate limit, wait as asked and try once more
+		case resp.StatusCode == http.StatusServiceUnavailable && attempt == 0:
+			resp.Body.Close()
+			wait := requestInterval
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = min(time.Duration(seconds)*time.Second, maxRetryWait)
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+		default:
+			resp.Body.Close()
+			return fmt.Errorf("musicbrainz returned status %d", resp.StatusCode)
+		}
+	}
+}
+
+// Lucene special characters in search terms
+var luceneEscaper = strings.NewReplacer(
+	`\`, `\\`, `+`, `\+`, `-`, `\-`, `!`, `\!`, `(`, `\(`, `)`, `\)`, `:`, `\:`,
+	`^`, `\^`, `[`, `\[`, `]`, `\]`, `"`, `\"`, `{`, `\{`, `}`, `\}`, `~`, `\~`,
+	`*`, `\*`, `?`, `\?`, `|`, `\|`, `&`, `\&`, `/`, `\/`,
+)
+
+type artistCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+	Artist     struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"artist"`
+}
+
+// creditName joins an artist credit the way MusicBrainz displays it
+func creditName(credits []artistCredit) string {
+	var name strings.Builder
+	for _, credit := range credits {
+		name.WriteString(credit.Name + credit.JoinPhrase)
+	}
+	return name.String()
+}
+
+// SearchReleases finds releases by title and, when known, artist, best match first
+func (c *Client) SearchReleases(ctx context.Context, title, artist string, limit int) ([]models.MusicBrainzCandidate, error) {
+	query := `release:"` + luceneEscaper.Replace(title) + `"`
+	if artist != "" {
+		query += ` AND artist:"` + luceneEscaper.Replace(artist) + `"`
+	}
+
+	var body struct {
+		Releases []struct {
+			ID           string         `json:"id"`
+			Score        int            `json:"score"`
+			Title        string         `json:"title"`
+			Date         string         `json:"date"`
+			Country      string         `json:"country"`
+			ArtistCredit []artistCredit `json:"artist-credit"`
+		} `json:"releases"`
+	}
+	params := url.Values{"query": {query}, "limit": {strconv.Itoa(limit)}}
+	if err := c.get(ctx, "/release", params, &body); err != nil {
+		return nil, err
+	}
+
+	candidates := make([]models.MusicBrainzCandidate, 0, len(body.Releases))
+	for _, release := range body.Releases {
+		candidates = append(candidates, models.MusicBrainzCandidate{
+			ReleaseID: release.ID,
+			Title:     release.Title,
+			Artist:    creditName(release.ArtistCredit),
+			Date:      release.Date,
+			Country:   release.Country,
+			Score:     release.Score,
+		})
+	}
+	return candidates, nil
+}
+
+// Release fetches a release with its labels, artists and tracks
+func (c *Client) Release(ctx context.Context, id string) (Release, error) {
+	if !ValidID(id) {
+		return Release{}, ErrInvalidID
+	}
+
+	var body struct {
+		ID           string `json:"id"`
+		Title        string `json:"title"`
+		Date         string `json:"date"`
+		ReleaseGroup struct {
+			ID string `json:"id"`
+		} `json:"release-group"`
+		ArtistCredit []artistCredit `json:"artist-credit"`
+		LabelInfo    []struct {
+			Label *struct {
+				Name string `json:"name"`
+			} `json:"label"`
+		} `json:"label-info"`
+		Media []struct {
+			Tracks []struct {
+				Title  string `json:"title"`
+				Length int    `json:"length"`
+			} `json:"tracks"`
+		} `json:"media"`
+	}
+	params := url.Values{"inc": {"artist-credits labels recordings release-groups"}}
+	if err := c.get(ctx, "/release/"+id, params, &body); err != nil {
+		return Release{}, err
+	}
+
+	release := Release{ID: body.ID, Title: body.Title, Date: body.Date, ReleaseGroupID: body.ReleaseGroup.ID}
+
+	for _, credit := range body.ArtistCredit {
+		release.Artists = append(release.Artists, models.MusicBrainzArtist{ID: credit.Artist.ID, Name: credit.Artist.Name})
+	}
+
+	seen := map[string]bool{}
+	for _, info := range body.LabelInfo {
+		if info.Label != nil && !seen[info.Label.Name] {
+			seen[info.Label.Name] = true
+			release.Labels = append(release.Labels, info.Label.Name)
+		}
+	}
+
+	// Number tracks across discs
+	for _, medium := range body.Media {
+		for _, track := range medium.Tracks {
+			release.Tracks = append(release.Tracks, models.Track{Number: len(release.Tracks) + 1, Title: track.Title, DurationMs: track.Length})
+		}
+	}
+	return release, nil
+}
@@ -0,0 +1,195 @@
+package musicbrainz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/* This file runs the client against a local stub of the MusicBrainz web service: the limiter keeps requests apart, a 503 is retried exactly once, and releases are read with their labels, artists and tracks. */
+
+const testReleaseID = "0d1f5a6c-5b4e-4f0e-9a41-52a1f1f1c0de"
+
+// testInterval keeps the limiter measurable without slowing the tests down
+const testInterval = 50 * time.Millisecond
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL, "TunePeepTest/1.0")
+	client.limiter.interval = testInterval
+	return client
+}
+
+func TestLimiterSpacesRequests(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		w.Write([]byte(`{"releases":[]}`))
+	})
+
+	// Concurrent callers share the limiter
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.SearchReleases(context.Background(), "White Pony", "", 5); err != nil {
+				t.Errorf("SearchReleases: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(times) != 4 {
+		t.Fatalf("stub got %d requests, want 4", len(times))
+	}
+	first, last := times[0], times[0]
+	for _, at := range times {
+		if at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	// Four requests take at least three intervals, less a little timer slack
+	if spread := last.Sub(first); spread < 3*testInterval-10*time.Millisecond {
+		t.Errorf("4 requests within %v, want at least %v", spread, 3*testInterval)
+	}
+}
+
+func TestLimiterHonorsContext(t *testing.T) {
+	l := &limiter{interval: time.Hour}
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("first wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestRetriesOnce(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"503 then success", 1, 2, false},
+		{"503 twice", 2, 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= tt.failures {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte(`{"releases":[]}`))
+			})
+
+			_, err := client.SearchReleases(context.Background(), "White Pony", "", 5)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("SearchReleases = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "503") {
+				t.Errorf("error %q does not name the status", err)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("stub got %d requests, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestSearchReleases(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/release" || r.URL.Query().Get("fmt") != "json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if agent := r.Header.Get("User-Agent"); agent != "TunePeepTest/1.0" {
+			t.Errorf("User-Agent = %q", agent)
+		}
+		if query := r.URL.Query().Get("query"); query != `release:"AC\/DC\: Live" AND artist:"AC\/DC"` {
+			t.Errorf("query = %q", query)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"releases": []map[string]interface{}{{
+				"id":    testReleaseID,
+				"score": 100,
+				"title": "Live",
+				"date":  "1992-10-27",
+				"artist-credit": []map[string]interface{}{
+					{"name": "AC/DC", "joinphrase": " & ", "artist": map[string]string{"id": "a1", "name": "AC/DC"}},
+					{"name": "Friends", "artist": map[string]string{"id": "a2", "name": "Friends"}},
+				},
+			}},
+		})
+	})
+
+	candidates, err := client.SearchReleases(context.Background(), "AC/DC: Live", "AC/DC", 5)
+	if err != nil {
+		t.Fatalf("SearchReleases: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Artist != "AC/DC & Friends" || candidates[0].Score != 100 {
+		t.Errorf("candidates = %+v", candidates)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/release/"+testReleaseID {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":            testReleaseID,
+			"title":         "White Pony",
+			"date":          "2000-06-20",
+			"release-group": map[string]string{"id": "rg1"},
+			"artist-credit": []map[string]interface{}{{"name": "Deftones", "artist": map[string]string{"id": "a1", "name": "Deftones"}}},
+			"label-info":    []map[string]interface{}{{"label": map[string]string{"name": "Maverick"}}, {"label": map[string]string{"name": "Maverick"}}, {"label": nil}},
+			"media": []map[string]interface{}{
+				{"tracks": []map[string]interface{}{{"title": "Feiticeira", "length": 189000}}},
+				{"tracks": []map[string]interface{}{{"title": "Back to School", "length": 237000}}},
+			},
+		})
+	})
+
+	release, err := client.Release(context.Background(), testReleaseID)
+	if err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if release.ReleaseGroupID != "rg1" || len(release.Labels) != 1 || len(release.Artists) != 1 {
+		t.Errorf("release = %+v", release)
+	}
+	if len(release.Tracks) != 2 || release.Tracks[1].Number != 2 || release.Tracks[1].Title != "Back to School" {
+		t.Errorf("tracks = %+v", release.Tracks)
+	}
+
+	if _, err := client.Release(context.Background(), "ffffffff-ffff-ffff-ffff-ffffffffffff"); !errors.Is(err, ErrReleaseNotFound) {
+		t.Errorf("Release of an unknown ID = %v, want ErrReleaseNotFound", err)
+	}
+	if _, err := client.Release(context.Background(), "white-pony"); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("Release of an invalid ID = %v, want ErrInvalidID", err)
+	}
+}
@@ -0,0 +1,103 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	controller "github.com/omicreativedev/TunePeep/Server/MusicServer/controllers"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/middleware"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/* This file defines admin-only API routes under /admin. They run after the authentication middleware from SetupProtectedRoutes and additionally require the ADMIN role. */
+
+func SetupAdminRoutes(router *gin.Engine, client *mongo.Client) {
+	admin := router.Group("/admin", middleware.AdminMiddleWare())
+
+	// User management
+	admin.GET("/users", controller.AdminListUsers(client))
+	admin.GET("/users/:user_id", controller.AdminGetUser(client))
+	admin.PATCH("/users/:user_id/role", controller.AdminUpdateUserRole(client))
+	admin.POST("/users/:user_id/disable", controller.AdminDisableUser(client))
+	admin.POST("/users/:user_id/enable", controller.AdminEnableUser(client))
+	admin.POST("/users/:user_id/logout", controller.AdminForceLogout(client))
+	admin.GET("/users/:user_id/export", controller.AdminExportUser(client))
+	admin.DELETE("/users/:user_id", controller.AdminDeleteUser(client))
+
+	// Registration mode and invite codes
+	admin.PUT("/settings/registration", controller.AdminSetRegistrationMode(client))
+	admin.POST("/invites", controller.AdminCreateInvite(client))
+	admin.GET("/invites", controller.AdminListInvites(client))
+	admin.GET("/invites/:code", controller.AdminGetInvite(client))
+	admin.DELETE("/invites/:code", controller.AdminRevokeInvite(client))
+
+	// Genre management
+	admin.GET("/genres", controller.AdminListGenres(client))
+	admin.POST("/genres", controller.AdminCreateGenre(client))
+	admin.PATCH("/genres/:genre_id", controller.AdminRenameGenre(client))
+	admin.POST("/genres/:genre_id/merge", controller.AdminMergeGenre(client))
+	admin.DELETE("/genres/:genre_id", controller.AdminDeleteGenre(client))
+
+	// Ranking taxonomy and re-ranking
+	admin.GET("/rankings", controller.AdminListRankings(client))
+	admin.POST("/rankings", controller.AdminCreateRanking(client))
+	admin.PUT("/rankings/order", controller.AdminReorderRankings(client))
+	admin.PATCH("/rankings/:ranking_value", controller.AdminRenameRanking(client))
+	admin.DELETE("/rankings/:ranking_value", controller.AdminDeleteRanking(client))
+	admin.POST("/rankings/rerank", controller.AdminRerankMusics(client))
+
+	// Curated playlists
+	admin.POST("/playlists", controller.AdminCreatePlaylist(client))
+	admin.PATCH("/playlists/:playlist_id", controller.AdminUpdatePlaylist(client))
+	admin.DELETE("/playlists/:playlist_id", controller.AdminDeletePlaylist(client))
+	admin.POST("/playlists/:playlist_id/items", controller.AdminAddPlaylistItems(client))
+	admin.DELETE("/playlists/:playlist_id/items/:music_id", controller.AdminRemovePlaylistItem(client))
+	admin.PUT("/playlists/:playlist_id/order", controller.AdminReorderPlaylist(client))
+
+	// Uploaded album covers
+	admin.POST("/musics/:music_id/cover", controller.AdminUploadCover(client))
+	admin.DELETE("/musics/:music_id/cover", controller.AdminDeleteCover(client))
+
+	// Artists and the title migration
+	admin.POST("/artists", controller.AdminCreateArtist(client))
+	admin.PATCH("/artists/:artist_id", controller.AdminUpdateArtist(client))
+	admin.DELETE("/artists/:artist_id", controller.AdminDeleteArtist(client))
+	admin.POST("/artists/migration", controller.AdminStartArtistMigration(client))
+	admin.GET("/artists/migration", controller.AdminListArtistProposals(client))
+	admin.PUT("/artists/migration/:music_id", controller.AdminUpdateArtistProposal(client))
+	admin.POST("/artists/migration/apply", controller.AdminApplyArtistProposals(client))
+	admin.POST("/artists/migration/reject", controller.AdminRejectArtistProposals(client))
+
+	// Bulk catalog import
+	admin.POST("/musics/import", controller.AdminImportMusics(client))
+
+	// Duplicate detection
+	admin.GET("/duplicates", controller.AdminListDuplicates(client))
+	admin.POST("/duplicates/merge", controller.AdminMergeDuplicates(client))
+
+	// MusicBrainz enrichment
+	admin.POST("/musicbrainz/enrich", controller.AdminEnrichMusicBrainz(client))
+	admin.GET("/musicbrainz/review", controller.AdminListMusicBrainzReview(client))
+	admin.PUT("/musics/:music_id/musicbrainz", controller.AdminConfirmMusicBrainz(client))
+	admin.DELETE("/musics/:music_id/musicbrainz", controller.AdminRejectMusicBrainz(client))
+
+	// Access rules and user groups
+	admin.PUT("/musics/:music_id/access", controller.AdminSetMusicAccess(client))
+	admin.PUT("/playlists/:playlist_id/access", controller.AdminSetPlaylistAccess(client))
+	admin.GET("/groups", controller.AdminListGroups(client))
+	admin.POST("/groups", controller.AdminCreateGroup(client))
+	admin.PATCH("/groups/:name", controller.AdminUpdateGroup(client))
+	admin.DELETE("/groups/:name", controller.AdminDeleteGroup(client))
+	admin.GET("/groups/:name/members", controller.AdminListGroupMembers(client))
+	admin.POST("/groups/:name/members", controller.AdminAddGroupMembers(client))
+	admin.DELETE("/groups/:name/members/:user_id", controller.AdminRemoveGroupMember(client))
+
+	// Share links for people without an account
+	admin.POST("/shares", controller.AdminCreateShareLink(client))
+	admin.GET("/shares", controller.AdminListShareLinks(client))
+	admin.GET("/shares/:share_id", controller.AdminGetShareLink(client))
+	admin.DELETE("/shares/:share_id", controller.AdminRevokeShareLink(client))
+
+	// Background jobs
+	admin.GET("/jobs", controller.AdminListJobs())
+	admin.GET("/jobs/:job_id", controller.AdminGetJob())
+	admin.POST("/jobs/:job_id/cancel", controller.AdminCancelJob())
+}
@@ -10,13 +10,27 @@ import (
 /* This file defines public API routes that require authentication. It maps HTTP endpoints to their corresponding controller functions. */
 
 func SetupProtectedRoutes(router *gin.Engine, client *mongo.Client) {
-	router.Use(middleware.AuthMiddleWare())
+	router.Use(middleware.AuthMiddleWare(client))
 
 	router.GET("/music/:music_id", controller.GetMusic(client))
-	router.POST("/addmusic", controller.AddMusic(client))
+	router.POST("/addmusic", middleware.AdminMiddleWare(), controller.AddMusic(client))
+	router.POST("/addmusic/preview", middleware.AdminMiddleWare(), controller.AddMusicPreview(client))
   router.GET("/recommendedmusic", controller.GetRecommendedMusics(client))
 	router.PATCH("/updatereview/:music_id", controller.AdminReviewUpdate(client))
  // NEW
- 	router.PATCH("/edit/:music_id", controller.EditMusic(client))
-	router.DELETE("/delete/:music_id", controller.DeleteMusic(client))
+ 	router.PATCH("/edit/:music_id", middleware.AdminMiddleWare(), controller.EditMusic(client))
+	router.DELETE("/delete/:music_id", middleware.AdminMiddleWare(), controller.DeleteMusic(client))
+
+	// Self-service profile
+	router.GET("/me", controller.GetMe(client))
+	router.PATCH("/me", controller.UpdateMe(client))
+	router.POST("/me/password", controller.ChangePassword(client))
+	router.GET("/me/export", controller.ExportMe(client))
+	router.DELETE("/me", controller.DeleteMe(client))
+
+	// Two-factor authentication
+	router.POST("/mfa/enroll", controller.BeginMFAEnrollment(client))
+	router.POST("/mfa/enroll/confirm", controller.ConfirmMFAEnrollment(client))
+	router.POST("/mfa/disable", controller.DisableMFA(client))
+	router.POST("/mfa/recovery-codes", controller.RegenerateRecoveryCodes(client))
 }
@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	controller "github.com/omicreativedev/TunePeep/Server/MusicServer/controllers"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/middleware"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
@@ -10,9 +11,26 @@ import (
 
 func SetupUnProtectedRoutes(router *gin.Engine, client *mongo.Client) {
 
-	router.GET("/musics", controller.GetMusics(client))
+	// Anonymous visitors get teasers, logged in users get playable entries
+	router.GET("/musics", middleware.OptionalAuthMiddleWare(client), controller.GetMusics(client))
+	router.GET("/musics/export", middleware.OptionalAuthMiddleWare(client), controller.ExportMusics(client))
+	router.GET("/search", middleware.OptionalAuthMiddleWare(client), controller.SearchMusics(client))
+	router.GET("/suggest", middleware.OptionalAuthMiddleWare(client), controller.SuggestMusics(client))
+	router.GET("/playlists", middleware.OptionalAuthMiddleWare(client), controller.GetPlaylists(client))
+	router.GET("/playlists/:playlist_id", middleware.OptionalAuthMiddleWare(client), controller.GetPlaylist(client))
+	router.GET("/playlists/:playlist_id/export", middleware.OptionalAuthMiddleWare(client), controller.ExportPlaylist(client))
+	router.GET("/artists", middleware.OptionalAuthMiddleWare(client), controller.GetArtists(client))
+	router.GET("/artists/:artist_id", middleware.OptionalAuthMiddleWare(client), controller.GetArtist(client))
+	router.GET("/shared/:token", controller.GetSharedResource(client))
+	router.GET("/musics/:music_id/cover", middleware.OptionalAuthMiddleWare(client), controller.GetMusicCover(client))
+	router.GET("/covers/proxy", middleware.OptionalAuthMiddleWare(client), controller.GetCoverProxy(client))
+	router.GET("/covers/:hash/:variant", controller.GetCover())
 	router.POST("/register", controller.RegisterUser(client))
+	router.GET("/registration", controller.GetRegistrationInfo(client))
 	router.POST("/login", controller.LoginUser(client))
+	router.POST("/login/mfa", controller.VerifyMFALogin(client))
+	router.POST("/login/mfa/enroll", controller.BeginLoginMFAEnrollment(client))
+	router.POST("/login/mfa/enroll/confirm", controller.ConfirmLoginMFAEnrollment(client))
 	router.POST("/logout", controller.LogoutHandler(client))
 	router.GET("/genres", controller.GetGenres(client))
 	router.POST("/refresh", controller.RefreshTokenHandler(client))
@@ -0,0 +1,373 @@
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+/*
+This file is a small in-process full-text index. Documents are plain field/text maps, so the index can be rebuilt from any store (MongoDB today) and does not depend on a database text index. Queries are relevance ranked with per-field weights, tolerate typos in short queries, match the last word as a prefix and return highlighted snippets.
+*/
+
+// Document is one searchable record. Payload is handed back untouched in hits.
+type Document struct {
+	ID      string
+	Fields  map[string]string
+	Payload interface{}
+}
+
+// Highlight is a snippet of a matched field with the matches wrapped in <mark>.
+// The text is HTML escaped so the snippet can be rendered as HTML safely.
+type Highlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// Hit is one search result
+type Hit struct {
+	ID         string
+	Score      float64
+	Highlights []Highlight
+	Payload    interface{}
+}
+
+// Options narrows a query
+type Options struct {
+	Fields []string // only search these fields (all when empty)
+	Limit  int
+	Allow  func(doc Document) bool // optional filter, e.g. access checks
+}
+
+// posting records how often a term appears in a field of a document
+type posting struct {
+	doc   int
+	field string
+	count int
+}
+
+// Index is safe for concurrent use. Rebuild swaps the content atomically.
+type Index struct {
+	mu       sync.RWMutex
+	weights  map[string]float64
+	docs     []Document
+	postings map[string][]posting
+}
+
+const (
+	snippetRadius   = 60
+	shortQueryTerms = 3 // typo tolerance only applies to queries up to this many words
+)
+
+// NewIndex creates an empty index. Weights rank matches per field, fields
+// missing from the map weigh 1.
+func NewIndex(weights map[string]float64) *Index {
+	return &Index{weights: weights, postings: map[string][]posting{}}
+}
+
+// token is a normalized word and its byte offsets in the original text
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into lower case words of letters and digits
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+// Terms returns the normalized words of text, used by callers that index terms themselves
+func Terms(text string) []string {
+	tokens := tokenize(text)
+	terms := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		terms = append(terms, t.term)
+	}
+	return terms
+}
+
+// Rebuild replaces the whole index content
+func (idx *Index) Rebuild(docs []Document) {
+	postings := map[string][]posting{}
+	for i, doc := range docs {
+		for field, text := range doc.Fields {
+			counts := map[string]int{}
+			for _, t := range tokenize(text) {
+				counts[t.term]++
+			}
+			for term, count := range counts {
+				postings[term] = append(postings[term], posting{doc: i, field: field, count: count})
+			}
+		}
+	}
+
+	idx.mu.Lock()
+	idx.docs = docs
+	idx.postings = postings
+	idx.mu.Unlock()
+}
+
+// Len is the number of indexed documents
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// maxTypos is how many edits a query word of this length may contain
+func maxTypos(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+// editDistance is the optimal string alignment distance (Levenshtein plus
+// adjacent transpositions), giving up once it exceeds limit
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > limit {
+		return limit + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// expand finds the indexed terms a query word matches and how well (1 = exact)
+func (idx *Index) expand(word string, prefix, fuzzy bool) map[string]float64 {
+	matches := map[string]float64{}
+	if _, ok := idx.postings[word]; ok {
+		matches[word] = 1
+	}
+
+	typos := 0
+	if fuzzy {
+		typos = maxTypos(word)
+	}
+	if !prefix && typos == 0 {
+		return matches
+	}
+
+	for term := range idx.postings {
+		if term == word {
+			continue
+		}
+		if prefix && len(word) >= 2 && strings.HasPrefix(term, word) {
+			matches[term] = math.Max(matches[term], 0.8)
+			continue
+		}
+		if typos > 0 {
+			if d := editDistance(word, term, typos); d <= typos {
+				matches[term] = math.Max(matches[term], 1-0.3*float64(d))
+			}
+		}
+	}
+	return matches
+}
+
+// Search runs a query and returns hits by descending relevance
+func (idx *Index) Search(query string, opts Options) []Hit {
+	words := Terms(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	allowedFields := map[string]bool{}
+	for _, field := range opts.Fields {
+		allowedFields[field] = true
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	fuzzy := len(words) <= shortQueryTerms
+	total := float64(len(idx.docs))
+
+	scores := map[int]float64{}
+	matchedWords := map[int]int{}
+	matchedTerms := map[int]map[string]map[string]bool{} // doc -> field -> term
+
+	for i, word := range words {
+		isLast := i == len(words)-1
+		seen := map[int]bool{}
+
+		for term, quality := range idx.expand(word, isLast, fuzzy) {
+			postings := idx.postings[term]
+			idf := math.Log(1 + total/float64(len(postings)))
+
+			for _, p := range postings {
+				if len(allowedFields) > 0 && !allowedFields[p.field] {
+					continue
+				}
+				weight, ok := idx.weights[p.field]
+				if !ok {
+					weight = 1
+				}
+				tf := float64(p.count) / float64(p.count+1)
+				scores[p.doc] += weight * idf * tf * quality
+
+				if matchedTerms[p.doc] == nil {
+					matchedTerms[p.doc] = map[string]map[string]bool{}
+				}
+				if matchedTerms[p.doc][p.field] == nil {
+					matchedTerms[p.doc][p.field] = map[string]bool{}
+				}
+				matchedTerms[p.doc][p.field][term] = true
+
+				if !seen[p.doc] {
+					seen[p.doc] = true
+					matchedWords[p.doc]++
+				}
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for docIndex, score := range scores {
+		doc := idx.docs[docIndex]
+		if opts.Allow != nil && !opts.Allow(doc) {
+			continue
+		}
+
+		// Documents matching every query word rank above partial matches
+		coverage := float64(matchedWords[docIndex]) / float64(len(words))
+		score *= coverage * coverage
+
+		hits = append(hits, Hit{
+			ID:         doc.ID,
+			Score:      math.Round(score*1000) / 1000,
+			Highlights: highlights(doc, matchedTerms[docIndex]),
+			Payload:    doc.Payload,
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	if opts.Limit > 0 && len(hits) > opts.Limit {
+		hits = hits[:opts.Limit]
+	}
+	return hits
+}
+
+// highlights builds one snippet per matched field, in a stable field order
+func highlights(doc Document, matched map[string]map[string]bool) []Highlight {
+	fields := make([]string, 0, len(matched))
+	for field := range matched {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	result := make([]Highlight, 0, len(fields))
+	for _, field := range fields {
+		if snippet := snippet(doc.Fields[field], matched[field]); snippet != "" {
+			result = append(result, Highlight{Field: field, Snippet: snippet})
+		}
+	}
+	return result
+}
+
+// snippet cuts a window around the first match and marks every match inside it
+func snippet(text string, terms map[string]bool) string {
+	tokens := tokenize(text)
+
+	first := -1
+	for i, t := range tokens {
+		if terms[t.term] {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	from := max(0, tokens[first].start-snippetRadius)
+	to := min(len(text), tokens[first].end+snippetRadius)
+	// Do not cut words in half
+	for from > 0 && !unicode.IsSpace(rune(text[from-1])) {
+		from--
+	}
+	for to < len(text) && !unicode.IsSpace(rune(text[to])) {
+		to++
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, t := range tokens {
+		if t.start < from || t.end > to || !terms[t.term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:t.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[t.start:t.end]))
+		b.WriteString("</mark>")
+		pos = t.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+This file is the in-memory prefix index behind typeahead suggestions. Every entry is indexed under its full text and under each later word, so "pony" suggests "Deftones - White Pony". Keys are kept sorted and looked up with a binary search, which keeps a lookup well under a millisecond for a catalog of this size.
+*/
+
+// Suggestion is one typeahead entry. Refs are the IDs of the records it
+// stands for (e.g. the albums of an artist) and are used to filter by access.
+type Suggestion struct {
+	Text string
+	Kind string
+	Refs []string
+}
+
+// prefixKey points from a normalized key to an entry
+type prefixKey struct {
+	key       string
+	entry     int
+	wholeText bool // key starts at the first word of the entry
+}
+
+// PrefixIndex is safe for concurrent use. Rebuild swaps the content atomically.
+type PrefixIndex struct {
+	mu      sync.RWMutex
+	entries []Suggestion
+	keys    []prefixKey
+}
+
+// maxScannedKeys bounds the work for very short prefixes
+const maxScannedKeys = 2000
+
+func NewPrefixIndex() *PrefixIndex {
+	return &PrefixIndex{}
+}
+
+// normalize lower cases text and reduces it to single spaced words
+func normalize(text string) string {
+	return strings.Join(Terms(text), " ")
+}
+
+// Rebuild replaces the whole index content. Entries with the same kind and
+// text are merged and their refs combined.
+func (p *PrefixIndex) Rebuild(suggestions []Suggestion) {
+	merged := map[string]int{}
+	var entries []Suggestion
+	for _, s := range suggestions {
+		id := s.Kind + "\x00" + normalize(s.Text)
+		if i, ok := merged[id]; ok {
+			entries[i].Refs = append(entries[i].Refs, s.Refs...)
+			continue
+		}
+		merged[id] = len(entries)
+		entries = append(entries, Suggestion{Text: s.Text, Kind: s.Kind, Refs: append([]string(nil), s.Refs...)})
+	}
+
+	var keys []prefixKey
+	for i, entry := range entries {
+		words := Terms(entry.Text)
+		for w := range words {
+			keys = append(keys, prefixKey{key: strings.Join(words[w:], " "), entry: i, wholeText: w == 0})
+		}
+	}
+	sort.Slice(keys, func(a, b int) bool { return keys[a].key < keys[b].key })
+
+	p.mu.Lock()
+	p.entries = entries
+	p.keys = keys
+	p.mu.Unlock()
+}
+
+// Suggest returns up to limit entries matching the prefix. Matches at the
+// start of the text rank first, then shorter texts. Allow may drop entries
+// the caller is not permitted to see.
+func (p *PrefixIndex) Suggest(prefix string, limit int, allow func(Suggestion) bool) []Suggestion {
+	needle := normalize(prefix)
+	if needle == "" {
+		return nil
+	}
+	// Keep a trailing space meaningful ("pink " should not match "pinkerton")
+	if strings.HasSuffix(prefix, " ") {
+		needle += " "
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	type candidate struct {
+		entry     int
+		wholeText bool
+	}
+	best := map[int]bool{} // entry -> matched from the start
+	var found []candidate
+
+	start := sort.Search(len(p.keys), func(i int) bool { return p.keys[i].key >= needle })
+	for i := start; i < len(p.keys) && i-start < maxScannedKeys; i++ {
+		k := p.keys[i]
+		if !strings.HasPrefix(k.key, needle) {
+			break
+		}
+		if whole, seen := best[k.entry]; seen {
+			if k.wholeText && !whole {
+				best[k.entry] = true
+			}
+			continue
+		}
+		best[k.entry] = k.wholeText
+		found = append(found, candidate{entry: k.entry})
+	}
+
+	for i := range found {
+		found[i].wholeText = best[found[i].entry]
+	}
+
+	sort.Slice(found, func(a, b int) bool {
+		ea, eb := p.entries[found[a].entry], p.entries[found[b].entry]
+		if found[a].wholeText != found[b].wholeText {
+			return found[a].wholeText
+		}
+		if len(ea.Text) != len(eb.Text) {
+			return len(ea.Text) < len(eb.Text)
+		}
+		return ea.Text < eb.Text
+	})
+
+	result := make([]Suggestion, 0, limit)
+	for _, c := range found {
+		entry := p.entries[c.entry]
+		if allow != nil && !allow(entry) {
+			continue
+		}
+		result = append(result, entry)
+		if len(result) == limit {
+			break
+		}
+	}
+	return result
+}
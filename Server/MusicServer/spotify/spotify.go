@@ -0,0 +1,310 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/*
+This file imports album metadata from the Spotify Web API. Music entries are keyed by Spotify album IDs, so the ID alone is enough to fill the title, artist, release year, track list and cover. The client uses the client credentials flow; both base URLs can be changed so a local stub server can stand in for Spotify.
+*/
+
+const (
+	DefaultAPIURL      = "https://api.spotify.com"
+	DefaultAccountsURL = "https://accounts.spotify.com"
+
+	requestTimeout = 10 * time.Second
+	maxTrackPages  = 20
+)
+
+var (
+	ErrInvalidID     = errors.New("not a valid Spotify album ID")
+	ErrAlbumNotFound = errors.New("album not found on Spotify")
+	ErrNotConfigured = errors.New("SPOTIFY_CLIENT_ID and SPOTIFY_CLIENT_SECRET are not set")
+	ErrRateLimited   = errors.New("Spotify rate limit reached")
+)
+
+// Album is what the importer knows about a Spotify album
+type Album struct {
+	ID          string
+	Title       string
+	Artist      string
+	ReleaseYear int
+	Tracks      []models.Track
+	CoverURL    string
+}
+
+// AlbumProvider looks up an album by its Spotify ID
+type AlbumProvider interface {
+	Album(ctx context.Context, id string) (Album, error)
+}
+
+var idPattern = regexp.MustCompile(`^[0-9A-Za-z]{22}$`)
+
+// Album links: spotify:album:<id> or https://open.spotify.com/album/<id>
+var linkPattern = regexp.MustCompile(`^(?:spotify:album:|(?:https?://)?open\.spotify\.com/(?:intl-[a-z-]+/)?album/)([0-9A-Za-z]{22})(?:[/?#].*)?$`)
+
+// ValidID reports whether id is a well formed Spotify ID
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// ParseID accepts a bare album ID, a spotify:album: URI or an open.spotify.com link
+func ParseID(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if ValidID(input) {
+		return input, nil
+	}
+	if match := linkPattern.FindStringSubmatch(input); match != nil {
+		return match[1], nil
+	}
+	return "", ErrInvalidID
+}
+
+// Client talks to the Spotify Web API
+type Client struct {
+	apiURL       string
+	accountsURL  string
+	clientID     string
+	clientSecret string
+	http         *http.Client
+
+	mu           sync.Mutex
+	token        string
+	tokenExpires time.Time
+}
+
+// NewClient uses the given base URLs (the Spotify ones when empty) and credentials
+func NewClient(apiURL, accountsURL, clientID, clientSecret string) *Client {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	if accountsURL == "" {
+		accountsURL = DefaultAccountsURL
+	}
+	return &Client{
+		apiURL:       strings.TrimRight(apiURL, "/"),
+		accountsURL:  strings.TrimRight(accountsURL, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		http:         &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// NewClientFromEnv reads SPOTIFY_CLIENT_ID, SPOTIFY_CLIENT_SECRET and the
+// optional SPOTIFY_API_URL and SPOTIFY_ACCOUNTS_URL
+func NewClientFromEnv() (*Client, error) {
+	clientID, clientSecret := os.Getenv("SPOTIFY_CLIENT_ID"), os.Getenv("SPOTIFY_CLIENT_SECRET")
+	if clientID == "" || clientSecret == "" {
+		return nil, ErrNotConfigured
+	}
+	return NewClient(os.Getenv("SPOTIFY_API_URL"), os.Getenv("SPOTIFY_ACCOUNTS_URL"), clientID, clientSecret), nil
+}
+
+// accessToken returns a cached app token, requesting a new one when it expires
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Before(c.tokenExpires) {
+		return c.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.accountsURL+"/api/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(c.clientID, c.clientSecret)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("spotify token request returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("spotify token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("spotify token response has no access_token")
+	}
+
+	// Renew a minute early so a token never expires mid-request
+	c.token = body.AccessToken
+	c.tokenExpires = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+// get fetches an API URL into target
+func (c *Client) get(ctx context.Context, rawURL string, target interface{}) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusBadRequest:
+		return ErrAlbumNotFound
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("%w, retry after %s seconds", ErrRateLimited, resp.Header.Get("Retry-After"))
+	case http.StatusUnauthorized:
+		// Token revoked early, ask for a new one next time
+		c.mu.Lock()
+		c.token = ""
+		c.mu.Unlock()
+		return errors.New("spotify rejected the access token")
+	default:
+		return fmt.Errorf("spotify returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(target); err != nil {
+		return fmt.Errorf("spotify response: %w", err)
+	}
+	return nil
+}
+
+type apiTrackPage struct {
+	Items []struct {
+		Name       string `json:"name"`
+		DurationMs int    `json:"duration_ms"`
+	} `json:"items"`
+	Next string `json:"next"`
+}
+
+type apiAlbum struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ReleaseDate string `json:"release_date"`
+	Artists     []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Images []struct {
+		URL   string `json:"url"`
+		Width int    `json:"width"`
+	} `json:"images"`
+	Tracks apiTrackPage `json:"tracks"`
+}
+
+// Album fetches an album and its full track list
+func (c *Client) Album(ctx context.Context, id string) (Album, error) {
+	if !ValidID(id) {
+		return Album{}, ErrInvalidID
+	}
+
+	var raw apiAlbum
+	if err := c.get(ctx, c.apiURL+"/v1/albums/"+id, &raw); err != nil {
+		return Album{}, err
+	}
+
+	album := Album{ID: raw.ID, Title: raw.Name}
+	if album.ID == "" {
+		album.ID = id
+	}
+
+	artists := make([]string, 0, len(raw.Artists))
+	for _, artist := range raw.Artists {
+		artists = append(artists, artist.Name)
+	}
+	album.Artist = strings.Join(artists, ", ")
+
+	// release_date is "2001", "2001-03" or "2001-03-13"
+	if len(raw.ReleaseDate) >= 4 {
+		album.ReleaseYear, _ = strconv.Atoi(raw.ReleaseDate[:4])
+	}
+
+	// Keep the widest image
+	best := 0
+	for _, image := range raw.Images {
+		if album.CoverURL == "" || image.Width > best {
+			album.CoverURL, best = image.URL, image.Width
+		}
+	}
+
+	// Long albums page their tracks
+	page := raw.Tracks
+	for pages := 0; ; pages++ {
+		for _, item := range page.Items {
+			album.Tracks = append(album.Tracks, models.Track{Number: len(album.Tracks) + 1, Title: item.Name, DurationMs: item.DurationMs})
+		}
+		if page.Next == "" || pages >= maxTrackPages {
+			break
+		}
+		// The token must not be sent anywhere but the API
+		next := page.Next
+		if !strings.HasPrefix(next, c.apiURL+"/") {
+			return Album{}, fmt.Errorf("spotify track page outside the API: %s", next)
+		}
+		page = apiTrackPage{}
+		if err := c.get(ctx, next, &page); err != nil {
+			return Album{}, err
+		}
+	}
+
+	return album, nil
+}
+
+// Fill copies an album's metadata onto a music entry and returns the
+// changed fields for a $set. Fields that already have a value are kept
+// unless overwrite is set.
+func Fill(music *models.Music, album Album, overwrite bool) bson.M {
+	changes := bson.M{}
+
+	if album.Title != "" && (overwrite || music.Title == "") {
+		music.Title = album.Title
+		changes["title"] = music.Title
+	}
+	if album.CoverURL != "" && (overwrite || music.AlbumImg == "") {
+		music.AlbumImg = album.CoverURL
+		changes["album_img"] = music.AlbumImg
+	}
+	if album.Artist != "" && (overwrite || music.Artist == "") {
+		music.Artist = album.Artist
+		changes["artist"] = music.Artist
+	}
+	if album.ReleaseYear > 0 && (overwrite || music.ReleaseYear == 0) {
+		music.ReleaseYear = album.ReleaseYear
+		changes["release_year"] = music.ReleaseYear
+	}
+	if len(album.Tracks) > 0 && (overwrite || len(music.Tracks) == 0) {
+		music.Tracks = album.Tracks
+		changes["tracks"] = music.Tracks
+	}
+	return changes
+}
@@ -0,0 +1,227 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+/* This file runs the client against a local stub of the Spotify accounts and Web API endpoints: token caching, track paging, the guard on where the token is sent and how error statuses are mapped. */
+
+const testAlbumID = "4aawyAB9vmqN3uQ7FjRGTy"
+
+// stub is a fake Spotify; albums answers /v1/albums/... requests
+type stub struct {
+	server     *httptest.Server
+	tokenCalls atomic.Int32
+	albumCalls atomic.Int32
+	lastAuth   atomic.Value
+	albums     func(w http.ResponseWriter, r *http.Request, baseURL string)
+}
+
+func newStub(t *testing.T, albums func(w http.ResponseWriter, r *http.Request, baseURL string)) *stub {
+	s := &stub{albums: albums}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/token":
+			s.tokenCalls.Add(1)
+			if id, secret, ok := r.BasicAuth(); !ok || id != "id" || secret != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.FormValue("grant_type") != "client_credentials" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token-1", "expires_in": 3600})
+		case strings.HasPrefix(r.URL.Path, "/v1/albums/"):
+			s.albumCalls.Add(1)
+			s.lastAuth.Store(r.Header.Get("Authorization"))
+			s.albums(w, r, s.server.URL)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *stub) client() *Client {
+	return NewClient(s.server.URL, s.server.URL, "id", "secret")
+}
+
+func writeAlbum(w http.ResponseWriter, tracks map[string]interface{}) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":           testAlbumID,
+		"name":         "White Pony",
+		"release_date": "2000-06-20",
+		"artists":      []map[string]string{{"name": "Deftones"}},
+		"images":       []map[string]interface{}{{"url": "small.jpg", "width": 64}, {"url": "large.jpg", "width": 640}},
+		"tracks":       tracks,
+	})
+}
+
+func TestAlbumCachesToken(t *testing.T) {
+	s := newStub(t, func(w http.ResponseWriter, r *http.Request, baseURL string) {
+		writeAlbum(w, map[string]interface{}{"items": []map[string]interface{}{{"name": "Feiticeira", "duration_ms": 189000}}})
+	})
+	client := s.client()
+
+	for i := 0; i < 3; i++ {
+		album, err := client.Album(context.Background(), testAlbumID)
+		if err != nil {
+			t.Fatalf("Album: %v", err)
+		}
+		if album.Title != "White Pony" || album.Artist != "Deftones" || album.ReleaseYear != 2000 || album.CoverURL != "large.jpg" {
+			t.Errorf("album = %+v", album)
+		}
+	}
+
+	if calls := s.tokenCalls.Load(); calls != 1 {
+		t.Errorf("token requested %d times, want 1", calls)
+	}
+	if auth := s.lastAuth.Load(); auth != "Bearer token-1" {
+		t.Errorf("Authorization = %v", auth)
+	}
+}
+
+func TestAlbumRenewsRejectedToken(t *testing.T) {
+	var rejected atomic.Bool
+	s := newStub(t, func(w http.ResponseWriter, r *http.Request, baseURL string) {
+		if !rejected.Swap(true) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeAlbum(w, map[string]interface{}{})
+	})
+	client := s.client()
+
+	if _, err := client.Album(context.Background(), testAlbumID); err == nil {
+		t.Fatal("Album with a rejected token succeeded")
+	}
+	if _, err := client.Album(context.Background(), testAlbumID); err != nil {
+		t.Fatalf("Album after renewing the token: %v", err)
+	}
+	if calls := s.tokenCalls.Load(); calls != 2 {
+		t.Errorf("token requested %d times, want 2", calls)
+	}
+}
+
+func TestAlbumPagesTracks(t *testing.T) {
+	s := newStub(t, func(w http.ResponseWriter, r *http.Request, baseURL string) {
+		switch r.URL.Path {
+		case "/v1/albums/" + testAlbumID:
+			writeAlbum(w, map[string]interface{}{
+				"items": []map[string]interface{}{{"name": "Feiticeira"}, {"name": "Digital Bath"}},
+				"next":  baseURL + "/v1/albums/" + testAlbumID + "/tracks?offset=2",
+			})
+		case "/v1/albums/" + testAlbumID + "/tracks":
+			if r.URL.Query().Get("offset") == "2" {
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"items": []map[string]interface{}{{"name": "Elite"}},
+					"next":  baseURL + "/v1/albums/" + testAlbumID + "/tracks?offset=3",
+				})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": []map[string]interface{}{{"name": "Rx Queen"}}})
+		}
+	})
+
+	album, err := s.client().Album(context.Background(), testAlbumID)
+	if err != nil {
+		t.Fatalf("Album: %v", err)
+	}
+
+	want := []string{"Feiticeira", "Digital Bath", "Elite", "Rx Queen"}
+	if len(album.Tracks) != len(want) {
+		t.Fatalf("got %d tracks, want %d", len(album.Tracks), len(want))
+	}
+	for i, track := range album.Tracks {
+		if track.Title != want[i] || track.Number != i+1 {
+			t.Errorf("track %d = %d %q, want %d %q", i, track.Number, track.Title, i+1, want[i])
+		}
+	}
+	if calls := s.albumCalls.Load(); calls != 3 {
+		t.Errorf("API called %d times, want 3", calls)
+	}
+}
+
+func TestAlbumRefusesPageOutsideAPI(t *testing.T) {
+	var elsewhere atomic.Int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		elsewhere.Add(1)
+	}))
+	defer other.Close()
+
+	s := newStub(t, func(w http.ResponseWriter, r *http.Request, baseURL string) {
+		writeAlbum(w, map[string]interface{}{
+			"items": []map[string]interface{}{{"name": "Feiticeira"}},
+			"next":  other.URL + "/v1/albums/" + testAlbumID + "/tracks?offset=1",
+		})
+	})
+
+	if _, err := s.client().Album(context.Background(), testAlbumID); err == nil || !strings.Contains(err.Error(), "outside the API") {
+		t.Errorf("Album = %v, want a page outside the API error", err)
+	}
+	if calls := elsewhere.Load(); calls != 0 {
+		t.Errorf("the other host got %d requests", calls)
+	}
+}
+
+func TestAlbumErrorStatuses(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+	}{
+		{http.StatusNotFound, ErrAlbumNotFound},
+		{http.StatusBadRequest, ErrAlbumNotFound},
+		{http.StatusTooManyRequests, ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		s := newStub(t, func(w http.ResponseWriter, r *http.Request, baseURL string) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(tt.status)
+		})
+
+		_, err := s.client().Album(context.Background(), testAlbumID)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("status %d: Album = %v, want %v", tt.status, err, tt.err)
+		}
+		if tt.status == http.StatusTooManyRequests && !strings.Contains(err.Error(), "30") {
+			t.Errorf("rate limit error %q does not name the Retry-After delay", err)
+		}
+	}
+}
+
+func TestAlbumInvalidID(t *testing.T) {
+	s := newStub(t, func(w http.ResponseWriter, r *http.Request, baseURL string) {})
+
+	if _, err := s.client().Album(context.Background(), "not-an-id"); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("Album = %v, want ErrInvalidID", err)
+	}
+	if calls := s.tokenCalls.Load() + s.albumCalls.Load(); calls != 0 {
+		t.Errorf("stub got %d requests for an invalid ID", calls)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	for _, input := range []string{
+		testAlbumID,
+		"spotify:album:" + testAlbumID,
+		"https://open.spotify.com/album/" + testAlbumID + "?si=abc",
+		"open.spotify.com/intl-de/album/" + testAlbumID,
+	} {
+		if id, err := ParseID(input); err != nil || id != testAlbumID {
+			t.Errorf("ParseID(%q) = %q, %v", input, id, err)
+		}
+	}
+	if _, err := ParseID("https://open.spotify.com/track/" + testAlbumID); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("ParseID of a track link = %v, want ErrInvalidID", err)
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/*
+This file writes entries to the audit trail (audit_logs collection) so admin actions on user data can be reviewed later.
+*/
+
+// WriteAuditLog records an action performed by actorId on a target record
+func WriteAuditLog(ctx context.Context, actorId, action, targetType, targetId string, details bson.M, client *mongo.Client) error {
+	entry := models.AuditLog{
+		Action:     action,
+		ActorID:    actorId,
+		TargetType: targetType,
+		TargetID:   targetId,
+		Details:    details,
+		CreatedAt:  time.Now(),
+	}
+
+	var auditCollection *mongo.Collection = database.OpenCollection("audit_logs", client)
+
+	_, err := auditCollection.InsertOne(ctx, entry)
+	return err
+}
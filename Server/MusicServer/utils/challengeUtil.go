@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+/*
+This file keeps track of MFA challenges. The challenge token only carries the challenge ID, so every code tried with it is counted here, the challenge locks after MaxMFAAttempts codes, and it is used up once the second factor is accepted so the token cannot be replayed.
+*/
+
+// CreateMFAChallenge stores a new challenge for the user and returns its ID
+func CreateMFAChallenge(c *gin.Context, userId, purpose string, client *mongo.Client) (string, error) {
+	var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	challenge := models.MFAChallenge{
+		ChallengeID: bson.NewObjectID().Hex(),
+		UserID:      userId,
+		Purpose:     purpose,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(mfaChallengeExpiration),
+	}
+
+	var challengeCollection *mongo.Collection = database.OpenCollection("mfa_challenges", client)
+
+	if _, err := challengeCollection.InsertOne(ctx, challenge); err != nil {
+		return "", err
+	}
+	return challenge.ChallengeID, nil
+}
+
+// openChallengeFilter matches a challenge of the user that is neither used,
+// locked nor expired
+func openChallengeFilter(challengeId, userId string) bson.M {
+	return bson.M{
+		"challenge_id": challengeId,
+		"user_id":      userId,
+		"used_at":      bson.M{"$exists": false},
+		"attempts":     bson.M{"$lt": MaxMFAAttempts},
+		"expires_at":   bson.M{"$gt": time.Now()},
+	}
+}
+
+// IsMFAChallengeOpen reports whether codes may still be tried with the challenge
+func IsMFAChallengeOpen(ctx context.Context, challengeId, userId string, client *mongo.Client) (bool, error) {
+	var challengeCollection *mongo.Collection = database.OpenCollection("mfa_challenges", client)
+
+	count, err := challengeCollection.CountDocuments(ctx, openChallengeFilter(challengeId, userId))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ReserveMFAAttempt counts one code against the challenge. It reports false
+// when the challenge is used, locked or expired.
+func ReserveMFAAttempt(ctx context.Context, challengeId, userId string, client *mongo.Client) (bool, error) {
+	var challengeCollection *mongo.Collection = database.OpenCollection("mfa_challenges", client)
+
+	update := bson.M{"$inc": bson.M{"attempts": 1}}
+	result, err := challengeCollection.UpdateOne(ctx, openChallengeFilter(challengeId, userId), update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// ConsumeMFAChallenge uses the challenge up. It reports false when another
+// request used it first.
+func ConsumeMFAChallenge(ctx context.Context, challengeId string, client *mongo.Client) (bool, error) {
+	var challengeCollection *mongo.Collection = database.OpenCollection("mfa_challenges", client)
+
+	filter := bson.M{"challenge_id": challengeId, "used_at": bson.M{"$exists": false}}
+	result, err := challengeCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"used_at": time.Now()}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/database"
+	"github.com/omicreativedev/TunePeep/Server/MusicServer/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+/*
+This file manages login sessions. Each login creates a session document whose ID is embedded in the JWT tokens, so the middleware can reject tokens from sessions that were revoked (logout, password change) before they expire.
+*/
+
+// CreateSession stores a new session for the user and returns its ID
+func CreateSession(c *gin.Context, userId string, client *mongo.Client) (string, error) {
+	var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	session := models.Session{
+		SessionID:  bson.NewObjectID().Hex(),
+		UserID:     userId,
+		UserAgent:  c.Request.UserAgent(),
+		IPAddress:  c.ClientIP(),
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshTokenExpiration),
+	}
+
+	var sessionCollection *mongo.Collection = database.OpenCollection("sessions", client)
+
+	if _, err := sessionCollection.InsertOne(ctx, session); err != nil {
+		return "", err
+	}
+	return session.SessionID, nil
+}
+
+// activeSessionFilter matches a session of the user that is neither revoked nor expired
+func activeSessionFilter(sessionId, userId string) bson.M {
+	return bson.M{
+		"session_id": sessionId,
+		"user_id":    userId,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+}
+
+// IsSessionActive reports whether the session can still be used
+func IsSessionActive(ctx context.Context, sessionId, userId string, client *mongo.Client) (bool, error) {
+	if sessionId == "" {
+		return false, nil
+	}
+
+	var sessionCollection *mongo.Collection = database.OpenCollection("sessions", client)
+
+	count, err := sessionCollection.CountDocuments(ctx, activeSessionFilter(sessionId, userId))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// AccountStatus is what the middleware needs to know about a user on each request
+type AccountStatus struct {
+	Disabled bool     `bson:"disabled"`
+	Groups   []string `bson:"groups"`
+}
+
+// GetAccountStatus loads whether the account is disabled and the access
+// groups it belongs to. Group changes therefore apply on the next request.
+func GetAccountStatus(ctx context.Context, userId string, client *mongo.Client) (AccountStatus, error) {
+	var status AccountStatus
+
+	var userCollection *mongo.Collection = database.OpenCollection("users", client)
+
+	findOptions := options.FindOne().SetProjection(bson.M{"disabled": 1, "groups": 1})
+	err := userCollection.FindOne(ctx, bson.M{"user_id": userId}, findOptions).Decode(&status)
+	return status, err
+}
+
+// ExtendSession marks the session as used and pushes its expiry forward (token refresh)
+func ExtendSession(ctx context.Context, sessionId, userId string, client *mongo.Client) (bool, error) {
+	var sessionCollection *mongo.Collection = database.OpenCollection("sessions", client)
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"last_used_at": now, "expires_at": now.Add(refreshTokenExpiration)}}
+
+	result, err := sessionCollection.UpdateOne(ctx, activeSessionFilter(sessionId, userId), update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// RevokeSession revokes a single session
+func RevokeSession(ctx context.Context, sessionId string, client *mongo.Client) error {
+	var sessionCollection *mongo.Collection = database.OpenCollection("sessions", client)
+
+	filter := bson.M{"session_id": sessionId, "revoked_at": bson.M{"$exists": false}}
+	_, err := sessionCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	return err
+}
+
+// RevokeUserSessions revokes every session of the user except the one given (may be empty)
+func RevokeUserSessions(ctx context.Context, userId, exceptSessionId string, client *mongo.Client) (int64, error) {
+	var sessionCollection *mongo.Collection = database.OpenCollection("sessions", client)
+
+	filter := bson.M{"user_id": userId, "revoked_at": bson.M{"$exists": false}}
+	if exceptSessionId != "" {
+		filter["session_id"] = bson.M{"$ne": exceptSessionId}
+	}
+
+	result, err := sessionCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -24,6 +24,7 @@ type SignedDetails struct {
 	LastName  string
 	Role      string
 	UserId    string
+	SessionId string
 	jwt.RegisteredClaims
 }
 
@@ -35,11 +36,33 @@ var SECRET_REFRESH_KEY string = os.Getenv("SECRET_REFRESH_KEY")
 const (
 	accessTokenExpiration  = 24 * time.Hour
 	refreshTokenExpiration = 24 * 7 * time.Hour
+	mfaChallengeExpiration = 5 * time.Minute
 	issuerName         = "TunePeep"
 )
 
+// Purposes of an MFA challenge token
+const (
+	MFAPurposeVerify = "verify" // user has MFA and must enter a code
+	MFAPurposeEnroll = "enroll" // user must enroll before tokens are issued (ADMIN)
+)
+
+// MFA challenge claims structure. Issued after a correct password and
+// exchanged for the real tokens once the second factor is checked.
+type MFAChallengeDetails struct {
+	UserId  string
+	Purpose string
+	jwt.RegisteredClaims
+}
+
+// Share link claims structure. A share token only names the link; what it
+// grants, how often it may be used and whether it was revoked live in the database.
+type ShareLinkDetails struct {
+	ShareId string
+	jwt.RegisteredClaims
+}
+
 // createToken generates a single JWT token
-func createToken(email, firstName, lastName, role, userId string, 
+func createToken(email, firstName, lastName, role, userId, sessionId string,
                  expiration time.Duration, secret string) (string, error) {
 	claims := &SignedDetails{
 		Email:     email,
@@ -47,6 +70,7 @@ func createToken(email, firstName, lastName, role, userId string,
 		LastName:  lastName,
 		Role:      role,
 		UserId:    userId,
+		SessionId: sessionId,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    issuerName,
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -58,17 +82,17 @@ func createToken(email, firstName, lastName, role, userId string,
 	return token.SignedString([]byte(secret))
 }
 
-// GenerateAllTokens creates new access and refresh tokens
-func GenerateAllTokens(email, firstName, lastName, role, userId string) (string, string, error) {
+// GenerateAllTokens creates new access and refresh tokens bound to a session
+func GenerateAllTokens(email, firstName, lastName, role, userId, sessionId string) (string, string, error) {
 	// Create access token
-	signedToken, err := createToken(email, firstName, lastName, role, userId, 
+	signedToken, err := createToken(email, firstName, lastName, role, userId, sessionId,
 		accessTokenExpiration, SECRET_KEY)
 	if err != nil {
 		return "", "", err
 	}
 	
 	// Create refresh token
-	signedRefreshToken, err := createToken(email, firstName, lastName, role, userId,
+	signedRefreshToken, err := createToken(email, firstName, lastName, role, userId, sessionId,
 		refreshTokenExpiration, SECRET_REFRESH_KEY)
 	if err != nil {
 		return "", "", err
@@ -178,7 +202,119 @@ func GetRoleFromContext(c *gin.Context) (string, error) {
 	return GetFromContext(c, "role")
 }
 
+// GetSessionIdFromContext gets sessionId from Gin context
+func GetSessionIdFromContext(c *gin.Context) (string, error) {
+	return GetFromContext(c, "sessionId")
+}
+
+// GetGroupsFromContext gets the caller's access groups from Gin context
+func GetGroupsFromContext(c *gin.Context) []string {
+	value, exists := c.Get("groups")
+	if !exists {
+		return nil
+	}
+	groups, _ := value.([]string)
+	return groups
+}
+
 // Verify refresh token signature expiration
 func ValidateRefreshToken(tokenString string) (*SignedDetails, error) {
 	return validateTokenHelper(tokenString, SECRET_REFRESH_KEY)
-}
\ No newline at end of file
+}
+
+// Challenge tokens are signed with a key derived from SECRET_KEY so they
+// can never pass ValidateToken as an access token
+func mfaChallengeKey() []byte {
+	return []byte(SECRET_KEY + "|mfa-challenge")
+}
+
+// GenerateMFAChallengeToken creates a short-lived token for the second login
+// step. Its ID names a stored challenge that limits the attempts and is used
+// up on success.
+func GenerateMFAChallengeToken(c *gin.Context, userId, purpose string, client *mongo.Client) (string, error) {
+	challengeId, err := CreateMFAChallenge(c, userId, purpose, client)
+	if err != nil {
+		return "", err
+	}
+
+	claims := &MFAChallengeDetails{
+		UserId:  userId,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        challengeId,
+			Issuer:    issuerName,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(mfaChallengeExpiration)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(mfaChallengeKey())
+}
+
+// ValidateMFAChallengeToken verifies a challenge token and its purpose
+func ValidateMFAChallengeToken(tokenString, purpose string) (*MFAChallengeDetails, error) {
+	claims := &MFAChallengeDetails{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return mfaChallengeKey(), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, errors.New("invalid signing method")
+	}
+
+	if claims.Purpose != purpose {
+		return nil, errors.New("invalid challenge purpose")
+	}
+
+	if claims.ID == "" {
+		return nil, errors.New("missing challenge ID")
+	}
+
+	return claims, nil
+}
+
+// Share tokens use their own derived key so they can never pass as an
+// access or challenge token
+func shareLinkKey() []byte {
+	return []byte(SECRET_KEY + "|share-link")
+}
+
+// GenerateShareToken signs the token for a share link. The claims are fixed
+// per link, so the same link always gets the same token.
+func GenerateShareToken(shareId string, expiresAt time.Time) (string, error) {
+	claims := &ShareLinkDetails{
+		ShareId: shareId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuerName,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(shareLinkKey())
+}
+
+// ValidateShareToken verifies a share token's signature and expiry and returns the link ID
+func ValidateShareToken(tokenString string) (string, error) {
+	claims := &ShareLinkDetails{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return shareLinkKey(), nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return "", errors.New("invalid signing method")
+	}
+
+	return claims.ShareId, nil
+}
@@ -0,0 +1,102 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+/*
+This file defines how video metadata is looked up. A MetadataProvider returns the title, channel and thumbnail of a video; the oEmbed provider asks YouTube, the fake provider answers offline. CachedProvider wraps either one so the same video is not fetched again while an admin edits a draft.
+*/
+
+// Metadata is what a provider knows about a video
+type Metadata struct {
+	VideoID      string `json:"video_id"`
+	Title        string `json:"title"`
+	Author       string `json:"author"`
+	AuthorURL    string `json:"author_url,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	Provider     string `json:"provider"`
+}
+
+var (
+	ErrVideoNotFound      = errors.New("video not found")
+	ErrVideoNotEmbeddable = errors.New("video is private or cannot be embedded")
+)
+
+// MetadataProvider looks up a video by its 11 character ID
+type MetadataProvider interface {
+	Lookup(ctx context.Context, videoID string) (Metadata, error)
+}
+
+type cacheEntry struct {
+	metadata  Metadata
+	err       error
+	expiresAt time.Time
+}
+
+// CachedProvider remembers answers from another provider for a while.
+// Videos that do not exist are remembered too, failures are not.
+type CachedProvider struct {
+	provider   MetadataProvider
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewCachedProvider wraps provider with a cache of up to maxEntries videos
+func NewCachedProvider(provider MetadataProvider, ttl time.Duration, maxEntries int) *CachedProvider {
+	return &CachedProvider{
+		provider:   provider,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]cacheEntry),
+	}
+}
+
+func (p *CachedProvider) Lookup(ctx context.Context, videoID string) (Metadata, error) {
+	now := time.Now()
+
+	p.mu.Lock()
+	entry, ok := p.entries[videoID]
+	p.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.metadata, entry.err
+	}
+
+	metadata, err := p.provider.Lookup(ctx, videoID)
+	if err != nil && !errors.Is(err, ErrVideoNotFound) && !errors.Is(err, ErrVideoNotEmbeddable) {
+		return metadata, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.entries) >= p.maxEntries {
+		p.evict(now)
+	}
+	p.entries[videoID] = cacheEntry{metadata: metadata, err: err, expiresAt: now.Add(p.ttl)}
+
+	return metadata, err
+}
+
+// evict drops expired entries, or the one closest to expiry when none are
+func (p *CachedProvider) evict(now time.Time) {
+	oldest := ""
+	for id, entry := range p.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(p.entries, id)
+			continue
+		}
+		if oldest == "" || entry.expiresAt.Before(p.entries[oldest].expiresAt) {
+			oldest = id
+		}
+	}
+	if len(p.entries) >= p.maxEntries && oldest != "" {
+		delete(p.entries, oldest)
+	}
+}
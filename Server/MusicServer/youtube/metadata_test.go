@@ -0,0 +1,128 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+/* This file checks what CachedProvider remembers: answers and missing videos until they expire, never failures, and no more than maxEntries videos. */
+
+// countingProvider answers from a map of errors and counts the lookups per video
+type countingProvider struct {
+	errs  map[string]error
+	calls map[string]int
+}
+
+func newCountingProvider() *countingProvider {
+	return &countingProvider{errs: map[string]error{}, calls: map[string]int{}}
+}
+
+func (p *countingProvider) Lookup(ctx context.Context, videoID string) (Metadata, error) {
+	p.calls[videoID]++
+	if err := p.errs[videoID]; err != nil {
+		return Metadata{}, err
+	}
+	return Metadata{VideoID: videoID, Title: "Title " + videoID, Provider: "counting"}, nil
+}
+
+func TestCachedProviderHit(t *testing.T) {
+	provider := newCountingProvider()
+	cached := NewCachedProvider(provider, time.Hour, 10)
+
+	for i := 0; i < 3; i++ {
+		metadata, err := cached.Lookup(context.Background(), "dQw4w9WgXcQ")
+		if err != nil {
+			t.Fatalf("Lookup: %v", err)
+		}
+		if metadata.Title != "Title dQw4w9WgXcQ" {
+			t.Errorf("Title = %q", metadata.Title)
+		}
+	}
+	if calls := provider.calls["dQw4w9WgXcQ"]; calls != 1 {
+		t.Errorf("provider called %d times, want 1", calls)
+	}
+}
+
+func TestCachedProviderExpiry(t *testing.T) {
+	provider := newCountingProvider()
+	cached := NewCachedProvider(provider, 10*time.Millisecond, 10)
+
+	cached.Lookup(context.Background(), "dQw4w9WgXcQ")
+	time.Sleep(20 * time.Millisecond)
+	cached.Lookup(context.Background(), "dQw4w9WgXcQ")
+
+	if calls := provider.calls["dQw4w9WgXcQ"]; calls != 2 {
+		t.Errorf("provider called %d times after expiry, want 2", calls)
+	}
+}
+
+func TestCachedProviderErrors(t *testing.T) {
+	errUnavailable := errors.New("service unavailable")
+
+	provider := newCountingProvider()
+	provider.errs["missing0000"] = ErrVideoNotFound
+	provider.errs["private0000"] = ErrVideoNotEmbeddable
+	provider.errs["failing0000"] = errUnavailable
+	cached := NewCachedProvider(provider, time.Hour, 10)
+
+	tests := []struct {
+		videoID string
+		err     error
+		calls   int
+	}{
+		{"missing0000", ErrVideoNotFound, 1},
+		{"private0000", ErrVideoNotEmbeddable, 1},
+		{"failing0000", errUnavailable, 2},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 2; i++ {
+			if _, err := cached.Lookup(context.Background(), tt.videoID); !errors.Is(err, tt.err) {
+				t.Errorf("Lookup(%s) = %v, want %v", tt.videoID, err, tt.err)
+			}
+		}
+		if calls := provider.calls[tt.videoID]; calls != tt.calls {
+			t.Errorf("provider called %d times for %s, want %d", calls, tt.videoID, tt.calls)
+		}
+	}
+}
+
+func TestCachedProviderEviction(t *testing.T) {
+	provider := newCountingProvider()
+	cached := NewCachedProvider(provider, time.Hour, 2)
+
+	// The first video is the closest to expiry when the third one arrives
+	for _, id := range []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"} {
+		cached.Lookup(context.Background(), id)
+	}
+	if len(cached.entries) != 2 {
+		t.Fatalf("cache holds %d entries, want 2", len(cached.entries))
+	}
+	if _, ok := cached.entries["aaaaaaaaaaa"]; ok {
+		t.Error("oldest entry was not evicted")
+	}
+
+	cached.Lookup(context.Background(), "ccccccccccc")
+	cached.Lookup(context.Background(), "aaaaaaaaaaa")
+	if calls := provider.calls["ccccccccccc"]; calls != 1 {
+		t.Errorf("provider called %d times for a cached video, want 1", calls)
+	}
+	if calls := provider.calls["aaaaaaaaaaa"]; calls != 2 {
+		t.Errorf("provider called %d times for an evicted video, want 2", calls)
+	}
+}
+
+func TestCachedProviderEvictsExpiredFirst(t *testing.T) {
+	provider := newCountingProvider()
+	cached := NewCachedProvider(provider, 10*time.Millisecond, 2)
+
+	cached.Lookup(context.Background(), "aaaaaaaaaaa")
+	cached.Lookup(context.Background(), "bbbbbbbbbbb")
+	time.Sleep(20 * time.Millisecond)
+	cached.Lookup(context.Background(), "ccccccccccc")
+
+	if len(cached.entries) != 1 {
+		t.Errorf("cache holds %d entries after dropping expired ones, want 1", len(cached.entries))
+	}
+}
@@ -0,0 +1,108 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+/*
+This file is the oEmbed metadata provider. YouTube answers https://www.youtube.com/oembed without an API key, which is all a draft needs. The fake provider next to it returns made-up metadata so the preview flow works offline and in development.
+*/
+
+const DefaultOEmbedURL = "https://www.youtube.com/oembed"
+
+// OEmbedProvider looks videos up through YouTube's oEmbed endpoint
+type OEmbedProvider struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewOEmbedProvider uses endpoint (DefaultOEmbedURL when empty) and gives up
+// on a request after timeout
+func NewOEmbedProvider(endpoint string, timeout time.Duration) *OEmbedProvider {
+	if endpoint == "" {
+		endpoint = DefaultOEmbedURL
+	}
+	return &OEmbedProvider{endpoint: endpoint, client: &http.Client{Timeout: timeout}}
+}
+
+type oEmbedResponse struct {
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	AuthorURL    string `json:"author_url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+func (p *OEmbedProvider) Lookup(ctx context.Context, videoID string) (Metadata, error) {
+	if !ValidID(videoID) {
+		return Metadata{}, ErrInvalidID
+	}
+
+	query := url.Values{}
+	query.Set("url", "https://www.youtube.com/watch?v="+videoID)
+	query.Set("format", "json")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusBadRequest:
+		return Metadata{}, ErrVideoNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return Metadata{}, ErrVideoNotEmbeddable
+	default:
+		return Metadata{}, fmt.Errorf("oembed returned status %d", resp.StatusCode)
+	}
+
+	var body oEmbedResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return Metadata{}, fmt.Errorf("oembed response: %w", err)
+	}
+
+	return Metadata{
+		VideoID:      videoID,
+		Title:        body.Title,
+		Author:       body.AuthorName,
+		AuthorURL:    body.AuthorURL,
+		ThumbnailURL: body.ThumbnailURL,
+		Provider:     "oembed",
+	}, nil
+}
+
+// FakeProvider answers without any network access. IDs listed in Missing
+// are reported as not found.
+type FakeProvider struct {
+	Missing map[string]bool
+}
+
+func (p FakeProvider) Lookup(ctx context.Context, videoID string) (Metadata, error) {
+	if !ValidID(videoID) {
+		return Metadata{}, ErrInvalidID
+	}
+	if p.Missing[videoID] {
+		return Metadata{}, ErrVideoNotFound
+	}
+
+	return Metadata{
+		VideoID:      videoID,
+		Title:        "Sample Album " + videoID,
+		Author:       "Sample Artist",
+		AuthorURL:    "https://www.youtube.com/@sampleartist",
+		ThumbnailURL: "https://i.ytimg.com/vi/" + videoID + "/hqdefault.jpg",
+		Provider:     "fake",
+	}, nil
+}
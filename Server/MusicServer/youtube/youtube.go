@@ -0,0 +1,152 @@
+package youtube
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+This file turns whatever an admin pastes as a YouTube reference into the bare 11 character video ID the player needs. It understands plain IDs and the common URL forms (watch, youtu.be, shorts, embed, live, music.youtube.com and youtube-nocookie.com) and keeps any start or end timestamp as separate values in seconds.
+*/
+
+// Video is a parsed YouTube reference. Start and End are in seconds, 0 when not set.
+type Video struct {
+	ID    string
+	Start int
+	End   int
+}
+
+var (
+	ErrInvalidID   = errors.New("not a valid YouTube video ID")
+	ErrInvalidURL  = errors.New("not a recognised YouTube URL")
+	ErrInvalidTime = errors.New("invalid YouTube timestamp")
+)
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// Durations like 90, 90s, 1m30s or 1h2m3s
+var durationPattern = regexp.MustCompile(`^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s?)?$`)
+
+// Hosts that serve videos under /watch?v= or a path prefix
+var youtubeHosts = map[string]bool{
+	"youtube.com":              true,
+	"www.youtube.com":          true,
+	"m.youtube.com":            true,
+	"music.youtube.com":        true,
+	"youtube-nocookie.com":     true,
+	"www.youtube-nocookie.com": true,
+}
+
+// Path prefixes followed by the video ID
+var pathPrefixes = []string{"/shorts/", "/embed/", "/live/", "/v/", "/e/"}
+
+// ValidID reports whether id is a well formed 11 character video ID
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// ParseTime reads a timestamp such as "90", "90s" or "1m30s" into seconds
+func ParseTime(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+
+	match := durationPattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, ErrInvalidTime
+	}
+
+	seconds := 0
+	for i, unit := range []int{3600, 60, 1} {
+		if match[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return 0, ErrInvalidTime
+		}
+		seconds += n * unit
+	}
+	return seconds, nil
+}
+
+// Parse accepts a bare video ID or a YouTube URL and returns the video ID
+// with any start and end timestamps
+func Parse(input string) (Video, error) {
+	input = strings.TrimSpace(input)
+	if ValidID(input) {
+		return Video{ID: input}, nil
+	}
+
+	// Allow URLs pasted without a scheme, e.g. "youtu.be/abc"
+	raw := input
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return Video{}, ErrInvalidURL
+	}
+
+	host := strings.ToLower(u.Hostname())
+	query := u.Query()
+
+	var id string
+	switch {
+	case host == "youtu.be" || host == "www.youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case youtubeHosts[host]:
+		if u.Path == "/watch" {
+			id = query.Get("v")
+			break
+		}
+		for _, prefix := range pathPrefixes {
+			if strings.HasPrefix(u.Path, prefix) {
+				id = strings.SplitN(strings.TrimPrefix(u.Path, prefix), "/", 2)[0]
+				break
+			}
+		}
+	default:
+		return Video{}, ErrInvalidURL
+	}
+
+	if !ValidID(id) {
+		return Video{}, ErrInvalidID
+	}
+
+	video := Video{ID: id}
+
+	// Timestamps come as t= or start= in the query, or as #t= in the fragment
+	start := query.Get("t")
+	if start == "" {
+		start = query.Get("start")
+	}
+	if start == "" && strings.HasPrefix(u.Fragment, "t=") {
+		start = strings.TrimPrefix(u.Fragment, "t=")
+	}
+
+	if video.Start, err = ParseTime(start); err != nil {
+		return Video{}, err
+	}
+	if video.End, err = ParseTime(query.Get("end")); err != nil {
+		return Video{}, err
+	}
+	if video.End > 0 && video.End <= video.Start {
+		return Video{}, ErrInvalidTime
+	}
+	return video, nil
+}
+
+// WatchURL is the youtube.com watch link of a video, starting at start seconds
+func WatchURL(id string, start int) string {
+	link := "https://www.youtube.com/watch?v=" + url.QueryEscape(id)
+	if start > 0 {
+		link += "&t=" + strconv.Itoa(start) + "s"
+	}
+	return link
+}
@@ -0,0 +1,106 @@
+package youtube
+
+import (
+	"errors"
+	"testing"
+)
+
+/* This file checks which YouTube references Parse accepts: bare IDs, the URL forms admins paste and the start and end timestamps they carry. */
+
+const testID = "dQw4w9WgXcQ"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Video
+		wantErr error
+	}{
+		{testID, Video{ID: testID}, nil},
+		{"  " + testID + "\n", Video{ID: testID}, nil},
+		{"https://www.youtube.com/watch?v=" + testID, Video{ID: testID}, nil},
+		{"http://m.youtube.com/watch?v=" + testID + "&feature=share", Video{ID: testID}, nil},
+		{"youtube.com/watch?v=" + testID, Video{ID: testID}, nil},
+		{"https://youtu.be/" + testID, Video{ID: testID}, nil},
+		{"youtu.be/" + testID + "?si=abc", Video{ID: testID}, nil},
+		{"https://www.youtube.com/shorts/" + testID, Video{ID: testID}, nil},
+		{"https://www.youtube.com/embed/" + testID + "?rel=0", Video{ID: testID}, nil},
+		{"https://www.youtube-nocookie.com/embed/" + testID, Video{ID: testID}, nil},
+		{"https://www.youtube.com/live/" + testID + "/extra", Video{ID: testID}, nil},
+		{"https://music.youtube.com/watch?v=" + testID + "&list=RDAMVM", Video{ID: testID}, nil},
+		{"https://WWW.YOUTUBE.COM/watch?v=" + testID, Video{ID: testID}, nil},
+
+		// Timestamps
+		{"https://youtu.be/" + testID + "?t=90", Video{ID: testID, Start: 90}, nil},
+		{"https://www.youtube.com/watch?v=" + testID + "&t=1m30s", Video{ID: testID, Start: 90}, nil},
+		{"https://www.youtube.com/watch?v=" + testID + "&t=1h2m3s", Video{ID: testID, Start: 3723}, nil},
+		{"https://www.youtube.com/embed/" + testID + "?start=30&end=95", Video{ID: testID, Start: 30, End: 95}, nil},
+		{"https://www.youtube.com/watch?v=" + testID + "#t=45s", Video{ID: testID, Start: 45}, nil},
+		{"https://www.youtube.com/watch?v=" + testID + "&t=10&start=20", Video{ID: testID, Start: 10}, nil},
+		{"https://www.youtube.com/watch?v=" + testID + "&end=60", Video{ID: testID, End: 60}, nil},
+		{"https://www.youtube.com/watch?v=" + testID + "&t=abc", Video{}, ErrInvalidTime},
+		{"https://www.youtube.com/embed/" + testID + "?start=60&end=60", Video{}, ErrInvalidTime},
+		{"https://www.youtube.com/embed/" + testID + "?start=90&end=30", Video{}, ErrInvalidTime},
+
+		// Malformed IDs
+		{"dQw4w9WgXc", Video{}, ErrInvalidURL},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXc", Video{}, ErrInvalidID},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQQ", Video{}, ErrInvalidID},
+		{"https://www.youtube.com/watch?v=dQw4w9WgX!Q", Video{}, ErrInvalidID},
+		{"https://youtu.be/", Video{}, ErrInvalidID},
+		{"https://youtu.be/" + testID + "/extra", Video{}, ErrInvalidID},
+		{"https://www.youtube.com/channel/UCuAXFkgsw1L7xaCfnd5JJOw", Video{}, ErrInvalidID},
+		{"https://www.youtube.com/watch", Video{}, ErrInvalidID},
+
+		// Not YouTube
+		{"https://vimeo.com/" + testID, Video{}, ErrInvalidURL},
+		{"https://youtube.com.evil.example/watch?v=" + testID, Video{}, ErrInvalidURL},
+		{"", Video{}, ErrInvalidURL},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"90", 90, false},
+		{"90s", 90, false},
+		{" 1M30S ", 90, false},
+		{"2m", 120, false},
+		{"1h", 3600, false},
+		{"1h0m5s", 3605, false},
+		{"-5", 0, true},
+		{"1.5", 0, true},
+		{"5s1m", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTime(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTime(%q) = %d, %v, want %d, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWatchURL(t *testing.T) {
+	if got, want := WatchURL(testID, 0), "https://www.youtube.com/watch?v="+testID; got != want {
+		t.Errorf("WatchURL = %q, want %q", got, want)
+	}
+	if got, want := WatchURL(testID, 90), "https://www.youtube.com/watch?v="+testID+"&t=90s"; got != want {
+		t.Errorf("WatchURL = %q, want %q", got, want)
+	}
+}